@@ -1,14 +1,19 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
+	"time"
 
 	"asterisk-manager/repositories"
 	"asterisk-manager/services"
 )
 
 func main() {
+	at := flag.String("at", "", "сгенерировать конфигурацию на момент времени (RFC 3339, например 2024-05-14T18:00:00+03:00)")
+	flag.Parse()
+
 	fmt.Println("🚀 Asterisk Configuration Generator")
 	fmt.Println("====================================")
 
@@ -18,12 +23,26 @@ func main() {
 	repos := repositories.InitRepos(connStr)
 
 	// Создаём генератор с выходной папкой results
-	generator := services.NewAsteriskGenerator("results")
+	generator := services.NewAsteriskGeneratorFromEnv("results")
 
-	// Загружаем данные из БД
-	fmt.Println("\n📂 Загрузка данных из базы данных...")
-	if err := generator.LoadFromDatabase(repos); err != nil {
-		log.Fatalf("❌ Ошибка загрузки данных из БД: %v", err)
+	if *at != "" {
+		// Состояние на момент времени восстанавливается по истории ревизий
+		moment, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			log.Fatalf("❌ Неверный формат -at: %v", err)
+		}
+		fmt.Printf("\n🕰️  Загрузка состояния на %s...\n", moment.Format(time.RFC3339))
+		snapshot, err := repos.FindSnapshot(moment)
+		if err != nil {
+			log.Fatalf("❌ Ошибка загрузки истории: %v", err)
+		}
+		generator.LoadSnapshot(snapshot)
+	} else {
+		// Загружаем данные из БД
+		fmt.Println("\n📂 Загрузка данных из базы данных...")
+		if err := generator.LoadFromDatabase(repos); err != nil {
+			log.Fatalf("❌ Ошибка загрузки данных из БД: %v", err)
+		}
 	}
 
 	// Генерируем конфигурационные файлы
@@ -57,10 +57,24 @@ func main() {
 		log.Fatalf("❌ Ошибка заполнения пользователей: %v", err)
 	}
 
+	// История ревизий
+	fmt.Println("  → Ревизии...")
+	if _, err := repos.BaselineRevisions(); err != nil {
+		log.Fatalf("❌ Ошибка создания ревизий: %v", err)
+	}
+
 	fmt.Println("\n✅ Все данные успешно загружены!")
 }
 
 func cleanTables(repos *repositories.Repos) error {
+	// История относится к удаляемым данным, а их ID будут выданы заново
+	if err := repos.DeleteAll(&domain.Revision{}); err != nil {
+		return fmt.Errorf("очистка revisions: %w", err)
+	}
+	if err := repos.DeleteAll(&domain.AuditEntry{}); err != nil {
+		return fmt.Errorf("очистка audit_log: %w", err)
+	}
+
 	// Удаляем в правильном порядке (сначала зависимые таблицы)
 	if err := repos.DeleteAll(&domain.Profile{}); err != nil {
 		return fmt.Errorf("очистка profiles: %w", err)
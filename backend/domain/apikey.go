@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// APIKey ключ доступа к API для скриптов и сервисных учётных записей.
+// Хранится только SHA-256 ключа; Prefix - начало ключа, чтобы его можно было узнать в списке.
+// Ключ действует от имени создателя и не даёт больше прав, чем у его роли.
+type APIKey struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	Name       string       `gorm:"not null" json:"name"`
+	Prefix     string       `gorm:"not null" json:"prefix"`
+	KeyHash    string       `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes     []Permission `gorm:"serializer:json;type:jsonb;not null" json:"scopes"`
+	CreatedBy  uint         `gorm:"index;not null" json:"createdBy"`
+	ExpiresAt  *time.Time   `json:"expiresAt"`
+	LastUsedAt *time.Time   `json:"lastUsedAt"`
+	LastUsedIP string       `json:"lastUsedIp"`
+	RevokedAt  *time.Time   `json:"revokedAt"`
+	CreatedAt  time.Time    `json:"createdAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (APIKey) TableName() string {
+	return "sipadmin.api_keys"
+}
+
+// IsActive проверяет, что ключ не отозван и не истёк
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// APIKeyUsage запись журнала использования ключа
+type APIKeyUsage struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	APIKeyID  uint      `gorm:"index;not null" json:"apiKeyId"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (APIKeyUsage) TableName() string {
+	return "sipadmin.api_key_usage"
+}
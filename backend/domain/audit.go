@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"strconv"
+	"time"
+)
+
+// AuditAction действие над сущностью
+type AuditAction string
+
+const (
+	AuditActionCreate AuditAction = "create"
+	AuditActionUpdate AuditAction = "update"
+	AuditActionDelete AuditAction = "delete"
+	// AuditActionPurge окончательное удаление из корзины
+	AuditActionPurge AuditAction = "purge"
+)
+
+// AuditEntityType тип сущности в журнале аудита
+type AuditEntityType string
+
+const (
+	AuditEntityProfile  AuditEntityType = "profile"
+	AuditEntityDevice   AuditEntityType = "device"
+	AuditEntityLocation AuditEntityType = "location"
+)
+
+// Auditable сущность, изменения которой пишутся в журнал аудита
+type Auditable interface {
+	// AuditEntity возвращает тип сущности и её идентификатор (ID или MAC)
+	AuditEntity() (AuditEntityType, string)
+}
+
+// AuditChange значение поля до и после изменения
+type AuditChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// AuditEntry запись журнала аудита: кто, когда и с какого IP изменил сущность.
+// Changes содержит только изменившиеся поля (при создании и удалении - все поля).
+// UserID пуст для изменений, сделанных не через API (например, CLI).
+type AuditEntry struct {
+	ID         uint                   `gorm:"primaryKey" json:"id"`
+	UserID     *uint                  `gorm:"index" json:"userId"`
+	Username   string                 `gorm:"index" json:"username"`
+	APIKeyID   *uint                  `json:"apiKeyId,omitempty"`
+	Action     AuditAction            `gorm:"not null" json:"action"`
+	EntityType AuditEntityType        `gorm:"not null;index:idx_audit_entity" json:"entityType"`
+	EntityID   string                 `gorm:"not null;index:idx_audit_entity" json:"entityId"`
+	Changes    map[string]AuditChange `gorm:"serializer:json;type:jsonb" json:"changes"`
+	IP         string                 `json:"ip"`
+	CreatedAt  time.Time              `gorm:"index" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (AuditEntry) TableName() string {
+	return "sipadmin.audit_log"
+}
+
+// AuditFilter фильтр журнала аудита
+type AuditFilter struct {
+	UserID     *uint           `query:"userId"`
+	Username   string          `query:"username"`
+	Action     AuditAction     `query:"action"`
+	EntityType AuditEntityType `query:"entityType"`
+	EntityID   string          `query:"entityId"`
+	From       *time.Time      `query:"-"`
+	To         *time.Time      `query:"-"`
+}
+
+// AuditEntity реализует Auditable
+func (p Profile) AuditEntity() (AuditEntityType, string) {
+	return AuditEntityProfile, strconv.FormatUint(uint64(p.ID), 10)
+}
+
+// AuditEntity реализует Auditable
+func (d Device) AuditEntity() (AuditEntityType, string) {
+	return AuditEntityDevice, NormalizeMAC(d.MAC)
+}
+
+// AuditEntity реализует Auditable
+func (l Location) AuditEntity() (AuditEntityType, string) {
+	return AuditEntityLocation, strconv.FormatUint(uint64(l.ID), 10)
+}
@@ -0,0 +1,125 @@
+package domain
+
+// MaxBulkProfiles наибольшее число профилей в одной массовой операции
+const MaxBulkProfiles = 1000
+
+// BulkMode режим массовой операции
+type BulkMode string
+
+const (
+	// BulkModeAtomic применяет изменения, только если все профили изменены успешно
+	BulkModeAtomic BulkMode = "atomic"
+	// BulkModeBestEffort сохраняет успешные изменения, даже если часть профилей не изменилась
+	BulkModeBestEffort BulkMode = "bestEffort"
+)
+
+// BulkProfileChanges изменения, применяемые к каждому профилю; nil - поле не меняется
+type BulkProfileChanges struct {
+	LocationID     *uint `json:"locationId"`
+	RingGroup      *int  `json:"ringGroup"`
+	PickupGroup    *int  `json:"pickupGroup"`
+	IsActive       *bool `json:"isActive"`
+	UnassignDevice bool  `json:"unassignDevice"`
+}
+
+// IsEmpty проверяет, что ни одно изменение не задано
+func (c *BulkProfileChanges) IsEmpty() bool {
+	return c.LocationID == nil && c.RingGroup == nil && c.PickupGroup == nil && c.IsActive == nil && !c.UnassignDevice
+}
+
+// Apply применяет изменения к профилю
+func (c *BulkProfileChanges) Apply(p *Profile) {
+	if c.LocationID != nil {
+		locationID := *c.LocationID
+		p.LocationID = &locationID
+	}
+	if c.RingGroup != nil {
+		ringGroup := *c.RingGroup
+		p.RingGroup = &ringGroup
+	}
+	if c.PickupGroup != nil {
+		pickupGroup := *c.PickupGroup
+		p.PickupGroup = &pickupGroup
+	}
+	if c.IsActive != nil {
+		p.IsActive = *c.IsActive
+	}
+	if c.UnassignDevice {
+		p.Device = nil
+	}
+}
+
+// BulkProfileRequest массовое изменение профилей: по списку IDs или по фильтру списка профилей
+// (сортировка фильтра не учитывается)
+type BulkProfileRequest struct {
+	IDs    []uint             `json:"ids"`
+	Filter *ProfileFilter     `json:"filter"`
+	Set    BulkProfileChanges `json:"set"`
+	Mode   BulkMode           `json:"mode"`
+}
+
+// Validate проверяет запрос и подставляет режим по умолчанию (atomic)
+func (r *BulkProfileRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+	if r.Mode == "" {
+		r.Mode = BulkModeAtomic
+	}
+	if r.Mode != BulkModeAtomic && r.Mode != BulkModeBestEffort {
+		errs.Add("mode", "Must be atomic or bestEffort")
+	}
+
+	switch {
+	case len(r.IDs) == 0 && r.Filter == nil:
+		errs.Add("ids", "Either ids or filter is required")
+	case len(r.IDs) > 0 && r.Filter != nil:
+		errs.Add("ids", "Must not be combined with filter")
+	case len(r.IDs) > MaxBulkProfiles:
+		errs.Add("ids", "At most 1000 profiles per request")
+	}
+	if r.Filter != nil {
+		for field, message := range r.Filter.Validate() {
+			errs.Add("filter."+field, message)
+		}
+	}
+
+	if r.Set.IsEmpty() {
+		errs.Add("set", "At least one change is required")
+	}
+	if r.Set.RingGroup != nil && *r.Set.RingGroup < 0 {
+		errs.Add("set.ringGroup", "Must not be negative")
+	}
+	if r.Set.PickupGroup != nil && *r.Set.PickupGroup < 0 {
+		errs.Add("set.pickupGroup", "Must not be negative")
+	}
+	return errs
+}
+
+// BulkItemStatus результат операции над одним профилем
+type BulkItemStatus string
+
+const (
+	BulkItemUpdated   BulkItemStatus = "updated"
+	BulkItemUnchanged BulkItemStatus = "unchanged"
+	BulkItemFailed    BulkItemStatus = "failed"
+	// BulkItemRolledBack профиль изменился бы, но atomic-операция отменена из-за ошибок других профилей
+	BulkItemRolledBack BulkItemStatus = "rolledBack"
+)
+
+// BulkItemResult результат для одного профиля; Fields - ошибки проверки по полям
+type BulkItemResult struct {
+	ID     uint              `json:"id"`
+	Status BulkItemStatus    `json:"status"`
+	Error  string            `json:"error,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// BulkResult результат массовой операции; Applied - были ли сохранены изменения
+type BulkResult struct {
+	Mode      BulkMode         `json:"mode"`
+	Applied   bool             `json:"applied"`
+	Total     int              `json:"total"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkProfileRequestValidate(t *testing.T) {
+	ringGroup := -1
+	request := BulkProfileRequest{IDs: []uint{1}, Filter: &ProfileFilter{Sort: "password"}, Mode: "sometimes"}
+	assert.Equal(t, []string{"filter.sort", "ids", "mode", "set"}, fieldNames(request.Validate()))
+
+	request = BulkProfileRequest{Filter: &ProfileFilter{}, Set: BulkProfileChanges{RingGroup: &ringGroup}}
+	assert.Equal(t, []string{"set.ringGroup"}, fieldNames(request.Validate()))
+
+	isActive := false
+	request = BulkProfileRequest{IDs: []uint{1, 2}, Set: BulkProfileChanges{IsActive: &isActive, UnassignDevice: true}}
+	assert.Empty(t, request.Validate())
+	assert.Equal(t, BulkModeAtomic, request.Mode)
+
+	mac := "80:5e:c0:18:ab:ac"
+	profile := Profile{Device: &mac, IsActive: true}
+	request.Set.Apply(&profile)
+	assert.Nil(t, profile.Device)
+	assert.False(t, profile.IsActive)
+}
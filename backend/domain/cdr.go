@@ -0,0 +1,67 @@
+package domain
+
+import "time"
+
+// CDRDisposition итог звонка в терминах Asterisk
+type CDRDisposition string
+
+const (
+	CDRDispositionAnswered CDRDisposition = "ANSWERED"
+	CDRDispositionNoAnswer CDRDisposition = "NO ANSWER"
+	CDRDispositionBusy     CDRDisposition = "BUSY"
+	CDRDispositionFailed   CDRDisposition = "FAILED"
+)
+
+// CDRDirection направление звонка относительно станции
+type CDRDirection string
+
+const (
+	CDRDirectionInbound  CDRDirection = "inbound"
+	CDRDirectionOutbound CDRDirection = "outbound"
+	CDRDirectionInternal CDRDirection = "internal"
+)
+
+// CDR запись о звонке (call detail record) из Master.csv или AMI
+type CDR struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	UniqueID    string         `gorm:"uniqueIndex;not null" json:"uniqueId"`
+	AccountCode string         `json:"accountCode"`
+	Src         string         `gorm:"index" json:"src"`
+	Dst         string         `gorm:"index" json:"dst"`
+	DContext    string         `json:"dcontext"`
+	CallerID    string         `json:"callerId"`
+	Channel     string         `json:"channel"`
+	DstChannel  string         `json:"dstChannel"`
+	LastApp     string         `json:"lastApp"`
+	LastData    string         `json:"lastData"`
+	Start       time.Time      `gorm:"index;not null" json:"start"`
+	Answer      *time.Time     `json:"answer"`
+	End         time.Time      `json:"end"`
+	Duration    int            `json:"duration"`
+	BillSec     int            `json:"billSec"`
+	Disposition CDRDisposition `gorm:"index" json:"disposition"`
+	AMAFlags    string         `json:"amaFlags"`
+	UserField   string         `json:"userField"`
+	Direction   CDRDirection   `gorm:"index" json:"direction"`
+	Extension   string         `gorm:"index" json:"extension"`
+	CityNumber  string         `gorm:"index" json:"cityNumber"`
+	ProfileID   *uint          `gorm:"index" json:"profileId"`
+	LocationID  *uint          `gorm:"index" json:"locationId"`
+	RingGroup   *int           `json:"ringGroup"`
+	CreatedAt   time.Time      `json:"createdAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (CDR) TableName() string {
+	return "sipadmin.cdr"
+}
+
+// CDRFilter параметры фильтрации списка звонков
+type CDRFilter struct {
+	Extension   string         `query:"extension"`
+	Number      string         `query:"number"`
+	From        *time.Time     `query:"-"`
+	To          *time.Time     `query:"-"`
+	Disposition CDRDisposition `query:"disposition"`
+	Direction   CDRDirection   `query:"direction"`
+}
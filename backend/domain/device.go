@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // DeviceModel типы поддерживаемых устройств
 type DeviceModel string
@@ -12,15 +16,49 @@ const (
 	DeviceModelCisco       DeviceModel = "Cisco"
 )
 
-// Device представляет IP-телефон или устройство
+// IsValid проверяет, что модель поддерживается
+func (m DeviceModel) IsValid() bool {
+	switch m {
+	case DeviceModelYealinkT27G, DeviceModelYealinkT23G, DeviceModelFanvil, DeviceModelCisco:
+		return true
+	}
+	return false
+}
+
+// Device представляет IP-телефон или устройство.
+// Удалённое устройство попадает в корзину (DeletedAt) и продолжает занимать свой MAC.
 type Device struct {
-	MAC         string      `gorm:"primaryKey;type:macaddr" json:"mac"`
-	DeviceModel DeviceModel `gorm:"not null" json:"deviceModel"`
-	CreatedAt   time.Time   `json:"createdAt"`
-	UpdatedAt   time.Time   `json:"updatedAt"`
+	MAC         string         `gorm:"primaryKey;type:macaddr" json:"mac"`
+	DeviceModel DeviceModel    `gorm:"not null" json:"deviceModel"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deletedAt"`
 }
 
 // TableName указывает имя таблицы в БД
 func (Device) TableName() string {
 	return "sipadmin.devices"
 }
+
+// DeviceWithUsage устройство в списке с числом неудалённых профилей, которым оно назначено
+type DeviceWithUsage struct {
+	Device
+	ProfileCount int64 `json:"profileCount"`
+}
+
+// DeviceFilter фильтр списка устройств (?deviceModel=Fanvil&assigned=false&locationId=2).
+// LocationID отбирает устройства, назначенные профилям этой локации.
+type DeviceFilter struct {
+	DeviceModel DeviceModel `query:"deviceModel"`
+	Assigned    *bool       `query:"assigned"`
+	LocationID  *uint       `query:"locationId"`
+}
+
+// Validate проверяет модель устройства
+func (f *DeviceFilter) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+	if f.DeviceModel != "" && !f.DeviceModel.IsValid() {
+		errs.Add("deviceModel", "Unknown device model")
+	}
+	return errs
+}
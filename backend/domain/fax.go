@@ -0,0 +1,79 @@
+package domain
+
+import "time"
+
+// Fax принятый факс из каталога /var/calls/FAX
+type Fax struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Path       string     `gorm:"uniqueIndex;not null" json:"-"`
+	FileName   string     `gorm:"not null" json:"fileName"`
+	CityNumber string     `gorm:"index;not null" json:"cityNumber"`
+	Sender     string     `gorm:"index" json:"sender"`
+	ReceivedAt time.Time  `gorm:"index;not null" json:"receivedAt"`
+	Size       int64      `json:"size"`
+	ProfileID  *uint      `json:"profileId"`
+	LocationID *uint      `gorm:"index" json:"locationId"`
+	RingGroup  *int       `gorm:"index" json:"ringGroup"`
+	IsRead     bool       `gorm:"default:false" json:"isRead"`
+	ReadAt     *time.Time `json:"readAt"`
+	ReadBy     string     `json:"readBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (Fax) TableName() string {
+	return "sipadmin.faxes"
+}
+
+// FaxFilter параметры поиска факсов
+type FaxFilter struct {
+	Search     string     `query:"q"`
+	CityNumber string     `query:"cityNumber"`
+	RingGroup  *int       `query:"ringGroup"`
+	LocationID *uint      `query:"locationId"`
+	Unread     bool       `query:"unread"`
+	From       *time.Time `query:"-"`
+	To         *time.Time `query:"-"`
+}
+
+// FaxRecipient адрес рассылки факсов ринг-группы
+type FaxRecipient struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RingGroup int       `gorm:"index;not null" json:"ringGroup"`
+	Email     string    `gorm:"not null" json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (FaxRecipient) TableName() string {
+	return "sipadmin.fax_recipients"
+}
+
+// FaxDeliveryStatus статус доставки факса по почте
+type FaxDeliveryStatus string
+
+const (
+	FaxDeliveryPending FaxDeliveryStatus = "pending"
+	FaxDeliverySent    FaxDeliveryStatus = "sent"
+	FaxDeliveryFailed  FaxDeliveryStatus = "failed"
+)
+
+// FaxDelivery попытка доставки факса по почте
+type FaxDelivery struct {
+	ID            uint              `gorm:"primaryKey" json:"id"`
+	FaxID         uint              `gorm:"index;not null" json:"faxId"`
+	Recipients    string            `gorm:"not null" json:"recipients"`
+	Status        FaxDeliveryStatus `gorm:"index;not null" json:"status"`
+	Attempts      int               `json:"attempts"`
+	LastError     string            `json:"lastError"`
+	NextAttemptAt time.Time         `gorm:"index" json:"nextAttemptAt"`
+	SentAt        *time.Time        `json:"sentAt"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	UpdatedAt     time.Time         `json:"updatedAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (FaxDelivery) TableName() string {
+	return "sipadmin.fax_deliveries"
+}
@@ -0,0 +1,50 @@
+package domain
+
+// DeletePolicy определяет, что делать с профилями, ссылающимися на удаляемое устройство или локацию
+type DeletePolicy string
+
+const (
+	// DeletePolicyBlock запрещает удаление, пока на запись ссылаются профили
+	DeletePolicyBlock DeletePolicy = "block"
+	// DeletePolicyCascade удаляет зависимые профили в корзину вместе с записью
+	DeletePolicyCascade DeletePolicy = "cascade"
+	// DeletePolicyReassign переносит зависимые профили на другое устройство или локацию
+	DeletePolicyReassign DeletePolicy = "reassign"
+)
+
+// IsValid проверяет, что политика известна
+func (p DeletePolicy) IsValid() bool {
+	switch p {
+	case DeletePolicyBlock, DeletePolicyCascade, DeletePolicyReassign:
+		return true
+	}
+	return false
+}
+
+// FileChangeType вид изменения сгенерированного файла
+type FileChangeType string
+
+const (
+	FileAdded    FileChangeType = "added"
+	FileRemoved  FileChangeType = "removed"
+	FileModified FileChangeType = "modified"
+)
+
+// FileChange изменение одного файла конфигурации Asterisk; Path относительно каталога генерации
+type FileChange struct {
+	Path   string         `json:"path"`
+	Change FileChangeType `json:"change"`
+}
+
+// DeleteImpact результат анализа удаления устройства или локации (DELETE ...?dryRun=true).
+// Files - файлы конфигурации, которые изменятся после удаления по выбранной политике;
+// для заблокированного удаления - если бы запись удалили, оставив ссылки профилей.
+type DeleteImpact struct {
+	EntityType AuditEntityType `json:"entityType"`
+	EntityID   string          `json:"entityId"`
+	Policy     DeletePolicy    `json:"policy"`
+	ReassignTo string          `json:"reassignTo,omitempty"`
+	Blocked    bool            `json:"blocked"`
+	Profiles   []Profile       `json:"profiles"`
+	Files      []FileChange    `json:"files"`
+}
@@ -1,20 +1,33 @@
 package domain
 
-import "time"
+import (
+	"time"
 
-// Location представляет локацию (здание/адрес) с настройками сети
+	"gorm.io/gorm"
+)
+
+// Location представляет локацию (здание/адрес) с настройками сети.
+// Удалённая локация попадает в корзину (DeletedAt); её профили не генерируются, пока она там.
 type Location struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"not null" json:"name"`
-	Server    string    `gorm:"type:inet;not null" json:"server"`
-	Subnet    string    `gorm:"type:cidr;not null" json:"subnet"`
-	VoipVLAN  int       `gorm:"not null" json:"voipVlan"`
-	VLAN      int       `gorm:"not null" json:"vlan"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Name      string         `gorm:"not null" json:"name"`
+	Server    string         `gorm:"type:inet;not null" json:"server"`
+	Subnet    string         `gorm:"type:cidr;not null" json:"subnet"`
+	VoipVLAN  int            `gorm:"not null" json:"voipVlan"`
+	VLAN      int            `gorm:"not null" json:"vlan"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt"`
 }
 
 // TableName указывает имя таблицы в БД
 func (Location) TableName() string {
 	return "sipadmin.locations"
 }
+
+// LocationWithCounts локация в списке с числом неудалённых профилей и назначенных им устройств
+type LocationWithCounts struct {
+	Location
+	ProfileCount int64 `json:"profileCount"`
+	DeviceCount  int64 `json:"deviceCount"`
+}
@@ -0,0 +1,49 @@
+package domain
+
+import "time"
+
+// LoginAttempt попытка входа по паролю
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Username  string    `gorm:"index" json:"username"`
+	IP        string    `gorm:"index" json:"ip"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (LoginAttempt) TableName() string {
+	return "sipadmin.login_attempts"
+}
+
+// LockoutKind по чему считаются неудачные попытки
+type LockoutKind string
+
+const (
+	LockoutByUsername LockoutKind = "username"
+	LockoutByIP       LockoutKind = "ip"
+)
+
+// Lockout счётчик неудачных попыток входа для имени пользователя или IP.
+// После порога вход блокируется до LockedUntil, каждая следующая неудача удваивает блокировку.
+type Lockout struct {
+	ID            uint        `gorm:"primaryKey" json:"id"`
+	Kind          LockoutKind `gorm:"uniqueIndex:idx_lockouts_kind_key;not null" json:"kind"`
+	Key           string      `gorm:"uniqueIndex:idx_lockouts_kind_key;not null" json:"key"`
+	Failures      int         `json:"failures"`
+	LastFailureAt time.Time   `json:"lastFailureAt"`
+	LockedUntil   *time.Time  `json:"lockedUntil"`
+}
+
+// TableName указывает имя таблицы в БД
+func (Lockout) TableName() string {
+	return "sipadmin.lockouts"
+}
+
+// LoginAttemptFilter фильтр журнала попыток входа
+type LoginAttemptFilter struct {
+	Username string `query:"username"`
+	IP       string `query:"ip"`
+	Failed   bool   `query:"failed"`
+}
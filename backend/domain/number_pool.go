@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NumberPool диапазон внутренних номеров локации или отдела (например 6100-6199 для ЗАГСа),
+// из которого выдаются номера новым профилям. Диапазоны пулов не пересекаются.
+type NumberPool struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Name       string    `gorm:"not null" json:"name"`
+	LocationID *uint     `json:"locationId"`
+	Department string    `gorm:"not null;default:''" json:"department"`
+	RangeStart int       `gorm:"not null" json:"rangeStart"`
+	RangeEnd   int       `gorm:"not null" json:"rangeEnd"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (NumberPool) TableName() string {
+	return "sipadmin.number_pools"
+}
+
+// Size возвращает число номеров в пуле
+func (p *NumberPool) Size() int {
+	return p.RangeEnd - p.RangeStart + 1
+}
+
+// Contains проверяет, входит ли номер в диапазон пула
+func (p *NumberPool) Contains(number int) bool {
+	return number >= p.RangeStart && number <= p.RangeEnd
+}
+
+// Overlaps проверяет, пересекаются ли диапазоны пулов
+func (p *NumberPool) Overlaps(other *NumberPool) bool {
+	return p.RangeStart <= other.RangeEnd && other.RangeStart <= p.RangeEnd
+}
+
+// Validate проверяет поля пула: диапазон внутри допустимых внутренних номеров
+func (p *NumberPool) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+	if strings.TrimSpace(p.Name) == "" {
+		errs.Add("name", "Is required")
+	}
+	inRange := fmt.Sprintf("Must be between %d and %d", MinInternalNumber, MaxInternalNumber)
+	if p.RangeStart < MinInternalNumber || p.RangeStart > MaxInternalNumber {
+		errs.Add("rangeStart", inRange)
+	}
+	if p.RangeEnd < MinInternalNumber || p.RangeEnd > MaxInternalNumber {
+		errs.Add("rangeEnd", inRange)
+	}
+	if p.RangeEnd < p.RangeStart {
+		errs.Add("rangeEnd", "Must not be less than rangeStart")
+	}
+	return errs
+}
+
+// FreeNumber первый свободный номер пула (GET /api/number-pools/:id/next); номер не бронируется
+type FreeNumber struct {
+	PoolID uint `json:"poolId"`
+	Number int  `json:"number"`
+}
+
+// NumberReservation бронь номера пула до ExpiresAt: номер не выдаётся автоматически,
+// пока бронь действует. Бронь снимается, когда номер получает профиль.
+type NumberReservation struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PoolID    uint      `gorm:"not null;index" json:"poolId"`
+	Number    int       `gorm:"not null;uniqueIndex" json:"number"`
+	Note      string    `gorm:"not null;default:''" json:"note"`
+	Username  string    `gorm:"not null;default:''" json:"username"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (NumberReservation) TableName() string {
+	return "sipadmin.number_reservations"
+}
+
+// ReserveNumberRequest запрос брони: Number - конкретный номер (иначе первый свободный),
+// TTL - срок брони в формате Go ("48h"), по умолчанию NUMBER_RESERVATION_TTL
+type ReserveNumberRequest struct {
+	Number *int   `json:"number"`
+	TTL    string `json:"ttl"`
+	Note   string `json:"note"`
+}
+
+// MaxReservationTTL наибольший срок брони
+const MaxReservationTTL = 90 * 24 * time.Hour
+
+// Duration разбирает срок брони; пустой срок заменяется defaultTTL
+func (r *ReserveNumberRequest) Duration(defaultTTL time.Duration) (time.Duration, error) {
+	if r.TTL == "" {
+		return defaultTTL, nil
+	}
+	ttl, err := time.ParseDuration(r.TTL)
+	if err != nil || ttl <= 0 || ttl > MaxReservationTTL {
+		return 0, ValidationErrors{"ttl": "Must be a positive duration up to 2160h, e.g. 48h"}
+	}
+	return ttl, nil
+}
+
+// PoolUtilisation заполненность пула: Used - номера неудалённых профилей,
+// Reserved - действующие брони, Free - остальные номера диапазона
+type PoolUtilisation struct {
+	NumberPool
+	LocationName string  `json:"locationName"`
+	Size         int     `json:"size"`
+	Used         int     `json:"used"`
+	Reserved     int     `json:"reserved"`
+	Free         int     `json:"free"`
+	Percent      float64 `json:"percent"`
+	NextFree     *int    `json:"nextFree"`
+}
+
+// Calculate вычисляет размер, число свободных номеров и процент занятых (с бронями)
+func (u *PoolUtilisation) Calculate() {
+	u.Size = u.NumberPool.Size()
+	u.Free = u.Size - u.Used - u.Reserved
+	if u.Free < 0 {
+		u.Free = 0
+	}
+	if u.Size > 0 {
+		u.Percent = float64(u.Used+u.Reserved) * 100 / float64(u.Size)
+	}
+}
+
+// CreateProfileRequest тело создания профиля: с AllocateFromPool (ID пула) внутренний номер
+// не указывается, профиль получает первый свободный номер пула. С FromReservation профиль
+// занимает забронированный номер internalNumber, бронь снимается.
+type CreateProfileRequest struct {
+	Profile
+	AllocateFromPool *uint `json:"allocateFromPool"`
+	FromReservation  bool  `json:"fromReservation"`
+}
+
+// Validate проверяет, что способ выбора номера задан однозначно
+func (r *CreateProfileRequest) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+	if r.AllocateFromPool != nil && r.InternalNumber != 0 {
+		errs.Add("allocateFromPool", "Must not be combined with internalNumber")
+	}
+	if r.AllocateFromPool != nil && r.FromReservation {
+		errs.Add("fromReservation", "Must not be combined with allocateFromPool")
+	}
+	return errs
+}
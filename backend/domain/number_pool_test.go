@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumberPoolValidate(t *testing.T) {
+	pool := NumberPool{Name: "", RangeStart: 6199, RangeEnd: 6100}
+	assert.Equal(t, []string{"name", "rangeEnd"}, fieldNames(pool.Validate()))
+
+	pool = NumberPool{Name: "ЗАГС", RangeStart: 999, RangeEnd: 10000}
+	assert.Equal(t, []string{"rangeEnd", "rangeStart"}, fieldNames(pool.Validate()))
+
+	pool = NumberPool{Name: "ЗАГС", RangeStart: 6100, RangeEnd: 6199}
+	assert.Empty(t, pool.Validate())
+	assert.Equal(t, 100, pool.Size())
+	assert.True(t, pool.Contains(6199))
+	assert.False(t, pool.Contains(6200))
+	assert.True(t, pool.Overlaps(&NumberPool{RangeStart: 6199, RangeEnd: 6299}))
+	assert.False(t, pool.Overlaps(&NumberPool{RangeStart: 6200, RangeEnd: 6299}))
+
+	utilisation := PoolUtilisation{NumberPool: pool, Used: 60, Reserved: 15}
+	utilisation.Calculate()
+	assert.Equal(t, 25, utilisation.Free)
+	assert.Equal(t, 75.0, utilisation.Percent)
+}
+
+func TestReserveNumberRequestDuration(t *testing.T) {
+	request := ReserveNumberRequest{}
+	ttl, err := request.Duration(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, ttl)
+
+	request.TTL = "48h"
+	ttl, err = request.Duration(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 48*time.Hour, ttl)
+
+	for _, value := range []string{"tomorrow", "-1h", "2161h"} {
+		request.TTL = value
+		_, err = request.Duration(24 * time.Hour)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestCreateProfileRequestValidate(t *testing.T) {
+	poolID := uint(1)
+	request := CreateProfileRequest{Profile: Profile{InternalNumber: 6101}, AllocateFromPool: &poolID, FromReservation: true}
+	assert.Equal(t, []string{"allocateFromPool", "fromReservation"}, fieldNames(request.Validate()))
+
+	request = CreateProfileRequest{Profile: Profile{InternalNumber: 6101}, FromReservation: true}
+	assert.Empty(t, request.Validate())
+}
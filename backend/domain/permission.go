@@ -0,0 +1,73 @@
+package domain
+
+// Permission право на действие с ресурсом в формате "ресурс:действие"
+type Permission string
+
+const (
+	PermissionProfilesRead     Permission = "profiles:read"
+	PermissionProfilesWrite    Permission = "profiles:write"
+	PermissionDevicesRead      Permission = "devices:read"
+	PermissionDevicesWrite     Permission = "devices:write"
+	PermissionLocationsRead    Permission = "locations:read"
+	PermissionLocationsWrite   Permission = "locations:write"
+	PermissionCDRRead          Permission = "cdr:read"
+	PermissionReportsRead      Permission = "reports:read"
+	PermissionRecordingsRead   Permission = "recordings:read"
+	PermissionRecordingsManage Permission = "recordings:manage"
+	PermissionFaxesRead        Permission = "faxes:read"
+	PermissionFaxesManage      Permission = "faxes:manage"
+	PermissionGeneratorRun     Permission = "generator:run"
+	PermissionUsersManage      Permission = "users:manage"
+	PermissionAPIKeysManage    Permission = "apikeys:manage"
+	PermissionAuditRead        Permission = "audit:read"
+)
+
+// rolePermissions права, выданные ролям
+var rolePermissions = map[UserRole][]Permission{
+	UserRoleAdmin: {
+		PermissionProfilesRead,
+		PermissionProfilesWrite,
+		PermissionDevicesRead,
+		PermissionDevicesWrite,
+		PermissionLocationsRead,
+		PermissionLocationsWrite,
+		PermissionCDRRead,
+		PermissionReportsRead,
+		PermissionRecordingsRead,
+		PermissionRecordingsManage,
+		PermissionFaxesRead,
+		PermissionFaxesManage,
+		PermissionGeneratorRun,
+		PermissionUsersManage,
+		PermissionAPIKeysManage,
+		PermissionAuditRead,
+	},
+	UserRoleUser: {
+		PermissionProfilesRead,
+		PermissionDevicesRead,
+		PermissionLocationsRead,
+		PermissionCDRRead,
+		PermissionReportsRead,
+		PermissionRecordingsRead,
+		PermissionFaxesRead,
+	},
+}
+
+// Permissions возвращает права роли
+func (r UserRole) Permissions() []Permission {
+	permissions := rolePermissions[r]
+	if permissions == nil {
+		return []Permission{}
+	}
+	return permissions
+}
+
+// Can проверяет, есть ли у роли право
+func (r UserRole) Can(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -2,32 +2,37 @@ package domain
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
-// Profile представляет профиль сотрудника с SIP-настройками
+// Profile представляет профиль сотрудника с SIP-настройками.
+// Удалённый профиль попадает в корзину (DeletedAt), его внутренний номер освобождается.
 type Profile struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	Name           string    `json:"name"`
-	Email          string    `json:"email"`
-	Device         *string   `gorm:"type:macaddr" json:"device"`
-	LocationID     *uint     `json:"locationId"`
-	InternalNumber int       `gorm:"uniqueIndex;not null" json:"internalNumber"`
-	ExternalNumber string    `json:"externalNumber"`
-	RingGroup      *int      `json:"ringGroup"`
-	PickupGroup    *int      `json:"pickupGroup"`
-	IsActive       bool      `gorm:"default:true" json:"isActive"`
-	CreatedAt      time.Time `json:"createdAt"`
-	UpdatedAt      time.Time `json:"updatedAt"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	Name           string         `json:"name"`
+	Email          string         `json:"email"`
+	Device         *string        `gorm:"type:macaddr" json:"device"`
+	LocationID     *uint          `json:"locationId"`
+	InternalNumber int            `gorm:"uniqueIndex:idx_profiles_internal_number_active,where:deleted_at IS NULL;not null" json:"internalNumber"`
+	ExternalNumber string         `json:"externalNumber"`
+	RingGroup      *int           `json:"ringGroup"`
+	PickupGroup    *int           `json:"pickupGroup"`
+	IsActive       bool           `gorm:"default:true" json:"isActive"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"deletedAt"`
 }
 
 // ProfileWithLocation представляет профиль с данными локации
 type ProfileWithLocation struct {
 	Profile
-	LocationName *string `json:"locationName"`
-	Server       *string `json:"server"`
-	Subnet       *string `json:"subnet"`
-	VoipVLAN     *int    `json:"voipVlan"`
-	VLAN         *int    `json:"vlan"`
+	DeviceModel  *DeviceModel `json:"deviceModel"`
+	LocationName *string      `json:"locationName"`
+	Server       *string      `json:"server"`
+	Subnet       *string      `json:"subnet"`
+	VoipVLAN     *int         `json:"voipVlan"`
+	VLAN         *int         `json:"vlan"`
 }
 
 // TableName указывает имя таблицы в БД
@@ -42,10 +47,11 @@ func (p *Profile) IsLocalOnly() bool {
 
 // GetExternalNumberClean возвращает внешний номер без дефисов
 func (p *Profile) GetExternalNumberClean() string {
-	return cleanPhoneNumber(p.ExternalNumber)
+	return CleanPhoneNumber(p.ExternalNumber)
 }
 
-func cleanPhoneNumber(phone string) string {
+// CleanPhoneNumber оставляет в номере только цифры
+func CleanPhoneNumber(phone string) string {
 	result := ""
 	for _, char := range phone {
 		if char >= '0' && char <= '9' {
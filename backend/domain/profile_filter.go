@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+)
+
+// ProfileSortColumns поля списка профилей, по которым разрешена сортировка (?sort=name, ?sort=-internalNumber),
+// и соответствующие им выражения запроса FindProfilesWithLocations
+var ProfileSortColumns = map[string]string{
+	"id":             "p.id",
+	"name":           "p.name",
+	"email":          "p.email",
+	"internalNumber": "p.internal_number",
+	"externalNumber": "p.external_number",
+	"device":         "p.device",
+	"deviceModel":    "d.device_model",
+	"locationName":   "l.name",
+	"ringGroup":      "p.ring_group",
+	"pickupGroup":    "p.pickup_group",
+	"isActive":       "p.is_active",
+	"createdAt":      "p.created_at",
+	"updatedAt":      "p.updated_at",
+}
+
+// ProfileFilter фильтр, поиск и сортировка списка профилей
+// (?locationId=1&isActive=true&ringGroup=2&pickupGroup=1&deviceModel=Fanvil&hasDevice=false&search=иванов&sort=-name)
+type ProfileFilter struct {
+	LocationID  *uint       `query:"locationId"`
+	IsActive    *bool       `query:"isActive"`
+	RingGroup   *int        `query:"ringGroup"`
+	PickupGroup *int        `query:"pickupGroup"`
+	DeviceModel DeviceModel `query:"deviceModel"`
+	HasDevice   *bool       `query:"hasDevice"`
+	// Search ищет подстроку в ФИО, email, внутреннем и внешнем номере
+	Search string `query:"search"`
+	// Sort поле из ProfileSortColumns, с "-" в начале - по убыванию
+	Sort string `query:"sort"`
+}
+
+// Validate проверяет модель устройства и поле сортировки
+func (f *ProfileFilter) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+	if f.DeviceModel != "" && !f.DeviceModel.IsValid() {
+		errs.Add("deviceModel", "Unknown device model")
+	}
+	if field, _ := f.SortField(); field != "" {
+		if _, ok := ProfileSortColumns[field]; !ok {
+			errs.Add("sort", "Must be one of: "+strings.Join(profileSortFields(), ", "))
+		}
+	}
+	return errs
+}
+
+// SortField возвращает поле сортировки и её направление
+func (f *ProfileFilter) SortField() (field string, desc bool) {
+	sort := strings.TrimSpace(f.Sort)
+	if strings.HasPrefix(sort, "-") {
+		return sort[1:], true
+	}
+	return sort, false
+}
+
+func profileSortFields() []string {
+	fields := make([]string, 0, len(ProfileSortColumns))
+	for field := range ProfileSortColumns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
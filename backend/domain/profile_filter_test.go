@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fieldNames возвращает отсортированные имена полей с ошибками
+func fieldNames(errs ValidationErrors) []string {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestProfileFilterValidate(t *testing.T) {
+	filter := ProfileFilter{DeviceModel: DeviceModelFanvil, Sort: "-internalNumber"}
+	assert.Empty(t, filter.Validate())
+	field, desc := filter.SortField()
+	assert.Equal(t, "internalNumber", field)
+	assert.True(t, desc)
+
+	filter = ProfileFilter{DeviceModel: "Panasonic", Sort: "password"}
+	assert.Equal(t, []string{"deviceModel", "sort"}, fieldNames(filter.Validate()))
+}
@@ -0,0 +1,119 @@
+package domain
+
+import "time"
+
+// Recording запись разговора, найденная в каталоге записей
+type Recording struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Path       string    `gorm:"uniqueIndex;not null" json:"-"`
+	FileName   string    `gorm:"not null" json:"fileName"`
+	Caller     string    `gorm:"index" json:"caller"`
+	Callee     string    `gorm:"index" json:"callee"`
+	StartedAt  time.Time `gorm:"index;not null" json:"startedAt"`
+	Size       int64     `json:"size"`
+	Format     string    `json:"format"`
+	CDRID      *uint     `gorm:"column:cdr_id" json:"cdrId"`
+	ProfileID  *uint     `gorm:"index" json:"profileId"`
+	LocationID *uint     `gorm:"index" json:"locationId"`
+	RingGroup  *int      `gorm:"index" json:"ringGroup"`
+	LegalHold  bool      `gorm:"default:false" json:"legalHold"`
+	HoldReason string    `json:"holdReason"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (Recording) TableName() string {
+	return "sipadmin.recordings"
+}
+
+// RetentionPolicy срок хранения записей для локации и/или ринг-группы.
+// Политика без локации и ринг-группы действует по умолчанию.
+type RetentionPolicy struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Name       string    `gorm:"not null" json:"name"`
+	LocationID *uint     `json:"locationId"`
+	RingGroup  *int      `json:"ringGroup"`
+	Days       int       `gorm:"not null" json:"days"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (RetentionPolicy) TableName() string {
+	return "sipadmin.retention_policies"
+}
+
+// Specificity возвращает приоритет политики: чем точнее условие, тем выше
+func (p *RetentionPolicy) Specificity() int {
+	specificity := 0
+	if p.RingGroup != nil {
+		specificity += 2
+	}
+	if p.LocationID != nil {
+		specificity++
+	}
+	return specificity
+}
+
+// Matches проверяет, распространяется ли политика на запись
+func (p *RetentionPolicy) Matches(r *Recording) bool {
+	if p.LocationID != nil && (r.LocationID == nil || *r.LocationID != *p.LocationID) {
+		return false
+	}
+	if p.RingGroup != nil && (r.RingGroup == nil || *r.RingGroup != *p.RingGroup) {
+		return false
+	}
+	return true
+}
+
+// RecordingDeletion журнал удалений записей разговоров
+type RecordingDeletion struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	RecordingID   uint      `gorm:"index;not null" json:"recordingId"`
+	Path          string    `gorm:"not null" json:"path"`
+	Caller        string    `json:"caller"`
+	Callee        string    `json:"callee"`
+	StartedAt     time.Time `json:"startedAt"`
+	LocationID    *uint     `json:"locationId"`
+	RingGroup     *int      `json:"ringGroup"`
+	PolicyID      *uint     `json:"policyId"`
+	RetentionDays int       `json:"retentionDays"`
+	DeletedBy     string    `gorm:"not null" json:"deletedBy"`
+	DeletedAt     time.Time `gorm:"index;not null" json:"deletedAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (RecordingDeletion) TableName() string {
+	return "sipadmin.recording_deletions"
+}
+
+// PurgeItem запись, подлежащая удалению по политике хранения
+type PurgeItem struct {
+	RecordingID   uint      `json:"recordingId"`
+	FileName      string    `json:"fileName"`
+	StartedAt     time.Time `json:"startedAt"`
+	LocationID    *uint     `json:"locationId"`
+	RingGroup     *int      `json:"ringGroup"`
+	PolicyID      uint      `json:"policyId"`
+	PolicyName    string    `json:"policyName"`
+	RetentionDays int       `json:"retentionDays"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// PurgeReport результат (или план при dryRun) очистки записей
+type PurgeReport struct {
+	DryRun  bool        `json:"dryRun"`
+	Items   []PurgeItem `json:"items"`
+	Deleted int         `json:"deleted"`
+	Failed  int         `json:"failed"`
+}
+
+// RecordingFilter параметры поиска записей разговоров
+type RecordingFilter struct {
+	Search     string     `query:"q"`
+	Extension  string     `query:"extension"`
+	LocationID *uint      `query:"locationId"`
+	From       *time.Time `query:"-"`
+	To         *time.Time `query:"-"`
+}
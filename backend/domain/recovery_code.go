@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// RecoveryCode одноразовый код восстановления на случай потери устройства с TOTP.
+// В БД хранится только SHA-256 от кода.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"userId"`
+	CodeHash  string     `gorm:"not null" json:"-"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (RecoveryCode) TableName() string {
+	return "sipadmin.recovery_codes"
+}
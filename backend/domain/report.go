@@ -0,0 +1,85 @@
+package domain
+
+import "time"
+
+// ReportFilter общие параметры отчётов по звонкам
+type ReportFilter struct {
+	From       *time.Time   `query:"-"`
+	To         *time.Time   `query:"-"`
+	LocationID *uint        `query:"locationId"`
+	RingGroup  *int         `query:"ringGroup"`
+	Direction  CDRDirection `query:"direction"`
+	Limit      int          `query:"limit"`
+}
+
+// Число строк на группу в топ-отчётах по умолчанию и наибольшее (как perPage пагинации)
+const (
+	DefaultReportLimit = 10
+	MaxReportLimit     = 100
+)
+
+// TopLimit возвращает Limit, заменяя неположительный значением по умолчанию и ограничивая сверху
+func (f *ReportFilter) TopLimit() int {
+	if f == nil || f.Limit < 1 {
+		return DefaultReportLimit
+	}
+	if f.Limit > MaxReportLimit {
+		return MaxReportLimit
+	}
+	return f.Limit
+}
+
+// CallsPerDay количество звонков за день
+type CallsPerDay struct {
+	Day      time.Time `json:"day"`
+	Total    int64     `json:"total"`
+	Answered int64     `json:"answered"`
+	Missed   int64     `json:"missed"`
+}
+
+// RingGroupCalls отвеченные и пропущенные звонки ринг-группы
+type RingGroupCalls struct {
+	RingGroup    *int    `json:"ringGroup"`
+	LocationID   *uint   `json:"locationId"`
+	LocationName *string `json:"locationName"`
+	Total        int64   `json:"total"`
+	Answered     int64   `json:"answered"`
+	Missed       int64   `json:"missed"`
+}
+
+// CallDurations среднее время ожидания ответа и разговора
+type CallDurations struct {
+	RingGroup    *int     `json:"ringGroup"`
+	LocationID   *uint    `json:"locationId"`
+	LocationName *string  `json:"locationName"`
+	Calls        int64    `json:"calls"`
+	AvgRingSec   *float64 `json:"avgRingSec"`
+	AvgTalkSec   *float64 `json:"avgTalkSec"`
+}
+
+// HourlyCalls количество звонков по часу суток
+type HourlyCalls struct {
+	Hour     int   `json:"hour"`
+	Total    int64 `json:"total"`
+	Answered int64 `json:"answered"`
+	Missed   int64 `json:"missed"`
+}
+
+// TopCaller внешний абонент, чаще всего звонящий на городской номер
+type TopCaller struct {
+	CityNumber   string  `json:"cityNumber"`
+	Caller       string  `json:"caller"`
+	LocationID   *uint   `json:"locationId"`
+	LocationName *string `json:"locationName"`
+	Calls        int64   `json:"calls"`
+}
+
+// UnansweredDID неотвеченные входящие на городской номер
+type UnansweredDID struct {
+	CityNumber   string    `json:"cityNumber"`
+	RingGroup    *int      `json:"ringGroup"`
+	LocationID   *uint     `json:"locationId"`
+	LocationName *string   `json:"locationName"`
+	Unanswered   int64     `json:"unanswered"`
+	LastCall     time.Time `json:"lastCall"`
+}
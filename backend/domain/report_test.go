@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportFilterTopLimit(t *testing.T) {
+	var filter *ReportFilter
+	assert.Equal(t, DefaultReportLimit, filter.TopLimit())
+	assert.Equal(t, DefaultReportLimit, (&ReportFilter{Limit: -5}).TopLimit())
+	assert.Equal(t, 25, (&ReportFilter{Limit: 25}).TopLimit())
+	assert.Equal(t, MaxReportLimit, (&ReportFilter{Limit: 100000}).TopLimit())
+}
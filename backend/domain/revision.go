@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AuditActionRestore восстановление сущности из ревизии
+const AuditActionRestore AuditAction = "restore"
+
+// Revision полное состояние сущности после очередного изменения.
+// Для удаления Data хранит последнее состояние перед удалением.
+// Номер ревизии растёт с 1 отдельно для каждой сущности.
+type Revision struct {
+	ID         uint                   `gorm:"primaryKey" json:"id"`
+	EntityType AuditEntityType        `gorm:"not null;uniqueIndex:idx_revisions_entity" json:"entityType"`
+	EntityID   string                 `gorm:"not null;uniqueIndex:idx_revisions_entity" json:"entityId"`
+	Revision   int                    `gorm:"not null;uniqueIndex:idx_revisions_entity" json:"revision"`
+	Action     AuditAction            `gorm:"not null" json:"action"`
+	Data       map[string]interface{} `gorm:"serializer:json;type:jsonb" json:"data"`
+	UserID     *uint                  `json:"userId"`
+	Username   string                 `json:"username"`
+	CreatedAt  time.Time              `gorm:"index" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (Revision) TableName() string {
+	return "sipadmin.revisions"
+}
+
+// IsDeleted проверяет, что ревизия фиксирует удаление сущности (в корзину или окончательное)
+func (r *Revision) IsDeleted() bool {
+	return r.Action == AuditActionDelete || r.Action == AuditActionPurge
+}
+
+// Decode восстанавливает сущность из данных ревизии
+func (r *Revision) Decode(dest Auditable) error {
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal revision data")
+	}
+	return errors.Wrap(json.Unmarshal(data, dest), "failed to decode revision data")
+}
+
+// Snapshot состояние профилей, устройств и локаций на момент времени
+type Snapshot struct {
+	At        time.Time  `json:"at"`
+	Profiles  []Profile  `json:"profiles"`
+	Devices   []Device   `json:"devices"`
+	Locations []Location `json:"locations"`
+}
+
+// NewAuditable создаёт пустую сущность заданного типа
+func NewAuditable(entityType AuditEntityType) (Auditable, error) {
+	switch entityType {
+	case AuditEntityProfile:
+		return &Profile{}, nil
+	case AuditEntityDevice:
+		return &Device{}, nil
+	case AuditEntityLocation:
+		return &Location{}, nil
+	}
+	return nil, errors.Errorf("unknown entity type %q", entityType)
+}
+
+// NormalizeMAC приводит MAC-адрес к виду, в котором его хранит PostgreSQL (00:15:65:aa:bb:cc)
+func NormalizeMAC(mac string) string {
+	if hw, err := net.ParseMAC(mac); err == nil {
+		return hw.String()
+	}
+	return strings.ToLower(mac)
+}
+
+// RevisionData раскладывает сущность в данные ревизии так, как она видна в API
+func RevisionData(entity Auditable) (map[string]interface{}, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal revision data")
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal revision data")
+	}
+	return fields, nil
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MinSearchLength минимальная длина строки поиска
+	MinSearchLength = 2
+	// DefaultSearchLimit и MaxSearchLimit число результатов поиска по умолчанию и наибольшее
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchHitType тип найденной сущности
+type SearchHitType string
+
+const (
+	SearchHitProfile   SearchHitType = "profile"
+	SearchHitDevice    SearchHitType = "device"
+	SearchHitLocation  SearchHitType = "location"
+	SearchHitRingGroup SearchHitType = "ringGroup"
+)
+
+// SearchHit результат поиска. ID - идентификатор сущности (ID, MAC или номер группы),
+// Score - релевантность от 0 до 1, результаты отсортированы по ней
+type SearchHit struct {
+	Type     SearchHitType `json:"type"`
+	ID       string        `json:"id"`
+	Title    string        `json:"title"`
+	Subtitle string        `json:"subtitle"`
+	Score    float64       `json:"score"`
+}
+
+// SearchQuery разобранная строка поиска (GET /api/search?q=)
+type SearchQuery struct {
+	// Text строка поиска в нижнем регистре
+	Text string
+	// Digits цифры строки, как у номеров телефонов (CleanPhoneNumber): "24-48" -> "2448"
+	Digits string
+	// NumberOnly - строка состоит из цифр и разделителей номера, ищется как номер
+	NumberOnly bool
+	// MACHex шестнадцатеричные цифры, если строка похожа на часть MAC в любом формате
+	MACHex string
+	// Types типы сущностей, которые ищутся
+	Types []SearchHitType
+	Limit int
+}
+
+// NewSearchQuery разбирает строку поиска; limit вне допустимого диапазона заменяется значением по умолчанию
+func NewSearchQuery(q string, limit int, types []SearchHitType) (*SearchQuery, error) {
+	text := strings.ToLower(strings.TrimSpace(q))
+	if utf8.RuneCountInString(text) < MinSearchLength {
+		return nil, ValidationErrors{"q": "Must be at least 2 characters"}
+	}
+	if limit < 1 || limit > MaxSearchLimit {
+		limit = DefaultSearchLimit
+	}
+
+	query := &SearchQuery{
+		Text:   text,
+		Digits: CleanPhoneNumber(text),
+		Types:  types,
+		Limit:  limit,
+	}
+	query.NumberOnly = query.Digits != "" && strings.Trim(text, "0123456789-+() ") == ""
+	if hex := macHex(text); utf8.RuneCountInString(hex) >= MinSearchLength {
+		query.MACHex = hex
+	}
+	return query, nil
+}
+
+// Has проверяет, ищется ли сущность типа t
+func (q *SearchQuery) Has(t SearchHitType) bool {
+	for _, searched := range q.Types {
+		if searched == t {
+			return true
+		}
+	}
+	return false
+}
+
+// macHex убирает разделители MAC (":", "-", ".", пробелы); пустая строка, если в ней есть другие символы
+func macHex(text string) string {
+	var sb strings.Builder
+	for _, char := range text {
+		switch {
+		case char >= '0' && char <= '9', char >= 'a' && char <= 'f':
+			sb.WriteRune(char)
+		case char == ':' || char == '-' || char == '.' || char == ' ':
+		default:
+			return ""
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSearchQuery(t *testing.T) {
+	all := []SearchHitType{SearchHitProfile, SearchHitRingGroup}
+
+	tests := []struct {
+		q          string
+		digits     string
+		numberOnly bool
+		macHex     string
+	}{
+		{q: "  Иванов ", digits: "", numberOnly: false, macHex: ""},
+		{q: "24-48", digits: "2448", numberOnly: true, macHex: "2448"},
+		{q: "+7 (3452) 24-48", digits: "734522448", numberOnly: true, macHex: ""},
+		{q: "80:5E:C0", digits: "8050", numberOnly: false, macHex: "805ec0"},
+		{q: "805e.c018", digits: "805018", numberOnly: false, macHex: "805ec018"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			query, err := NewSearchQuery(tt.q, 0, all)
+			require.NoError(t, err)
+			assert.Equal(t, tt.digits, query.Digits)
+			assert.Equal(t, tt.numberOnly, query.NumberOnly)
+			assert.Equal(t, tt.macHex, query.MACHex)
+			assert.Equal(t, DefaultSearchLimit, query.Limit)
+			assert.True(t, query.Has(SearchHitRingGroup))
+			assert.False(t, query.Has(SearchHitDevice))
+		})
+	}
+
+	_, err := NewSearchQuery(" я ", 10, all)
+	assert.Equal(t, []string{"q"}, fieldNames(err.(ValidationErrors)))
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// Session сессия пользователя, к которой привязаны refresh-токен и короткие access-токены.
+// В БД хранится только SHA-256 от refresh-токена; PreviousTokenHash нужен, чтобы
+// распознать повторное использование уже обменянного токена.
+type Session struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	UserID            uint       `gorm:"index;not null" json:"userId"`
+	TokenHash         string     `gorm:"uniqueIndex;not null" json:"-"`
+	PreviousTokenHash string     `gorm:"index" json:"-"`
+	IP                string     `json:"ip"`
+	UserAgent         string     `json:"userAgent"`
+	ExpiresAt         time.Time  `gorm:"index" json:"expiresAt"`
+	LastUsedAt        time.Time  `json:"lastUsedAt"`
+	RevokedAt         *time.Time `json:"revokedAt"`
+	RevokeReason      string     `json:"revokeReason,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+// TableName указывает имя таблицы в БД
+func (Session) TableName() string {
+	return "sipadmin.sessions"
+}
+
+// IsActive проверяет, что сессия не отозвана и не истекла
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// SessionResponse DTO сессии для списка; Current - сессия текущего запроса
+type SessionResponse struct {
+	Session
+	Current bool `json:"current"`
+}
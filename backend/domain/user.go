@@ -14,14 +14,41 @@ const (
 	UserRoleUser  UserRole = "user"
 )
 
-// User представляет пользователя системы
+// AuthSource источник проверки пароля пользователя
+type AuthSource string
+
+const (
+	// AuthSourceLocal пароль хранится в БД
+	AuthSourceLocal AuthSource = "local"
+	// AuthSourceLDAP пароль проверяется в LDAP / Active Directory, роль берётся из групп
+	AuthSourceLDAP AuthSource = "ldap"
+	// AuthSourceOIDC вход через провайдера OpenID Connect, роль берётся из claims
+	AuthSourceOIDC AuthSource = "oidc"
+)
+
+// User представляет пользователя системы.
+// MustChangePassword - пользователь обязан сменить пароль, прежде чем работать с API.
+// TOTPSecret заполняется при настройке второго фактора, TOTPEnabled - после подтверждения кодом;
+// TOTPLastStep - шаг последнего принятого кода, чтобы один код нельзя было предъявить дважды.
 type User struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	Username     string    `gorm:"uniqueIndex;not null" json:"username"`
-	PasswordHash string    `gorm:"not null" json:"-"`
-	Role         UserRole  `gorm:"default:user" json:"role"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	Username           string     `gorm:"uniqueIndex;not null" json:"username"`
+	PasswordHash       string     `gorm:"not null" json:"-"`
+	Role               UserRole   `gorm:"default:user" json:"role"`
+	LocationID         *uint      `json:"locationId"`
+	AuthSource         AuthSource `gorm:"default:local" json:"authSource"`
+	IsActive           bool       `gorm:"default:true" json:"isActive"`
+	MustChangePassword bool       `gorm:"default:false" json:"mustChangePassword"`
+	TOTPSecret         string     `gorm:"column:totp_secret" json:"-"`
+	TOTPEnabled        bool       `gorm:"column:totp_enabled;default:false" json:"totpEnabled"`
+	TOTPLastStep       int64      `gorm:"column:totp_last_step;default:0" json:"-"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
+}
+
+// IsValid проверяет, что роль известна
+func (r UserRole) IsValid() bool {
+	return r == UserRoleAdmin || r == UserRoleUser
 }
 
 // TableName указывает имя таблицы в БД
@@ -29,6 +56,11 @@ func (User) TableName() string {
 	return "sipadmin.users"
 }
 
+// IsExternal проверяет, управляются ли пароль и роль пользователя внешним источником
+func (u *User) IsExternal() bool {
+	return u.AuthSource == AuthSourceLDAP || u.AuthSource == AuthSourceOIDC
+}
+
 // SetPassword устанавливает хеш пароля
 func (u *User) SetPassword(password string) error {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -47,18 +79,30 @@ func (u *User) CheckPassword(password string) bool {
 
 // UserResponse DTO для ответа (без пароля)
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Role      UserRole  `json:"role"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID                 uint         `json:"id"`
+	Username           string       `json:"username"`
+	Role               UserRole     `json:"role"`
+	LocationID         *uint        `json:"locationId"`
+	AuthSource         AuthSource   `json:"authSource"`
+	IsActive           bool         `json:"isActive"`
+	MustChangePassword bool         `json:"mustChangePassword"`
+	TOTPEnabled        bool         `json:"totpEnabled"`
+	Permissions        []Permission `json:"permissions"`
+	CreatedAt          time.Time    `json:"createdAt"`
 }
 
 // ToResponse конвертирует User в UserResponse
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Username:  u.Username,
-		Role:      u.Role,
-		CreatedAt: u.CreatedAt,
+		ID:                 u.ID,
+		Username:           u.Username,
+		Role:               u.Role,
+		LocationID:         u.LocationID,
+		AuthSource:         u.AuthSource,
+		IsActive:           u.IsActive,
+		MustChangePassword: u.MustChangePassword,
+		TOTPEnabled:        u.TOTPEnabled,
+		Permissions:        u.Role.Permissions(),
+		CreatedAt:          u.CreatedAt,
 	}
 }
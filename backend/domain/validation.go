@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"sort"
+	"strings"
+)
+
+// Ограничения значений профилей и локаций
+const (
+	MinInternalNumber = 1000 // внутренний номер - 4 цифры
+	MaxInternalNumber = 9999
+	MinVLAN           = 1
+	MaxVLAN           = 4094
+)
+
+// ValidationErrors ошибки проверки запроса по полям (имя поля в JSON - сообщение)
+type ValidationErrors map[string]string
+
+// Add добавляет ошибку поля; первая ошибка поля сохраняется
+func (e ValidationErrors) Add(field, message string) {
+	if _, ok := e[field]; !ok {
+		e[field] = message
+	}
+}
+
+// Err возвращает nil, если ошибок нет
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error реализует error
+func (e ValidationErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field, message := range e {
+		fields = append(fields, field+": "+message)
+	}
+	sort.Strings(fields)
+	return "validation failed: " + strings.Join(fields, "; ")
+}
+
+// Validate проверяет поля профиля, не требующие обращения к БД
+func (p *Profile) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+	if strings.TrimSpace(p.Name) == "" {
+		errs.Add("name", "Is required")
+	}
+	if p.Email != "" && !isEmail(p.Email) {
+		errs.Add("email", "Invalid email address")
+	}
+	if p.InternalNumber < MinInternalNumber || p.InternalNumber > MaxInternalNumber {
+		errs.Add("internalNumber", fmt.Sprintf("Must be between %d and %d", MinInternalNumber, MaxInternalNumber))
+	}
+	if p.Device != nil && !isMAC(*p.Device) {
+		errs.Add("device", "Invalid MAC address")
+	}
+	if p.RingGroup != nil && *p.RingGroup < 0 {
+		errs.Add("ringGroup", "Must not be negative")
+	}
+	if p.PickupGroup != nil && *p.PickupGroup < 0 {
+		errs.Add("pickupGroup", "Must not be negative")
+	}
+	return errs
+}
+
+// Validate проверяет поля устройства
+func (d *Device) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+	if !isMAC(d.MAC) {
+		errs.Add("mac", "Invalid MAC address")
+	}
+	if !d.DeviceModel.IsValid() {
+		errs.Add("deviceModel", "Unknown device model")
+	}
+	return errs
+}
+
+// Validate проверяет поля локации: подсеть задаётся адресом сети с префиксом,
+// сервер - адресом узла той же версии IP, и если он внутри подсети телефонов,
+// то не адресом сети или широковещательным. Сервер может быть и вне подсети (SIP-сервер
+// обычно в другой сети); доступность сервера из подсети не проверяется.
+func (l *Location) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+	if strings.TrimSpace(l.Name) == "" {
+		errs.Add("name", "Is required")
+	}
+
+	ip, subnet, err := net.ParseCIDR(l.Subnet)
+	switch {
+	case err != nil:
+		errs.Add("subnet", "Must be a network in CIDR notation, e.g. 10.1.191.0/26")
+	case !ip.Equal(subnet.IP):
+		errs.Add("subnet", "Host bits must be zero, expected "+subnet.String())
+	}
+
+	server := net.ParseIP(l.Server)
+	switch {
+	case server == nil:
+		errs.Add("server", "Invalid IP address")
+	case server.IsUnspecified() || server.IsLoopback() || server.IsMulticast():
+		errs.Add("server", "Must be a host address")
+	case subnet != nil && (server.To4() == nil) != (subnet.IP.To4() == nil):
+		errs.Add("server", "Must be of the same IP version as the subnet")
+	case subnet != nil && subnet.Contains(server) && (server.Equal(subnet.IP) || server.Equal(broadcast(subnet))):
+		errs.Add("server", "Must not be the network or broadcast address of the location subnet")
+	}
+
+	if l.VoipVLAN < MinVLAN || l.VoipVLAN > MaxVLAN {
+		errs.Add("voipVlan", fmt.Sprintf("Must be between %d and %d", MinVLAN, MaxVLAN))
+	}
+	if l.VLAN < MinVLAN || l.VLAN > MaxVLAN {
+		errs.Add("vlan", fmt.Sprintf("Must be between %d and %d", MinVLAN, MaxVLAN))
+	}
+	return errs
+}
+
+// isMAC проверяет MAC-адрес (EUI-48) в любом из форматов, которые принимает PostgreSQL macaddr
+func isMAC(value string) bool {
+	hw, err := net.ParseMAC(value)
+	return err == nil && len(hw) == 6
+}
+
+// isEmail проверяет, что строка - один адрес без отображаемого имени
+func isEmail(value string) bool {
+	address, err := mail.ParseAddress(value)
+	return err == nil && address.Address == value && address.Name == ""
+}
+
+// broadcast возвращает последний адрес подсети
+func broadcast(subnet *net.IPNet) net.IP {
+	ip := make(net.IP, len(subnet.IP))
+	for i := range subnet.IP {
+		ip[i] = subnet.IP[i] | ^subnet.Mask[i]
+	}
+	return ip
+}
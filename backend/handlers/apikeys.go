@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultAPIKeyTTL срок действия ключа, если expiresAt не указан
+const defaultAPIKeyTTL = 365 * 24 * time.Hour
+
+// CreateAPIKeyRequest запрос на создание API-ключа
+type CreateAPIKeyRequest struct {
+	Name      string              `json:"name"`
+	Scopes    []domain.Permission `json:"scopes"`
+	ExpiresAt *time.Time          `json:"expiresAt"`
+}
+
+// CreateAPIKeyResponse созданный ключ; значение key показывается только здесь
+type CreateAPIKeyResponse struct {
+	domain.APIKey
+	Key string `json:"key"`
+}
+
+// GetAPIKeys возвращает список API-ключей
+func (h *AuthHandler) GetAPIKeys(c *fiber.Ctx) error {
+	keys, err := h.repos.FindAPIKeys()
+	if err != nil {
+		return err
+	}
+	return c.JSON(keys)
+}
+
+// CreateAPIKey создает API-ключ от имени текущего пользователя
+func (h *AuthHandler) CreateAPIKey(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*services.JWTClaims)
+
+	var req CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Name == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Name is required")
+	}
+	if req.ExpiresAt == nil {
+		expiresAt := time.Now().Add(defaultAPIKeyTTL)
+		req.ExpiresAt = &expiresAt
+	}
+	if !req.ExpiresAt.After(time.Now()) {
+		return fiber.NewError(fiber.StatusBadRequest, "Expiry must be in the future")
+	}
+
+	var creator domain.User
+	if err := h.repos.FindByID(&creator, claims.UserID); err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "User not found")
+	}
+
+	key, value, err := h.authService.CreateAPIKey(&creator, req.Name, req.Scopes, req.ExpiresAt)
+	if errors.Is(err, services.ErrInvalidScope) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(CreateAPIKeyResponse{
+		APIKey: *key,
+		Key:    value,
+	})
+}
+
+// RevokeAPIKey отзывает API-ключ
+func (h *AuthHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	var key domain.APIKey
+	if err := h.repos.FindByID(&key, c.Params("id")); err != nil {
+		return err
+	}
+
+	if err := h.authService.RevokeAPIKey(key.ID); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetAPIKeyUsage возвращает журнал использования API-ключа
+func (h *AuthHandler) GetAPIKeyUsage(c *fiber.Ctx) error {
+	pagination, ok := c.Locals("pagination").(*domain.PaginationInput)
+	if !ok {
+		return fiber.NewError(fiber.StatusInternalServerError, "Pagination not found in context")
+	}
+
+	var key domain.APIKey
+	if err := h.repos.FindByID(&key, c.Params("id")); err != nil {
+		return err
+	}
+
+	usage, total, err := h.repos.FindAPIKeyUsage(key.ID, pagination)
+	if err != nil {
+		return err
+	}
+
+	paginationResponse := domain.PaginationResponse{
+		Total:   total,
+		Page:    pagination.Page,
+		PerPage: pagination.PerPage,
+	}
+	paginationResponse.CalculatePages()
+
+	return c.JSON(domain.PaginatedResult{
+		Data:       usage,
+		Pagination: paginationResponse,
+	})
+}
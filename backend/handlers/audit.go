@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// audited выполняет изменение и в той же транзакции записывает его в журнал аудита
+// и историю ревизий. before - состояние до изменения (nil при создании), after - после
+// (nil при удалении). Изменение без отличий в полях не записывается.
+func (h *Handler) audited(c *fiber.Ctx, action domain.AuditAction, before, after domain.Auditable, change func(tx *repositories.Repos) error) error {
+	return h.repos.Transaction(func(tx *repositories.Repos) error {
+		return auditedIn(tx, c, action, before, after, change)
+	})
+}
+
+// auditedIn как audited, но внутри уже открытой транзакции tx -
+// для нескольких изменений, которые должны примениться вместе
+func auditedIn(tx *repositories.Repos, c *fiber.Ctx, action domain.AuditAction, before, after domain.Auditable, change func(tx *repositories.Repos) error) error {
+	if err := change(tx); err != nil {
+		return err
+	}
+	return services.RecordAudit(tx, action, before, after, auditActor(c))
+}
+
+// auditActor возвращает автора изменения из запроса: пользователя или API-ключ и IP
+func auditActor(c *fiber.Ctx) services.AuditActor {
+	actor := services.AuditActor{IP: c.IP()}
+	if claims, ok := c.Locals("user").(*services.JWTClaims); ok {
+		actor.Username = claims.Username
+		if claims.APIKeyID != 0 {
+			actor.APIKeyID = &claims.APIKeyID
+		}
+		if claims.UserID != 0 {
+			actor.UserID = &claims.UserID
+		}
+	}
+	return actor
+}
+
+// GetAudit возвращает журнал аудита
+// (?entityType=profile&entityId=12&userId=1&username=admin&action=update&from=2024-01-01&to=2024-01-31)
+func (h *Handler) GetAudit(c *fiber.Ctx) error {
+	pagination, ok := c.Locals("pagination").(*domain.PaginationInput)
+	if !ok {
+		return fiber.NewError(fiber.StatusInternalServerError, "Pagination not found in context")
+	}
+
+	var filter domain.AuditFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid filter parameters")
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return err
+	}
+	filter.From = from
+	filter.To = to
+
+	entries, total, err := h.repos.FindAuditEntries(&filter, pagination)
+	if err != nil {
+		return err
+	}
+
+	paginationResponse := domain.PaginationResponse{
+		Total:   total,
+		Page:    pagination.Page,
+		PerPage: pagination.PerPage,
+	}
+	paginationResponse.CalculatePages()
+
+	return c.JSON(domain.PaginatedResult{
+		Data:       entries,
+		Pagination: paginationResponse,
+	})
+}
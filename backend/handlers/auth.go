@@ -1,6 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
 	"asterisk-manager/domain"
 	"asterisk-manager/services"
 
@@ -11,13 +16,15 @@ import (
 type AuthHandler struct {
 	*Handler
 	authService *services.AuthService
+	loginGuard  *services.LoginGuard
 }
 
 // NewAuthHandler создает новый хендлер авторизации
 func NewAuthHandler(handler *Handler) *AuthHandler {
 	return &AuthHandler{
 		Handler:     handler,
-		authService: services.NewAuthService(),
+		authService: services.NewAuthService(handler.repos),
+		loginGuard:  services.NewLoginGuard(handler.repos, services.LoginGuardConfigFromEnv()),
 	}
 }
 
@@ -27,10 +34,30 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
-// LoginResponse ответ с токеном
+// LoginResponse ответ с токенами; refreshToken выдаётся при входе и обновлении
 type LoginResponse struct {
-	Token string              `json:"token"`
-	User  domain.UserResponse `json:"user"`
+	Token        string              `json:"token"`
+	RefreshToken string              `json:"refreshToken,omitempty"`
+	ExpiresAt    time.Time           `json:"expiresAt"`
+	User         domain.UserResponse `json:"user"`
+}
+
+// MFAChallengeResponse ответ на верный пароль, если у пользователя подключён второй фактор
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`
+}
+
+// LoginMFARequest второй шаг входа: код TOTP или один из кодов восстановления
+type LoginMFARequest struct {
+	MFAToken     string `json:"mfaToken"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// RefreshRequest запрос на обновление токенов
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
 }
 
 // Login авторизация пользователя
@@ -44,29 +71,240 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Username and password are required")
 	}
 
-	// Ищем пользователя
-	var user domain.User
-	if err := h.repos.FindUserByUsername(&user, req.Username); err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "Invalid credentials")
+	// Защита от подбора: проверяем блокировку до проверки пароля
+	ip := c.IP()
+	wait, err := h.loginGuard.Check(req.Username, ip)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many failed login attempts, try again later")
 	}
 
-	// Проверяем пароль
-	if !user.CheckPassword(req.Password) {
-		return fiber.NewError(fiber.StatusUnauthorized, "Invalid credentials")
+	user, err := h.authService.Authenticate(req.Username, req.Password)
+	switch {
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return h.loginFailed(req.Username, ip, "invalid credentials",
+			fiber.NewError(fiber.StatusUnauthorized, "Invalid credentials"))
+	case errors.Is(err, services.ErrUserDisabled):
+		return h.loginFailed(req.Username, ip, "disabled",
+			fiber.NewError(fiber.StatusForbidden, "Account is disabled"))
+	case errors.Is(err, services.ErrLDAPNotAllowed):
+		return h.loginFailed(req.Username, ip, "not allowed",
+			fiber.NewError(fiber.StatusForbidden, "Access is not granted to this directory account"))
+	case err != nil:
+		return err
 	}
 
-	// Генерируем токен
-	token, err := h.authService.GenerateToken(&user)
+	// Пароль верный, но вход завершится только после проверки второго фактора
+	if user.TOTPEnabled {
+		mfaToken, err := h.authService.IssueMFAToken(user)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to generate token")
+		}
+		return c.JSON(MFAChallengeResponse{MFARequired: true, MFAToken: mfaToken})
+	}
+
+	return h.completeLogin(c, user, ip)
+}
+
+// LoginMFA второй шаг входа: обменивает промежуточный токен и код на сессию
+func (h *AuthHandler) LoginMFA(c *fiber.Ctx) error {
+	var req LoginMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return fiber.NewError(fiber.StatusBadRequest, "MFA token and code are required")
+	}
+
+	claims, err := h.authService.ParseMFAToken(req.MFAToken)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	}
+
+	// Неверные коды учитываются так же, как неверные пароли
+	ip := c.IP()
+	wait, err := h.loginGuard.Check(claims.Username, ip)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many failed login attempts, try again later")
+	}
+
+	user, err := h.authService.VerifyMFA(claims, req.Code, req.RecoveryCode)
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode):
+		return h.loginFailed(claims.Username, ip, "invalid two-factor code",
+			fiber.NewError(fiber.StatusUnauthorized, "Invalid two-factor code"))
+	case errors.Is(err, services.ErrInvalidMFAToken):
+		return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrUserDisabled):
+		return fiber.NewError(fiber.StatusForbidden, "Account is disabled")
+	case err != nil:
+		return err
+	}
+
+	return h.completeLogin(c, user, ip)
+}
+
+// completeLogin сбрасывает счётчик неудач и открывает сессию
+func (h *AuthHandler) completeLogin(c *fiber.Ctx, user *domain.User, ip string) error {
+	if err := h.loginGuard.RecordSuccess(user.Username, ip); err != nil {
+		return err
+	}
+
+	pair, err := h.authService.StartSession(user, ip, c.Get(fiber.HeaderUserAgent))
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to generate token")
 	}
 
+	return sendTokens(c, user, pair)
+}
+
+// loginFailed учитывает неудачную попытку входа и возвращает ошибку для клиента
+func (h *AuthHandler) loginFailed(username, ip, reason string, response error) error {
+	if err := h.loginGuard.RecordFailure(username, ip, reason); err != nil {
+		return err
+	}
+	return response
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Refresh token is required")
+	}
+
+	pair, user, err := h.authService.Refresh(req.RefreshToken, c.IP(), c.Get(fiber.HeaderUserAgent))
+	if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+		return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	return sendTokens(c, user, pair)
+}
+
+// Logout отзывает текущую сессию
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*services.JWTClaims)
+	if err := h.authService.RevokeSession(claims.SessionID, services.RevokeReasonLogout); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// sendTokens отправляет клиенту выданные токены
+func sendTokens(c *fiber.Ctx, user *domain.User, pair *services.TokenPair) error {
 	return c.JSON(LoginResponse{
-		Token: token,
-		User:  user.ToResponse(),
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresAt:    pair.ExpiresAt,
+		User:         user.ToResponse(),
 	})
 }
 
+// ChangePasswordRequest запрос на смену собственного пароля
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// ChangePassword меняет пароль текущего пользователя, завершает его остальные
+// сессии и выдаёт новый access-токен для текущей
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*services.JWTClaims)
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	var user domain.User
+	if err := h.repos.FindByID(&user, claims.UserID); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	if user.IsExternal() {
+		return fiber.NewError(fiber.StatusBadRequest, "Password of external user is managed by the identity provider")
+	}
+	if !user.CheckPassword(req.CurrentPassword) {
+		return fiber.NewError(fiber.StatusBadRequest, "Current password is incorrect")
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return fiber.NewError(fiber.StatusBadRequest, "New password must differ from the current one")
+	}
+	if err := h.authService.PasswordPolicy().Validate(req.NewPassword, user.Username); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := user.SetPassword(req.NewPassword); err != nil {
+		return err
+	}
+	user.MustChangePassword = false
+
+	if err := h.repos.Save(&user); err != nil {
+		return err
+	}
+	if err := h.authService.RevokeUserSessions(user.ID, claims.SessionID, services.RevokeReasonPasswordChanged); err != nil {
+		return err
+	}
+
+	token, expiresAt, err := h.authService.GenerateToken(&user, claims.SessionID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to generate token")
+	}
+
+	return sendTokens(c, &user, &services.TokenPair{AccessToken: token, ExpiresAt: expiresAt})
+}
+
+// GetSessions возвращает действующие сессии текущего пользователя
+func (h *AuthHandler) GetSessions(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*services.JWTClaims)
+	return h.sendSessions(c, claims.UserID, claims.SessionID)
+}
+
+// DeleteSession завершает одну из сессий текущего пользователя
+func (h *AuthHandler) DeleteSession(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*services.JWTClaims)
+
+	var session domain.Session
+	if err := h.repos.FindByID(&session, c.Params("id")); err != nil {
+		return err
+	}
+	if session.UserID != claims.UserID {
+		return fiber.NewError(fiber.StatusNotFound, "Session not found")
+	}
+
+	if err := h.authService.RevokeSession(session.ID, services.RevokeReasonLogout); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// sendSessions отправляет список действующих сессий пользователя
+func (h *AuthHandler) sendSessions(c *fiber.Ctx, userID, currentID uint) error {
+	sessions, err := h.authService.ActiveSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	response := make([]domain.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, domain.SessionResponse{
+			Session: session,
+			Current: session.ID == currentID,
+		})
+	}
+	return c.JSON(response)
+}
+
 // Me возвращает текущего пользователя
 func (h *AuthHandler) Me(c *fiber.Ctx) error {
 	claims := c.Locals("user").(*services.JWTClaims)
@@ -79,6 +317,11 @@ func (h *AuthHandler) Me(c *fiber.Ctx) error {
 	return c.JSON(user.ToResponse())
 }
 
+// GetLoginGuard возвращает защиту входа для фоновой очистки
+func (h *AuthHandler) GetLoginGuard() *services.LoginGuard {
+	return h.loginGuard
+}
+
 // GetAuthService возвращает сервис авторизации для middleware
 func (h *AuthHandler) GetAuthService() *services.AuthService {
 	return h.authService
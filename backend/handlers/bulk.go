@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// errBulkRollback откатывает atomic-операцию, в которой не все профили изменены
+var errBulkRollback = errors.New("bulk operation rolled back")
+
+// BulkProfiles массово изменяет профили, выбранные списком ID или фильтром: переносит в локацию,
+// задаёт группы вызова и перехвата, включает или выключает, снимает устройства.
+// Каждый профиль изменяется в своей точке сохранения; в режиме atomic при любой ошибке
+// отменяется всё (422), в bestEffort сохраняются успешные изменения. В ответе - результат по каждому профилю.
+func (h *Handler) BulkProfiles(c *fiber.Ctx) error {
+	var request domain.BulkProfileRequest
+	if err := c.BodyParser(&request); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := request.Validate().Err(); err != nil {
+		return err
+	}
+
+	ids := request.IDs
+	if request.Filter != nil {
+		var err error
+		if ids, err = h.repos.FindProfileIDs(request.Filter); err != nil {
+			return err
+		}
+		if len(ids) > domain.MaxBulkProfiles {
+			return domain.ValidationErrors{"filter": "Matches more than 1000 profiles, narrow it down"}
+		}
+	}
+
+	result := domain.BulkResult{
+		Mode:    request.Mode,
+		Total:   len(ids),
+		Results: make([]domain.BulkItemResult, 0, len(ids)),
+	}
+
+	err := h.repos.Transaction(func(tx *repositories.Repos) error {
+		// Изменяемые поля проверены в request.Validate; остаётся существование локации,
+		// проверяемое в той же транзакции, что и перенос
+		if request.Set.LocationID != nil {
+			exists, err := tx.Exists(&domain.Location{}, "id = ?", *request.Set.LocationID)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return domain.ValidationErrors{"set.locationId": "Location not found"}
+			}
+		}
+
+		for _, id := range ids {
+			item := h.bulkUpdateProfile(tx, c, id, &request.Set)
+			result.Results = append(result.Results, item)
+		}
+
+		for _, item := range result.Results {
+			switch item.Status {
+			case domain.BulkItemUpdated:
+				result.Updated++
+			case domain.BulkItemUnchanged:
+				result.Unchanged++
+			case domain.BulkItemFailed:
+				result.Failed++
+			}
+		}
+
+		if result.Failed > 0 && request.Mode == domain.BulkModeAtomic {
+			return errBulkRollback
+		}
+		return nil
+	})
+
+	if errors.Is(err, errBulkRollback) {
+		for i := range result.Results {
+			if result.Results[i].Status == domain.BulkItemUpdated {
+				result.Results[i].Status = domain.BulkItemRolledBack
+			}
+		}
+		result.Updated = 0
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(result)
+	}
+	if err != nil {
+		return err
+	}
+
+	result.Applied = true
+	return c.JSON(result)
+}
+
+// bulkUpdateProfile изменяет один профиль в точке сохранения внутри tx; ошибка откатывает
+// только этот профиль и попадает в результат
+func (h *Handler) bulkUpdateProfile(tx *repositories.Repos, c *fiber.Ctx, id uint, changes *domain.BulkProfileChanges) domain.BulkItemResult {
+	item := domain.BulkItemResult{ID: id, Status: domain.BulkItemUpdated}
+
+	err := tx.Transaction(func(tx *repositories.Repos) error {
+		var profile domain.Profile
+		if err := tx.FindByID(&profile, id); err != nil {
+			return err
+		}
+
+		before := profile
+		changes.Apply(&profile)
+
+		diff, err := services.AuditDiff(&before, &profile)
+		if err != nil {
+			return err
+		}
+		if len(diff) == 0 {
+			item.Status = domain.BulkItemUnchanged
+			return nil
+		}
+
+		// Профиль целиком не проверяется: неизменяемые поля, сохранённые до появления проверок
+		// (например, email старого формата), не должны мешать массовой операции
+		return auditedIn(tx, c, domain.AuditActionUpdate, &before, &profile, func(tx *repositories.Repos) error {
+			return tx.Save(&profile)
+		})
+	})
+	if err == nil {
+		return item
+	}
+
+	item.Status = domain.BulkItemFailed
+	var validationErrors domain.ValidationErrors
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		item.Error = "Not found"
+	case errors.As(err, &validationErrors):
+		item.Error = "Validation failed"
+		item.Fields = validationErrors
+	default:
+		if _, response, ok := databaseError(err); ok {
+			item.Error = response.Error
+			item.Fields = response.Fields
+		} else {
+			requestID, _ := c.Locals("requestid").(string)
+			log.Printf("[%s] Bulk update of profile %d failed: %v", requestID, id, err)
+			item.Error = "Internal server error"
+		}
+	}
+	return item
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories/dbtest"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkProfilesModes(t *testing.T) {
+	repos := dbtest.Open(t)
+	h := NewHandler(repos)
+	app := fiber.New(fiber.Config{ErrorHandler: h.ErrorHandler})
+	app.Post("/profiles/bulk", h.BulkProfiles)
+
+	center := domain.Location{Name: "Центр", Server: "10.0.0.1", Subnet: "10.0.0.0/24", VoipVLAN: 100, VLAN: 10}
+	branch := domain.Location{Name: "Филиал", Server: "10.0.1.1", Subnet: "10.0.1.0/24", VoipVLAN: 101, VLAN: 11}
+	require.NoError(t, repos.Create(&center))
+	require.NoError(t, repos.Create(&branch))
+	// Email старого формата не проходит проверку профиля, но не мешает переносу
+	legacy := domain.Profile{Name: "Иванов", Email: "ivanov", LocationID: &center.ID, InternalNumber: 6101, IsActive: true}
+	other := domain.Profile{Name: "Петров", LocationID: &center.ID, InternalNumber: 6102, IsActive: true}
+	require.NoError(t, repos.Create(&legacy))
+	require.NoError(t, repos.Create(&other))
+
+	bulk := func(mode domain.BulkMode, ids ...uint) (int, domain.BulkResult) {
+		request := domain.BulkProfileRequest{IDs: ids, Set: domain.BulkProfileChanges{LocationID: &branch.ID}, Mode: mode}
+		payload, err := json.Marshal(request)
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/profiles/bulk", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var result domain.BulkResult
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, result
+	}
+	locationOf := func(id uint) uint {
+		var profile domain.Profile
+		require.NoError(t, repos.FindByID(&profile, id))
+		return *profile.LocationID
+	}
+	statuses := func(result domain.BulkResult) []domain.BulkItemStatus {
+		var statuses []domain.BulkItemStatus
+		for _, item := range result.Results {
+			statuses = append(statuses, item.Status)
+		}
+		return statuses
+	}
+
+	// atomic: несуществующий профиль отменяет перенос остальных
+	status, result := bulk(domain.BulkModeAtomic, legacy.ID, other.ID, 999)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.False(t, result.Applied)
+	assert.Equal(t, 0, result.Updated)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, []domain.BulkItemStatus{domain.BulkItemRolledBack, domain.BulkItemRolledBack, domain.BulkItemFailed}, statuses(result))
+	assert.Equal(t, center.ID, locationOf(legacy.ID))
+	assert.Equal(t, center.ID, locationOf(other.ID))
+
+	// bestEffort: ошибка откатывает только свою точку сохранения
+	status, result = bulk(domain.BulkModeBestEffort, legacy.ID, 999, other.ID)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.True(t, result.Applied)
+	assert.Equal(t, 2, result.Updated)
+	assert.Equal(t, []domain.BulkItemStatus{domain.BulkItemUpdated, domain.BulkItemFailed, domain.BulkItemUpdated}, statuses(result))
+	assert.Equal(t, "Not found", result.Results[1].Error)
+	assert.Equal(t, branch.ID, locationOf(legacy.ID))
+	assert.Equal(t, branch.ID, locationOf(other.ID))
+
+	var entries []domain.AuditEntry
+	require.NoError(t, repos.FindAll(&entries))
+	assert.Len(t, entries, 2)
+
+	// Повтор ничего не меняет
+	status, result = bulk(domain.BulkModeAtomic, legacy.ID, other.ID)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 2, result.Unchanged)
+}
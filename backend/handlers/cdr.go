@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetCDR возвращает журнал звонков с фильтрацией и пагинацией
+func (h *Handler) GetCDR(c *fiber.Ctx) error {
+	pagination, ok := c.Locals("pagination").(*domain.PaginationInput)
+	if !ok {
+		return fiber.NewError(fiber.StatusInternalServerError, "Pagination not found in context")
+	}
+
+	var filter domain.CDRFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid filter parameters")
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return err
+	}
+	filter.From = from
+	filter.To = to
+
+	records, total, err := h.repos.FindCDR(&filter, pagination)
+	if err != nil {
+		return err
+	}
+
+	paginationResponse := domain.PaginationResponse{
+		Total:   total,
+		Page:    pagination.Page,
+		PerPage: pagination.PerPage,
+	}
+	paginationResponse.CalculatePages()
+
+	return c.JSON(domain.PaginatedResult{
+		Data:       records,
+		Pagination: paginationResponse,
+	})
+}
+
+// parseDateRange разбирает параметры from/to (YYYY-MM-DD или RFC 3339).
+// Дата без времени в to включается в диапазон целиком.
+func parseDateRange(c *fiber.Ctx) (*time.Time, *time.Time, error) {
+	from, _, err := parseTimeParam(c, "from")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	to, dateOnly, err := parseTimeParam(c, "to")
+	if err != nil {
+		return nil, nil, err
+	}
+	if to != nil && dateOnly {
+		next := to.AddDate(0, 0, 1)
+		to = &next
+	}
+
+	return from, to, nil
+}
+
+func parseTimeParam(c *fiber.Ctx, name string) (*time.Time, bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, false, nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return &t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, false, nil
+	}
+
+	return nil, false, fiber.NewError(fiber.StatusBadRequest, "Invalid date parameter: "+name)
+}
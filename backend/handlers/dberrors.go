@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Коды ошибок PostgreSQL, которые возвращаются клиенту как ошибки запроса
+const (
+	pgUniqueViolation           = "23505"
+	pgForeignKeyViolation       = "23503"
+	pgNotNullViolation          = "23502"
+	pgCheckViolation            = "23514"
+	pgInvalidTextRepresentation = "22P02"
+)
+
+// pgKeyDetail разбирает Detail ошибок ограничений:
+// Key (internal_number)=(1234) already exists.
+// Key (location_id)=(5) is not present in table "locations".
+// Key (id)=(5) is still referenced from table "profiles".
+var pgKeyDetail = regexp.MustCompile(`^Key \((.+?)\)=\((.*)\) (.+?)\.?$`)
+
+// pgReferencedTable извлекает таблицу из окончания Detail ошибки внешнего ключа
+var pgReferencedTable = regexp.MustCompile(`table "(?:[^"]+\.)?([^"]+)"`)
+
+// pgInvalidInput извлекает тип и значение из сообщения о неверном формате:
+// invalid input syntax for type inet: "10.0.0"
+var pgInvalidInput = regexp.MustCompile(`for type (\w+): "(.*)"$`)
+
+// databaseError преобразует ошибку PostgreSQL в ответ API. ok = false,
+// если ошибка не из базы или её код не относится к ошибкам запроса.
+func databaseError(err error) (status int, response ErrorResponse, ok bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return 0, ErrorResponse{}, false
+	}
+
+	column, value, rest := parseKeyDetail(pgErr.Detail)
+	if column == "" {
+		column = pgErr.ColumnName
+	}
+	field := jsonFieldName(column)
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		message := fmt.Sprintf("Value %s is already in use", value)
+		return fiber.StatusConflict, fieldError(field, message), true
+
+	case pgForeignKeyViolation:
+		table := referencedTable(rest)
+		// Удаление записи, на которую ещё ссылаются
+		if strings.Contains(rest, "still referenced") {
+			message := fmt.Sprintf("Record is still referenced from %s", table)
+			return fiber.StatusConflict, ErrorResponse{Error: message}, true
+		}
+		message := fmt.Sprintf("Value %s does not exist in %s", value, table)
+		return fiber.StatusUnprocessableEntity, fieldError(field, message), true
+
+	case pgNotNullViolation:
+		return fiber.StatusUnprocessableEntity, fieldError(field, "Is required"), true
+
+	case pgCheckViolation:
+		message := fmt.Sprintf("Violates constraint %s", pgErr.ConstraintName)
+		return fiber.StatusUnprocessableEntity, fieldError(field, message), true
+
+	case pgInvalidTextRepresentation:
+		message := "Invalid value"
+		if match := pgInvalidInput.FindStringSubmatch(pgErr.Message); match != nil {
+			message = fmt.Sprintf("Invalid %s value %q", match[1], match[2])
+		}
+		return fiber.StatusUnprocessableEntity, fieldError(field, message), true
+	}
+
+	return 0, ErrorResponse{}, false
+}
+
+// fieldError формирует ответ с ошибкой поля; без имени поля остаётся только общий текст
+func fieldError(field, message string) ErrorResponse {
+	if field == "" {
+		return ErrorResponse{Error: message}
+	}
+	return ErrorResponse{
+		Error:  field + ": " + message,
+		Fields: map[string]string{field: message},
+	}
+}
+
+func parseKeyDetail(detail string) (column, value, rest string) {
+	match := pgKeyDetail.FindStringSubmatch(detail)
+	if match == nil {
+		return "", "", detail
+	}
+	return match[1], match[2], match[3]
+}
+
+func referencedTable(detail string) string {
+	if match := pgReferencedTable.FindStringSubmatch(detail); match != nil {
+		return match[1]
+	}
+	return "another table"
+}
+
+// jsonFieldName переводит имя колонки в имя поля JSON: internal_number -> internalNumber.
+// Для составных ключей и выражений ("lower(email)", "entity_type, entity_id") поле не определяется.
+func jsonFieldName(column string) string {
+	if column == "" || strings.ContainsAny(column, " (,") {
+		return ""
+	}
+	parts := strings.Split(column, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
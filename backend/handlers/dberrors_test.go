@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"asterisk-manager/repositories"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorHandlerDatabase(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    *pgconn.PgError
+		status int
+		body   ErrorResponse
+	}{
+		{
+			name:   "duplicate internal number",
+			err:    &pgconn.PgError{Code: pgUniqueViolation, Detail: "Key (internal_number)=(1234) already exists."},
+			status: fiber.StatusConflict,
+			body: ErrorResponse{
+				Error:  "internalNumber: Value 1234 is already in use",
+				Fields: map[string]string{"internalNumber": "Value 1234 is already in use"},
+			},
+		},
+		{
+			name:   "missing location",
+			err:    &pgconn.PgError{Code: pgForeignKeyViolation, Detail: `Key (location_id)=(5) is not present in table "locations".`},
+			status: fiber.StatusUnprocessableEntity,
+			body: ErrorResponse{
+				Error:  "locationId: Value 5 does not exist in locations",
+				Fields: map[string]string{"locationId": "Value 5 does not exist in locations"},
+			},
+		},
+		{
+			name:   "referenced location",
+			err:    &pgconn.PgError{Code: pgForeignKeyViolation, Detail: `Key (id)=(5) is still referenced from table "profiles".`},
+			status: fiber.StatusConflict,
+			body:   ErrorResponse{Error: "Record is still referenced from profiles"},
+		},
+		{
+			name:   "invalid inet",
+			err:    &pgconn.PgError{Code: pgInvalidTextRepresentation, Message: `invalid input syntax for type inet: "10.0.0"`},
+			status: fiber.StatusUnprocessableEntity,
+			body:   ErrorResponse{Error: `Invalid inet value "10.0.0"`},
+		},
+		{
+			name:   "unknown code",
+			err:    &pgconn.PgError{Code: "53300"},
+			status: fiber.StatusInternalServerError,
+			body:   ErrorResponse{Error: "Internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&repositories.Repos{})
+			app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler})
+			app.Use(requestid.New())
+			app.Get("/test", func(c *fiber.Ctx) error {
+				return errors.Wrap(tt.err, "failed to save")
+			})
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set(fiber.HeaderXRequestID, "req-1")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+
+			var body ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			tt.body.RequestID = "req-1"
+			assert.Equal(t, tt.body, body)
+		})
+	}
+}
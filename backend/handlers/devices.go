@@ -1,18 +1,46 @@
 package handlers
 
 import (
+	"errors"
+
 	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
-// GetDevices возвращает список всех устройств
+// GetDevices возвращает список устройств с пагинацией и фильтром (см. domain.DeviceFilter)
 func (h *Handler) GetDevices(c *fiber.Ctx) error {
-	var devices []domain.Device
-	if err := h.repos.FindAllDevices(&devices); err != nil {
+	pagination, ok := c.Locals("pagination").(*domain.PaginationInput)
+	if !ok {
+		return fiber.NewError(fiber.StatusInternalServerError, "Pagination not found in context")
+	}
+
+	var filter domain.DeviceFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid filter parameters")
+	}
+	if err := filter.Validate().Err(); err != nil {
 		return err
 	}
-	return c.JSON(devices)
+
+	devices, total, err := h.repos.FindDevices(&filter, pagination)
+	if err != nil {
+		return err
+	}
+
+	paginationResponse := domain.PaginationResponse{
+		Total:   total,
+		Page:    pagination.Page,
+		PerPage: pagination.PerPage,
+	}
+	paginationResponse.CalculatePages()
+
+	return c.JSON(domain.PaginatedResult{
+		Data:       devices,
+		Pagination: paginationResponse,
+	})
 }
 
 // GetDevice возвращает одно устройство по MAC
@@ -31,8 +59,23 @@ func (h *Handler) CreateDevice(c *fiber.Ctx) error {
 	if err := c.BodyParser(&device); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
+	if err := h.validateDevice(&device, true); err != nil {
+		return err
+	}
+
+	// Устройство в корзине продолжает занимать MAC
+	err := h.repos.FindDeletedEntity(&domain.Device{}, domain.NormalizeMAC(device.MAC))
+	if err == nil {
+		return fiber.NewError(fiber.StatusConflict, "Device with this MAC is in trash, restore or purge it first")
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
 
-	if err := h.repos.Save(&device); err != nil {
+	err = h.audited(c, domain.AuditActionCreate, nil, &device, func(tx *repositories.Repos) error {
+		return tx.Save(&device)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -49,20 +92,29 @@ func (h *Handler) UpdateDevice(c *fiber.Ctx) error {
 		return err
 	}
 
-	// Парсим новые данные
+	before := device
+
+	// Парсим новые данные; MAC и даты из тела не принимаются
 	if err := c.BodyParser(&device); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
+	device.MAC, device.CreatedAt, device.DeletedAt = before.MAC, before.CreatedAt, before.DeletedAt
+	if err := h.validateDevice(&device, false); err != nil {
+		return err
+	}
 
 	// Сохраняем
-	if err := h.repos.Save(&device); err != nil {
+	err := h.audited(c, domain.AuditActionUpdate, &before, &device, func(tx *repositories.Repos) error {
+		return tx.Save(&device)
+	})
+	if err != nil {
 		return err
 	}
 
 	return c.JSON(device)
 }
 
-// DeleteDevice удаляет устройство
+// DeleteDevice удаляет устройство (политики и ?dryRun=true - см. deleteWithDependents)
 func (h *Handler) DeleteDevice(c *fiber.Ctx) error {
 	mac := c.Params("mac")
 	var device domain.Device
@@ -72,10 +124,6 @@ func (h *Handler) DeleteDevice(c *fiber.Ctx) error {
 		return err
 	}
 
-	// Удаляем
-	if err := h.repos.Delete(&device); err != nil {
-		return err
-	}
-
-	return c.SendStatus(fiber.StatusNoContent)
+	// Удаляем с учётом профилей, которые ссылаются на запись
+	return h.deleteWithDependents(c, &device)
 }
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FaxesHandler хендлер принятых факсов
+type FaxesHandler struct {
+	*Handler
+	faxService      *services.FaxService
+	deliveryService *services.FaxDeliveryService
+}
+
+// NewFaxesHandler создает хендлер факсов; deliveryService равен nil, если SMTP не настроен
+func NewFaxesHandler(handler *Handler, faxService *services.FaxService, deliveryService *services.FaxDeliveryService) *FaxesHandler {
+	return &FaxesHandler{
+		Handler:         handler,
+		faxService:      faxService,
+		deliveryService: deliveryService,
+	}
+}
+
+// IncomingFaxRequest уведомление Asterisk о принятом факсе
+type IncomingFaxRequest struct {
+	Path string `json:"path" form:"path"`
+}
+
+// GetFaxes возвращает список факсов с поиском и пагинацией
+func (h *FaxesHandler) GetFaxes(c *fiber.Ctx) error {
+	pagination, ok := c.Locals("pagination").(*domain.PaginationInput)
+	if !ok {
+		return fiber.NewError(fiber.StatusInternalServerError, "Pagination not found in context")
+	}
+
+	var filter domain.FaxFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid filter parameters")
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return err
+	}
+	filter.From = from
+	filter.To = to
+
+	// Пользователь видит только факсы своей локации
+	scope, err := h.locationScope(c)
+	if err != nil {
+		return err
+	}
+	if scope != nil {
+		filter.LocationID = scope
+	}
+
+	faxes, total, err := h.repos.FindFaxes(&filter, pagination)
+	if err != nil {
+		return err
+	}
+
+	paginationResponse := domain.PaginationResponse{
+		Total:   total,
+		Page:    pagination.Page,
+		PerPage: pagination.PerPage,
+	}
+	paginationResponse.CalculatePages()
+
+	return c.JSON(domain.PaginatedResult{
+		Data:       faxes,
+		Pagination: paginationResponse,
+	})
+}
+
+// GetFax возвращает один факс по ID
+func (h *FaxesHandler) GetFax(c *fiber.Ctx) error {
+	fax, err := h.findFax(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fax)
+}
+
+// DownloadFax отдаёт PDF факса
+func (h *FaxesHandler) DownloadFax(c *fiber.Ctx) error {
+	fax, err := h.findFax(c)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fax.FileName))
+	return c.SendFile(fax.Path)
+}
+
+// MarkFaxRead отмечает факс прочитанным
+func (h *FaxesHandler) MarkFaxRead(c *fiber.Ctx) error {
+	fax, err := h.findFax(c)
+	if err != nil {
+		return err
+	}
+
+	claims := c.Locals("user").(*services.JWTClaims)
+	now := time.Now()
+	fax.IsRead = true
+	fax.ReadAt = &now
+	fax.ReadBy = claims.Username
+
+	if err := h.repos.Save(fax); err != nil {
+		return err
+	}
+	return c.JSON(fax)
+}
+
+// MarkFaxUnread снимает отметку о прочтении
+func (h *FaxesHandler) MarkFaxUnread(c *fiber.Ctx) error {
+	fax, err := h.findFax(c)
+	if err != nil {
+		return err
+	}
+
+	fax.IsRead = false
+	fax.ReadAt = nil
+	fax.ReadBy = ""
+
+	if err := h.repos.Save(fax); err != nil {
+		return err
+	}
+	return c.JSON(fax)
+}
+
+// ScanFaxes запускает сканирование каталога факсов
+func (h *FaxesHandler) ScanFaxes(c *fiber.Ctx) error {
+	result, err := h.faxService.Scan()
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
+
+// IncomingFax принимает уведомление от диалплана о новом PDF (заголовок X-Fax-Token)
+func (h *FaxesHandler) IncomingFax(c *fiber.Ctx) error {
+	token := h.faxService.Config().HookToken
+	if token == "" {
+		return fiber.NewError(fiber.StatusNotFound, "Fax hook is disabled")
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Get("X-Fax-Token")), []byte(token)) != 1 {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid fax token")
+	}
+
+	var req IncomingFaxRequest
+	if err := c.BodyParser(&req); err != nil || req.Path == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Fax path is required")
+	}
+
+	fax, err := h.faxService.Ingest(req.Path)
+	if errors.Is(err, services.ErrFaxOutsideDir) || errors.Is(err, os.ErrNotExist) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fax)
+}
+
+// GetFaxDeliveries возвращает историю рассылки факса по почте
+func (h *FaxesHandler) GetFaxDeliveries(c *fiber.Ctx) error {
+	fax, err := h.findFax(c)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := h.repos.FindFaxDeliveries(fax.ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(deliveries)
+}
+
+// ResendFax повторно ставит факс в очередь рассылки
+func (h *FaxesHandler) ResendFax(c *fiber.Ctx) error {
+	if h.deliveryService == nil {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "Fax email delivery is not configured")
+	}
+
+	fax, err := h.findFax(c)
+	if err != nil {
+		return err
+	}
+
+	delivery, err := h.deliveryService.Enqueue(fax)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusAccepted).JSON(delivery)
+}
+
+// GetFaxRecipients возвращает адреса рассылки факсов (?ringGroup= для одной группы)
+func (h *FaxesHandler) GetFaxRecipients(c *fiber.Ctx) error {
+	var recipients []domain.FaxRecipient
+	if ringGroup := c.QueryInt("ringGroup"); ringGroup != 0 {
+		found, err := h.repos.FindFaxRecipients(ringGroup)
+		if err != nil {
+			return err
+		}
+		return c.JSON(found)
+	}
+
+	if err := h.repos.FindAll(&recipients); err != nil {
+		return err
+	}
+	return c.JSON(recipients)
+}
+
+// CreateFaxRecipient добавляет адрес рассылки факсов ринг-группы
+func (h *FaxesHandler) CreateFaxRecipient(c *fiber.Ctx) error {
+	var recipient domain.FaxRecipient
+	if err := c.BodyParser(&recipient); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if recipient.RingGroup == 0 || recipient.Email == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Ring group and email are required")
+	}
+
+	if err := h.repos.Save(&recipient); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(recipient)
+}
+
+// DeleteFaxRecipient удаляет адрес рассылки факсов
+func (h *FaxesHandler) DeleteFaxRecipient(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var recipient domain.FaxRecipient
+
+	// Проверяем существование
+	if err := h.repos.FindByID(&recipient, id); err != nil {
+		return err
+	}
+
+	// Удаляем
+	if err := h.repos.Delete(&recipient); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// findFax находит факс по ID с учётом локации пользователя
+func (h *FaxesHandler) findFax(c *fiber.Ctx) (*domain.Fax, error) {
+	var fax domain.Fax
+	if err := h.repos.FindByID(&fax, c.Params("id")); err != nil {
+		return nil, err
+	}
+
+	scope, err := h.locationScope(c)
+	if err != nil {
+		return nil, err
+	}
+	if scope != nil && (fax.LocationID == nil || *fax.LocationID != *scope) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access to fax denied")
+	}
+
+	return &fax, nil
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+// generatorMu не даёт двум запросам одновременно перезаписывать конфигурацию
+var generatorMu sync.Mutex
+
+// generatorOutputDir каталог, в который генерируется рабочая конфигурация Asterisk
+func generatorOutputDir() string {
+	if dir := os.Getenv("GENERATOR_OUTPUT_DIR"); dir != "" {
+		return dir
+	}
+	return "results"
+}
+
+// RunGenerator генерирует конфигурацию Asterisk из текущего состояния БД
+func (h *Handler) RunGenerator(c *fiber.Ctx) error {
+	generatorMu.Lock()
+	defer generatorMu.Unlock()
+
+	generator := services.NewAsteriskGeneratorFromEnv(generatorOutputDir())
+	if err := generator.LoadFromDatabase(h.repos); err != nil {
+		return err
+	}
+	if err := generator.Generate(); err != nil {
+		return err
+	}
+
+	return c.JSON(generator.GetStats())
+}
+
+// ExportGenerator генерирует конфигурацию на момент ?at= по истории ревизий
+// (без at - текущую) и отдаёт её zip-архивом, не трогая рабочий каталог
+func (h *Handler) ExportGenerator(c *fiber.Ctx) error {
+	at, err := parseSnapshotTime(c)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := h.repos.FindSnapshot(at)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "asterisk-config-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	generator := services.NewAsteriskGeneratorFromEnv(dir)
+	generator.LoadSnapshot(snapshot)
+	if err := generator.Generate(); err != nil {
+		return err
+	}
+
+	archive, err := zipDir(dir)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Attachment("asterisk-config-" + at.Format("20060102-150405") + ".zip")
+	return c.Send(archive)
+}
+
+// zipDir упаковывает содержимое каталога в zip-архив
+func zipDir(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		header.Method = zip.Deflate
+		header.Modified = time.Now()
+
+		w, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to archive generated config")
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to archive generated config")
+	}
+	return buf.Bytes(), nil
+}
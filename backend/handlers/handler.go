@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 
 	"asterisk-manager/domain"
 	"asterisk-manager/repositories"
+	"asterisk-manager/services"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -20,9 +22,12 @@ func NewHandler(repos *repositories.Repos) *Handler {
 	}
 }
 
-// ErrorResponse структура ответа с ошибкой
+// ErrorResponse структура ответа с ошибкой; Fields - ошибки проверки по полям запроса,
+// RequestID - идентификатор запроса для поиска записи в логе
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error     string            `json:"error"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
 }
 
 // Pagination middleware parses and validates pagination query parameters
@@ -43,21 +48,62 @@ func (h *Handler) Pagination(c *fiber.Ctx) error {
 	return c.Next()
 }
 
+// locationScope возвращает локацию, которой ограничен доступ текущего пользователя.
+// Администратор видит все локации (nil), пользователь - только назначенную ему.
+func (h *Handler) locationScope(c *fiber.Ctx) (*uint, error) {
+	claims := c.Locals("user").(*services.JWTClaims)
+	if claims.Role == domain.UserRoleAdmin {
+		return nil, nil
+	}
+
+	var user domain.User
+	if err := h.repos.FindByID(&user, claims.UserID); err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "User not found")
+	}
+	if user.LocationID == nil {
+		return nil, fiber.NewError(fiber.StatusForbidden, "No location assigned to user")
+	}
+
+	return user.LocationID, nil
+}
+
 // ErrorHandler централизованная обработка ошибок
 func (h *Handler) ErrorHandler(ctx *fiber.Ctx, err error) error {
+	requestID, _ := ctx.Locals("requestid").(string)
+	respond := func(status int, response ErrorResponse) error {
+		response.RequestID = requestID
+		return ctx.Status(status).JSON(response)
+	}
+
 	// GORM Record Not Found
 	if err == gorm.ErrRecordNotFound {
-		log.Printf("Record not found: %s %s", ctx.Method(), ctx.Path())
-		return ctx.Status(404).JSON(ErrorResponse{Error: "Not found"})
+		log.Printf("[%s] Record not found: %s %s", requestID, ctx.Method(), ctx.Path())
+		return respond(404, ErrorResponse{Error: "Not found"})
+	}
+
+	// Ошибки проверки запроса по полям
+	var validationErrors domain.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		log.Printf("[%s] Validation error: %s %s - %v", requestID, ctx.Method(), ctx.Path(), validationErrors)
+		return respond(fiber.StatusUnprocessableEntity, ErrorResponse{
+			Error:  "Validation failed",
+			Fields: validationErrors,
+		})
+	}
+
+	// Нарушения ограничений и неверный формат данных в PostgreSQL
+	if status, response, ok := databaseError(err); ok {
+		log.Printf("[%s] Database error: %s %s - %v", requestID, ctx.Method(), ctx.Path(), err)
+		return respond(status, response)
 	}
 
 	// Fiber ошибки
 	if fiberError, ok := err.(*fiber.Error); ok {
-		log.Printf("Fiber error: %s %s - %s", ctx.Method(), ctx.Path(), fiberError.Error())
-		return ctx.Status(fiberError.Code).JSON(ErrorResponse{Error: fiberError.Message})
+		log.Printf("[%s] Fiber error: %s %s - %s", requestID, ctx.Method(), ctx.Path(), fiberError.Error())
+		return respond(fiberError.Code, ErrorResponse{Error: fiberError.Message})
 	}
 
 	// Все остальные ошибки
-	log.Printf("Unexpected error: %s %s - %v", ctx.Method(), ctx.Path(), err)
-	return ctx.Status(500).JSON(ErrorResponse{Error: "Internal server error"})
+	log.Printf("[%s] Unexpected error: %s %s - %v", requestID, ctx.Method(), ctx.Path(), err)
+	return respond(500, ErrorResponse{Error: "Internal server error"})
 }
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// errDryRun откатывает транзакцию пробного удаления
+var errDryRun = errors.New("dry run")
+
+// deleteWithDependents удаляет устройство или локацию в корзину. Профили, которые на неё
+// ссылаются, обрабатываются по ?policy=: block (по умолчанию) - удаление запрещено,
+// cascade - профили удаляются в корзину, reassign - переносятся на ?reassignTo= (MAC или ID).
+// С ?dryRun=true ничего не меняется: в ответе DeleteImpact с зависимыми профилями
+// и файлами конфигурации, которые изменятся.
+func (h *Handler) deleteWithDependents(c *fiber.Ctx, entity domain.Auditable) error {
+	entityType, entityID := entity.AuditEntity()
+	impact := domain.DeleteImpact{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Policy:     domain.DeletePolicy(c.Query("policy", string(domain.DeletePolicyBlock))),
+		ReassignTo: c.Query("reassignTo"),
+	}
+	if !impact.Policy.IsValid() {
+		return fiber.NewError(fiber.StatusBadRequest, "Unknown delete policy, expected block, cascade or reassign")
+	}
+	if impact.Policy == domain.DeletePolicyReassign {
+		target, err := h.reassignTarget(entityType, entityID, impact.ReassignTo)
+		if err != nil {
+			return err
+		}
+		impact.ReassignTo = target
+	}
+
+	profiles, err := h.repos.FindDependentProfiles(entityType, entityID)
+	if err != nil {
+		return err
+	}
+	impact.Profiles = profiles
+	impact.Blocked = impact.Policy == domain.DeletePolicyBlock && len(profiles) > 0
+
+	if !c.QueryBool("dryRun") {
+		if impact.Blocked {
+			return fiber.NewError(fiber.StatusConflict, fmt.Sprintf(
+				"Referenced by %d profile(s); check them with dryRun=true or delete with policy cascade or reassign",
+				len(profiles)))
+		}
+
+		err := h.repos.Transaction(func(tx *repositories.Repos) error {
+			return applyDelete(tx, c, entity, &impact)
+		})
+		if err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	// Пробное удаление: конфигурация генерируется до и после изменения в транзакции,
+	// которая затем откатывается
+	before, err := services.GeneratedFiles(h.repos)
+	if err != nil {
+		return err
+	}
+	err = h.repos.Transaction(func(tx *repositories.Repos) error {
+		if err := applyDelete(tx, c, entity, &impact); err != nil {
+			return err
+		}
+		after, err := services.GeneratedFiles(tx)
+		if err != nil {
+			return err
+		}
+		impact.Files = services.DiffGeneratedFiles(before, after)
+		return errDryRun
+	})
+	if !errors.Is(err, errDryRun) {
+		return err
+	}
+
+	return c.JSON(impact)
+}
+
+// applyDelete применяет политику к зависимым профилям и удаляет сущность.
+// При политике block профили не трогаются (для пробного удаления - ссылки остаются висеть).
+func applyDelete(tx *repositories.Repos, c *fiber.Ctx, entity domain.Auditable, impact *domain.DeleteImpact) error {
+	for i := range impact.Profiles {
+		profile := impact.Profiles[i]
+
+		switch impact.Policy {
+		case domain.DeletePolicyCascade:
+			err := auditedIn(tx, c, domain.AuditActionDelete, &profile, nil, func(tx *repositories.Repos) error {
+				return tx.Delete(&profile)
+			})
+			if err != nil {
+				return err
+			}
+
+		case domain.DeletePolicyReassign:
+			updated := profile
+			if impact.EntityType == domain.AuditEntityDevice {
+				updated.Device = &impact.ReassignTo
+			} else {
+				locationID, _ := strconv.ParseUint(impact.ReassignTo, 10, 64)
+				id := uint(locationID)
+				updated.LocationID = &id
+			}
+			err := auditedIn(tx, c, domain.AuditActionUpdate, &profile, &updated, func(tx *repositories.Repos) error {
+				return tx.Save(&updated)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return auditedIn(tx, c, domain.AuditActionDelete, entity, nil, func(tx *repositories.Repos) error {
+		return tx.Delete(entity)
+	})
+}
+
+// reassignTarget проверяет устройство или локацию, на которую переносятся профили,
+// и возвращает её идентификатор в каноническом виде
+func (h *Handler) reassignTarget(entityType domain.AuditEntityType, entityID, target string) (string, error) {
+	fail := func(message string) (string, error) {
+		return "", domain.ValidationErrors{"reassignTo": message}
+	}
+	if target == "" {
+		return fail("Is required for policy reassign")
+	}
+
+	var err error
+	if entityType == domain.AuditEntityDevice {
+		if _, parseErr := net.ParseMAC(target); parseErr != nil {
+			return fail("Invalid MAC address")
+		}
+		target = domain.NormalizeMAC(target)
+		if target == entityID {
+			return fail("Must differ from the deleted device")
+		}
+		err = h.repos.FindOne(&domain.Device{}, "mac = ?", target)
+	} else {
+		id, parseErr := strconv.ParseUint(target, 10, 64)
+		if parseErr != nil {
+			return fail("Must be a location ID")
+		}
+		target = strconv.FormatUint(id, 10)
+		if target == entityID {
+			return fail("Must differ from the deleted location")
+		}
+		err = h.repos.FindByID(&domain.Location{}, target)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fail("Not found")
+	}
+	return target, err
+}
@@ -2,17 +2,34 @@ package handlers
 
 import (
 	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// GetLocations возвращает список всех локаций
+// GetLocations возвращает список локаций с пагинацией и числом профилей и устройств
 func (h *Handler) GetLocations(c *fiber.Ctx) error {
-	var locations []domain.Location
-	if err := h.repos.FindAll(&locations); err != nil {
+	pagination, ok := c.Locals("pagination").(*domain.PaginationInput)
+	if !ok {
+		return fiber.NewError(fiber.StatusInternalServerError, "Pagination not found in context")
+	}
+
+	locations, total, err := h.repos.FindLocationsWithCounts(pagination)
+	if err != nil {
 		return err
 	}
-	return c.JSON(locations)
+
+	paginationResponse := domain.PaginationResponse{
+		Total:   total,
+		Page:    pagination.Page,
+		PerPage: pagination.PerPage,
+	}
+	paginationResponse.CalculatePages()
+
+	return c.JSON(domain.PaginatedResult{
+		Data:       locations,
+		Pagination: paginationResponse,
+	})
 }
 
 // GetLocation возвращает одну локацию по ID
@@ -31,8 +48,14 @@ func (h *Handler) CreateLocation(c *fiber.Ctx) error {
 	if err := c.BodyParser(&location); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
+	if err := h.validateLocation(&location); err != nil {
+		return err
+	}
 
-	if err := h.repos.Save(&location); err != nil {
+	err := h.audited(c, domain.AuditActionCreate, nil, &location, func(tx *repositories.Repos) error {
+		return tx.Save(&location)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -49,20 +72,29 @@ func (h *Handler) UpdateLocation(c *fiber.Ctx) error {
 		return err
 	}
 
-	// Парсим новые данные
+	before := location
+
+	// Парсим новые данные; ID и даты из тела не принимаются
 	if err := c.BodyParser(&location); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
+	location.ID, location.CreatedAt, location.DeletedAt = before.ID, before.CreatedAt, before.DeletedAt
+	if err := h.validateLocation(&location); err != nil {
+		return err
+	}
 
 	// Сохраняем
-	if err := h.repos.Save(&location); err != nil {
+	err := h.audited(c, domain.AuditActionUpdate, &before, &location, func(tx *repositories.Repos) error {
+		return tx.Save(&location)
+	})
+	if err != nil {
 		return err
 	}
 
 	return c.JSON(location)
 }
 
-// DeleteLocation удаляет локацию
+// DeleteLocation удаляет локацию (политики и ?dryRun=true - см. deleteWithDependents)
 func (h *Handler) DeleteLocation(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var location domain.Location
@@ -72,10 +104,6 @@ func (h *Handler) DeleteLocation(c *fiber.Ctx) error {
 		return err
 	}
 
-	// Удаляем
-	if err := h.repos.Delete(&location); err != nil {
-		return err
-	}
-
-	return c.SendStatus(fiber.StatusNoContent)
+	// Удаляем с учётом профилей, которые ссылаются на запись
+	return h.deleteWithDependents(c, &location)
 }
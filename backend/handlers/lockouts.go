@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetLockouts возвращает действующие блокировки входа и свежие счётчики неудач
+func (h *AuthHandler) GetLockouts(c *fiber.Ctx) error {
+	now := time.Now()
+	lockouts, err := h.repos.FindActiveLockouts(now, now.Add(-h.loginGuard.Config().Window))
+	if err != nil {
+		return err
+	}
+	return c.JSON(lockouts)
+}
+
+// DeleteLockout снимает блокировку и сбрасывает счётчик неудач
+func (h *AuthHandler) DeleteLockout(c *fiber.Ctx) error {
+	var lockout domain.Lockout
+	if err := h.repos.FindByID(&lockout, c.Params("id")); err != nil {
+		return err
+	}
+
+	if err := h.repos.Delete(&lockout); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetLoginAttempts возвращает журнал попыток входа (?username=&ip=&failed=true)
+func (h *AuthHandler) GetLoginAttempts(c *fiber.Ctx) error {
+	pagination, ok := c.Locals("pagination").(*domain.PaginationInput)
+	if !ok {
+		return fiber.NewError(fiber.StatusInternalServerError, "Pagination not found in context")
+	}
+
+	var filter domain.LoginAttemptFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid filter parameters")
+	}
+
+	attempts, total, err := h.repos.FindLoginAttempts(&filter, pagination)
+	if err != nil {
+		return err
+	}
+
+	paginationResponse := domain.PaginationResponse{
+		Total:   total,
+		Page:    pagination.Page,
+		PerPage: pagination.PerPage,
+	}
+	paginationResponse.CalculatePages()
+
+	return c.JSON(domain.PaginatedResult{
+		Data:       attempts,
+		Pagination: paginationResponse,
+	})
+}
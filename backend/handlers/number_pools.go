@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// NumberPoolsHandler хендлер пулов номеров и броней
+type NumberPoolsHandler struct {
+	*Handler
+	reservationTTL time.Duration
+}
+
+// NewNumberPoolsHandler создает хендлер пулов номеров; reservationTTL - срок брони по умолчанию
+func NewNumberPoolsHandler(handler *Handler, reservationTTL time.Duration) *NumberPoolsHandler {
+	return &NumberPoolsHandler{
+		Handler:        handler,
+		reservationTTL: reservationTTL,
+	}
+}
+
+// GetNumberPools возвращает список пулов номеров
+func (h *NumberPoolsHandler) GetNumberPools(c *fiber.Ctx) error {
+	pools, err := h.repos.FindNumberPools()
+	if err != nil {
+		return err
+	}
+	return c.JSON(pools)
+}
+
+// GetNumberPoolUtilisation возвращает заполненность пулов: занятые, забронированные
+// и свободные номера и первый свободный номер каждого пула
+func (h *NumberPoolsHandler) GetNumberPoolUtilisation(c *fiber.Ctx) error {
+	report, err := h.repos.FindPoolUtilisation()
+	if err != nil {
+		return err
+	}
+	return c.JSON(report)
+}
+
+// GetNumberPool возвращает один пул по ID
+func (h *NumberPoolsHandler) GetNumberPool(c *fiber.Ctx) error {
+	var pool domain.NumberPool
+	if err := h.repos.FindByID(&pool, c.Params("id")); err != nil {
+		return err
+	}
+	return c.JSON(pool)
+}
+
+// CreateNumberPool создает пул номеров
+func (h *NumberPoolsHandler) CreateNumberPool(c *fiber.Ctx) error {
+	var pool domain.NumberPool
+	if err := c.BodyParser(&pool); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := h.validateNumberPool(&pool); err != nil {
+		return err
+	}
+
+	if err := h.repos.Save(&pool); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(pool)
+}
+
+// UpdateNumberPool обновляет пул номеров; брони вне нового диапазона снимаются
+func (h *NumberPoolsHandler) UpdateNumberPool(c *fiber.Ctx) error {
+	var pool domain.NumberPool
+
+	// Проверяем существование
+	if err := h.repos.FindByID(&pool, c.Params("id")); err != nil {
+		return err
+	}
+
+	// Парсим новые данные
+	id := pool.ID
+	if err := c.BodyParser(&pool); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	pool.ID = id
+	if err := h.validateNumberPool(&pool); err != nil {
+		return err
+	}
+
+	// Сохраняем
+	err := h.repos.Transaction(func(tx *repositories.Repos) error {
+		if err := tx.Save(&pool); err != nil {
+			return err
+		}
+		return tx.ReleaseReservationsOutside(&pool)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(pool)
+}
+
+// DeleteNumberPool удаляет пул номеров вместе с его бронями; профили с номерами пула не меняются
+func (h *NumberPoolsHandler) DeleteNumberPool(c *fiber.Ctx) error {
+	var pool domain.NumberPool
+
+	// Проверяем существование
+	if err := h.repos.FindByID(&pool, c.Params("id")); err != nil {
+		return err
+	}
+
+	// Удаляем
+	if err := h.repos.Delete(&pool); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetNextFreeNumber возвращает первый свободный номер пула, не бронируя его; 409, если пул исчерпан
+func (h *NumberPoolsHandler) GetNextFreeNumber(c *fiber.Ctx) error {
+	var pool domain.NumberPool
+	if err := h.repos.FindByID(&pool, c.Params("id")); err != nil {
+		return err
+	}
+
+	number, ok, err := h.repos.NextFreeNumber(&pool)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return poolExhausted(&pool)
+	}
+
+	return c.JSON(domain.FreeNumber{PoolID: pool.ID, Number: number})
+}
+
+// GetNumberReservations возвращает действующие брони пула
+func (h *NumberPoolsHandler) GetNumberReservations(c *fiber.Ctx) error {
+	var pool domain.NumberPool
+	if err := h.repos.FindByID(&pool, c.Params("id")); err != nil {
+		return err
+	}
+
+	reservations, err := h.repos.FindReservations(pool.ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(reservations)
+}
+
+// ReserveNumber бронирует указанный или первый свободный номер пула на ttl
+// (по умолчанию NUMBER_RESERVATION_TTL). Занятый или уже забронированный номер - 409.
+func (h *NumberPoolsHandler) ReserveNumber(c *fiber.Ctx) error {
+	poolID, err := c.ParamsInt("id")
+	if err != nil || poolID < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid pool ID")
+	}
+
+	var request domain.ReserveNumberRequest
+	if err := c.BodyParser(&request); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	ttl, err := request.Duration(h.reservationTTL)
+	if err != nil {
+		return err
+	}
+
+	claims := c.Locals("user").(*services.JWTClaims)
+	reservation := domain.NumberReservation{
+		Note:      request.Note,
+		Username:  claims.Username,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	err = h.repos.Transaction(func(tx *repositories.Repos) error {
+		pool, err := tx.LockNumberPool(uint(poolID))
+		if err != nil {
+			return err
+		}
+		reservation.PoolID = pool.ID
+
+		if request.Number == nil {
+			number, ok, err := tx.NextFreeNumber(pool)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return poolExhausted(pool)
+			}
+			reservation.Number = number
+		} else {
+			if !pool.Contains(*request.Number) {
+				return domain.ValidationErrors{"number": fmt.Sprintf("Must be between %d and %d", pool.RangeStart, pool.RangeEnd)}
+			}
+			if err := numberAvailable(tx, *request.Number); err != nil {
+				return err
+			}
+			reservation.Number = *request.Number
+		}
+
+		return tx.ReserveNumber(&reservation)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(reservation)
+}
+
+// DeleteNumberReservation снимает бронь номера пула
+func (h *NumberPoolsHandler) DeleteNumberReservation(c *fiber.Ctx) error {
+	var pool domain.NumberPool
+	if err := h.repos.FindByID(&pool, c.Params("id")); err != nil {
+		return err
+	}
+	number, err := c.ParamsInt("number")
+	if err != nil || !pool.Contains(number) {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid number")
+	}
+
+	released, err := h.repos.ReleaseReservation(number)
+	if err != nil {
+		return err
+	}
+	if released == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// allocateNumber выдаёт первый свободный номер пула внутри транзакции tx.
+// Строка пула блокируется до конца транзакции, поэтому параллельные запросы получают разные номера.
+func allocateNumber(tx *repositories.Repos, poolID uint) (int, error) {
+	pool, err := tx.LockNumberPool(poolID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, domain.ValidationErrors{"allocateFromPool": "Number pool not found"}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	number, ok, err := tx.NextFreeNumber(pool)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, poolExhausted(pool)
+	}
+	return number, nil
+}
+
+// numberAvailable проверяет, что номер не занят профилем и не забронирован
+func numberAvailable(tx *repositories.Repos, number int) error {
+	taken, err := tx.InternalNumberTaken(number, 0)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Number %d is already in use by a profile", number))
+	}
+	return numberNotReserved(tx, number)
+}
+
+// numberNotReserved проверяет, что на номер нет действующей брони
+func numberNotReserved(tx *repositories.Repos, number int) error {
+	reserved, err := tx.NumberReserved(number)
+	if err != nil {
+		return err
+	}
+	if reserved {
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Number %d is already reserved", number))
+	}
+	return nil
+}
+
+// poolExhausted ошибка пула без свободных номеров
+func poolExhausted(pool *domain.NumberPool) error {
+	return fiber.NewError(fiber.StatusConflict, fmt.Sprintf(
+		"Number pool %s (%d-%d) has no free numbers", pool.Name, pool.RangeStart, pool.RangeEnd))
+}
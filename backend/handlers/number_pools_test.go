@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories/dbtest"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileNumberReservations(t *testing.T) {
+	repos := dbtest.Open(t)
+	h := NewHandler(repos)
+	app := fiber.New(fiber.Config{ErrorHandler: h.ErrorHandler})
+	app.Post("/profiles", h.CreateProfile)
+	app.Put("/profiles/:id", h.UpdateProfile)
+
+	pool := domain.NumberPool{Name: "ЗАГС", RangeStart: 6100, RangeEnd: 6199}
+	require.NoError(t, repos.Create(&pool))
+	reservation := domain.NumberReservation{PoolID: pool.ID, Number: 6101, Username: "petrov", ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, repos.ReserveNumber(&reservation))
+
+	send := func(method, path string, body interface{}) (int, ErrorResponse) {
+		payload, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var response ErrorResponse
+		if resp.StatusCode >= fiber.StatusBadRequest {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		}
+		return resp.StatusCode, response
+	}
+
+	// Чужая бронь не снимается молча: без fromReservation номер занять нельзя
+	status, response := send("POST", "/profiles", map[string]interface{}{"name": "Иванов", "internalNumber": 6101})
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, "Number 6101 is already reserved", response.Error)
+
+	// Номер из пула бронь обходит
+	status, _ = send("POST", "/profiles", map[string]interface{}{"name": "Иванов", "allocateFromPool": pool.ID})
+	require.Equal(t, fiber.StatusCreated, status)
+	var allocated domain.Profile
+	require.NoError(t, repos.FindOne(&allocated, "name = ?", "Иванов"))
+	assert.Equal(t, 6100, allocated.InternalNumber)
+
+	// Смена номера на забронированный тоже 409, сохранение без смены номера проходит
+	status, _ = send("PUT", "/profiles/1", map[string]interface{}{"name": "Иванов", "internalNumber": 6101})
+	assert.Equal(t, fiber.StatusConflict, status)
+	status, _ = send("PUT", "/profiles/1", map[string]interface{}{"name": "Иванов И.", "internalNumber": 6100})
+	assert.Equal(t, fiber.StatusOK, status)
+
+	status, response = send("POST", "/profiles", map[string]interface{}{"name": "Петров", "internalNumber": 6102, "fromReservation": true})
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, map[string]string{"fromReservation": "Number is not reserved"}, response.Fields)
+
+	// По брони номер занимается, бронь снимается
+	status, _ = send("POST", "/profiles", map[string]interface{}{"name": "Петров", "internalNumber": 6101, "fromReservation": true})
+	assert.Equal(t, fiber.StatusCreated, status)
+	reserved, err := repos.NumberReserved(6101)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/url"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// OIDCTokenRequest запрос на получение токенов по одноразовому коду входа
+type OIDCTokenRequest struct {
+	Code string `json:"code"`
+}
+
+// AuthProvidersResponse доступные способы входа
+type AuthProvidersResponse struct {
+	LDAP bool `json:"ldap"`
+	OIDC bool `json:"oidc"`
+}
+
+// GetAuthProviders сообщает UI, какие способы входа включены
+func (h *AuthHandler) GetAuthProviders(c *fiber.Ctx) error {
+	return c.JSON(AuthProvidersResponse{
+		LDAP: h.authService.LDAPEnabled(),
+		OIDC: h.authService.OIDC() != nil,
+	})
+}
+
+// OIDCLogin перенаправляет браузер на страницу входа провайдера
+func (h *AuthHandler) OIDCLogin(c *fiber.Ctx) error {
+	oidc := h.authService.OIDC()
+	if oidc == nil {
+		return fiber.NewError(fiber.StatusNotFound, "OIDC login is not configured")
+	}
+
+	authURL, err := oidc.AuthCodeURL(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback завершает вход у провайдера и возвращает браузер во фронтенд
+// с одноразовым кодом (?code=...) или с ошибкой (?error=...)
+func (h *AuthHandler) OIDCCallback(c *fiber.Ctx) error {
+	oidc := h.authService.OIDC()
+	if oidc == nil {
+		return fiber.NewError(fiber.StatusNotFound, "OIDC login is not configured")
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		return redirectToFrontend(c, oidc, "error", providerError)
+	}
+
+	identity, err := oidc.Exchange(c.UserContext(), c.Query("state"), c.Query("code"))
+	if err != nil {
+		log.Printf("OIDC: вход не выполнен: %v", err)
+		return redirectToFrontend(c, oidc, "error", oidcErrorCode(err))
+	}
+
+	user, err := h.authService.AuthenticateOIDC(identity)
+	if err != nil {
+		log.Printf("OIDC: пользователь %s не допущен: %v", identity.Username, err)
+		return redirectToFrontend(c, oidc, "error", oidcErrorCode(err))
+	}
+
+	code, err := oidc.IssueLoginCode(user.ID)
+	if err != nil {
+		return err
+	}
+	return redirectToFrontend(c, oidc, "code", code)
+}
+
+// OIDCToken выдаёт токены по одноразовому коду из OIDCCallback
+func (h *AuthHandler) OIDCToken(c *fiber.Ctx) error {
+	oidc := h.authService.OIDC()
+	if oidc == nil {
+		return fiber.NewError(fiber.StatusNotFound, "OIDC login is not configured")
+	}
+
+	var req OIDCTokenRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Login code is required")
+	}
+
+	userID, err := oidc.RedeemLoginCode(req.Code)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired login code")
+	}
+
+	var user domain.User
+	if err := h.repos.FindByID(&user, userID); err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "User not found")
+	}
+	if !user.IsActive {
+		return fiber.NewError(fiber.StatusForbidden, "Account is disabled")
+	}
+
+	pair, err := h.authService.StartSession(&user, c.IP(), c.Get(fiber.HeaderUserAgent))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to generate token")
+	}
+
+	return sendTokens(c, &user, pair)
+}
+
+// redirectToFrontend возвращает браузер на страницу фронтенда с параметром
+func redirectToFrontend(c *fiber.Ctx, oidc *services.OIDCAuthenticator, key, value string) error {
+	target, err := url.Parse(oidc.Config().FrontendURL)
+	if err != nil {
+		return err
+	}
+	query := target.Query()
+	query.Set(key, value)
+	target.RawQuery = query.Encode()
+	return c.Redirect(target.String(), fiber.StatusFound)
+}
+
+// oidcErrorCode переводит ошибку входа в код для фронтенда
+func oidcErrorCode(err error) string {
+	switch {
+	case errors.Is(err, services.ErrOIDCInvalidState):
+		return "expired"
+	case errors.Is(err, services.ErrOIDCNotAllowed):
+		return "not_allowed"
+	case errors.Is(err, services.ErrUserDisabled):
+		return "disabled"
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return "conflict"
+	}
+	return "failed"
+}
@@ -2,11 +2,12 @@ package handlers
 
 import (
 	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// GetProfiles возвращает список всех профилей с пагинацией
+// GetProfiles возвращает список профилей с пагинацией, фильтрами, поиском и сортировкой (см. domain.ProfileFilter)
 func (h *Handler) GetProfiles(c *fiber.Ctx) error {
 	// Get pagination from context (set by middleware)
 	pagination, ok := c.Locals("pagination").(*domain.PaginationInput)
@@ -14,8 +15,16 @@ func (h *Handler) GetProfiles(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Pagination not found in context")
 	}
 
+	var filter domain.ProfileFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid filter parameters")
+	}
+	if err := filter.Validate().Err(); err != nil {
+		return err
+	}
+
 	// Get profiles with pagination
-	profiles, total, err := h.repos.FindProfilesWithLocations(nil, pagination)
+	profiles, total, err := h.repos.FindProfilesWithLocations(&filter, pagination)
 	if err != nil {
 		return err
 	}
@@ -46,14 +55,52 @@ func (h *Handler) GetProfile(c *fiber.Ctx) error {
 	return c.JSON(profile)
 }
 
-// CreateProfile создает новый профиль
+// CreateProfile создает новый профиль; с allocateFromPool внутренний номер выдаётся из пула.
+// Забронированный номер занимается только с fromReservation, бронь при этом снимается.
 func (h *Handler) CreateProfile(c *fiber.Ctx) error {
-	var profile domain.Profile
-	if err := c.BodyParser(&profile); err != nil {
+	var request domain.CreateProfileRequest
+	if err := c.BodyParser(&request); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
+	profile := request.Profile
+	if err := request.Validate().Err(); err != nil {
+		return err
+	}
 
-	if err := h.repos.Save(&profile); err != nil {
+	err := h.repos.Transaction(func(tx *repositories.Repos) error {
+		if request.AllocateFromPool != nil {
+			number, err := allocateNumber(tx, *request.AllocateFromPool)
+			if err != nil {
+				return err
+			}
+			profile.InternalNumber = number
+		}
+		if request.FromReservation {
+			reserved, err := tx.NumberReserved(profile.InternalNumber)
+			if err != nil {
+				return err
+			}
+			if !reserved {
+				return domain.ValidationErrors{"fromReservation": "Number is not reserved"}
+			}
+		}
+		if err := h.validateProfile(tx, &profile, !request.FromReservation); err != nil {
+			return err
+		}
+
+		return auditedIn(tx, c, domain.AuditActionCreate, nil, &profile, func(tx *repositories.Repos) error {
+			if err := tx.Save(&profile); err != nil {
+				return err
+			}
+			if !request.FromReservation {
+				return nil
+			}
+			// Профиль занял номер по брони - бронь больше не нужна
+			_, err := tx.ReleaseReservation(profile.InternalNumber)
+			return err
+		})
+	})
+	if err != nil {
 		return err
 	}
 
@@ -70,13 +117,28 @@ func (h *Handler) UpdateProfile(c *fiber.Ctx) error {
 		return err
 	}
 
-	// Парсим новые данные
+	// Прежнее состояние читаем отдельно: BodyParser пишет в указатели профиля (locationId, ringGroup...)
+	// на месте, и у копии структуры они изменились бы вместе с профилем
+	var before domain.Profile
+	if err := h.repos.FindByID(&before, id); err != nil {
+		return err
+	}
+
+	// Парсим новые данные; ID и даты из тела не принимаются
 	if err := c.BodyParser(&profile); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
+	profile.ID, profile.CreatedAt, profile.DeletedAt = before.ID, before.CreatedAt, before.DeletedAt
+	// Забронированный номер можно занять только при создании профиля по брони
+	if err := h.validateProfile(h.repos, &profile, profile.InternalNumber != before.InternalNumber); err != nil {
+		return err
+	}
 
 	// Сохраняем
-	if err := h.repos.Save(&profile); err != nil {
+	err := h.audited(c, domain.AuditActionUpdate, &before, &profile, func(tx *repositories.Repos) error {
+		return tx.Save(&profile)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -94,7 +156,10 @@ func (h *Handler) DeleteProfile(c *fiber.Ctx) error {
 	}
 
 	// Удаляем
-	if err := h.repos.Delete(&profile); err != nil {
+	err := h.audited(c, domain.AuditActionDelete, &profile, nil, func(tx *repositories.Repos) error {
+		return tx.Delete(&profile)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories/dbtest"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateProfileAuditsPointerFields(t *testing.T) {
+	repos := dbtest.Open(t)
+	h := NewHandler(repos)
+	app := fiber.New(fiber.Config{ErrorHandler: h.ErrorHandler})
+	app.Put("/profiles/:id", h.UpdateProfile)
+
+	center := domain.Location{Name: "Центр", Server: "10.0.0.1", Subnet: "10.0.0.0/24", VoipVLAN: 100, VLAN: 10}
+	branch := domain.Location{Name: "Филиал", Server: "10.0.1.1", Subnet: "10.0.1.0/24", VoipVLAN: 101, VLAN: 11}
+	require.NoError(t, repos.Create(&center))
+	require.NoError(t, repos.Create(&branch))
+	ringGroup := 6008
+	profile := domain.Profile{Name: "Иванов", LocationID: &center.ID, InternalNumber: 6101, RingGroup: &ringGroup, IsActive: true}
+	require.NoError(t, repos.Create(&profile))
+
+	// id в теле не меняет изменяемый профиль
+	payload, err := json.Marshal(map[string]interface{}{
+		"id": 999, "name": "Иванов", "internalNumber": 6101, "locationId": branch.ID, "ringGroup": 6010, "isActive": true,
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest("PUT", "/profiles/1", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var saved domain.Profile
+	require.NoError(t, repos.FindByID(&saved, profile.ID))
+	assert.Equal(t, branch.ID, *saved.LocationID)
+	assert.Equal(t, 6010, *saved.RingGroup)
+	exists, err := repos.Exists(&domain.Profile{}, "id = ?", 999)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	var entries []domain.AuditEntry
+	require.NoError(t, repos.FindAll(&entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, domain.AuditActionUpdate, entries[0].Action)
+	assert.Equal(t, "1", entries[0].EntityID)
+	require.Contains(t, entries[0].Changes, "locationId")
+	require.Contains(t, entries[0].Changes, "ringGroup")
+	assert.EqualValues(t, center.ID, entries[0].Changes["locationId"].Old)
+	assert.EqualValues(t, branch.ID, entries[0].Changes["locationId"].New)
+	assert.EqualValues(t, 6008, entries[0].Changes["ringGroup"].Old)
+	assert.EqualValues(t, 6010, entries[0].Changes["ringGroup"].New)
+	assert.NotContains(t, entries[0].Changes, "id")
+
+	revisions, err := repos.FindRevisions(domain.AuditEntityProfile, "1")
+	require.NoError(t, err)
+	assert.Len(t, revisions, 1)
+}
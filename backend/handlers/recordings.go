@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RecordingsHandler хендлер записей разговоров
+type RecordingsHandler struct {
+	*Handler
+	recordingService *services.RecordingService
+	retentionService *services.RetentionService
+}
+
+// NewRecordingsHandler создает хендлер записей разговоров
+func NewRecordingsHandler(handler *Handler, recordingService *services.RecordingService, retentionService *services.RetentionService) *RecordingsHandler {
+	return &RecordingsHandler{
+		Handler:          handler,
+		recordingService: recordingService,
+		retentionService: retentionService,
+	}
+}
+
+// GetRecordings возвращает список записей с поиском и пагинацией
+func (h *RecordingsHandler) GetRecordings(c *fiber.Ctx) error {
+	pagination, ok := c.Locals("pagination").(*domain.PaginationInput)
+	if !ok {
+		return fiber.NewError(fiber.StatusInternalServerError, "Pagination not found in context")
+	}
+
+	var filter domain.RecordingFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid filter parameters")
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return err
+	}
+	filter.From = from
+	filter.To = to
+
+	// Пользователь видит только записи своей локации
+	scope, err := h.locationScope(c)
+	if err != nil {
+		return err
+	}
+	if scope != nil {
+		filter.LocationID = scope
+	}
+
+	recordings, total, err := h.repos.FindRecordings(&filter, pagination)
+	if err != nil {
+		return err
+	}
+
+	paginationResponse := domain.PaginationResponse{
+		Total:   total,
+		Page:    pagination.Page,
+		PerPage: pagination.PerPage,
+	}
+	paginationResponse.CalculatePages()
+
+	return c.JSON(domain.PaginatedResult{
+		Data:       recordings,
+		Pagination: paginationResponse,
+	})
+}
+
+// GetRecording возвращает одну запись по ID
+func (h *RecordingsHandler) GetRecording(c *fiber.Ctx) error {
+	recording, err := h.findRecording(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(recording)
+}
+
+// GetRecordingAudio отдаёт аудиофайл записи с поддержкой HTTP Range
+func (h *RecordingsHandler) GetRecordingAudio(c *fiber.Ctx) error {
+	recording, err := h.findRecording(c)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", recording.FileName))
+	return c.SendFile(recording.Path)
+}
+
+// ScanRecordings запускает сканирование каталога записей
+func (h *RecordingsHandler) ScanRecordings(c *fiber.Ctx) error {
+	result, err := h.recordingService.Scan()
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
+
+// findRecording находит запись по ID с учётом локации пользователя
+func (h *RecordingsHandler) findRecording(c *fiber.Ctx) (*domain.Recording, error) {
+	var recording domain.Recording
+	if err := h.repos.FindByID(&recording, c.Params("id")); err != nil {
+		return nil, err
+	}
+
+	scope, err := h.locationScope(c)
+	if err != nil {
+		return nil, err
+	}
+	if scope != nil && (recording.LocationID == nil || *recording.LocationID != *scope) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access to recording denied")
+	}
+
+	return &recording, nil
+}
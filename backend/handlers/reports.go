@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"asterisk-manager/domain"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseReportFilter разбирает общие параметры отчётов
+func parseReportFilter(c *fiber.Ctx) (*domain.ReportFilter, error) {
+	var filter domain.ReportFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid filter parameters")
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return nil, err
+	}
+	filter.From = from
+	filter.To = to
+
+	return &filter, nil
+}
+
+// GetCallsPerDayReport возвращает количество звонков по дням
+func (h *Handler) GetCallsPerDayReport(c *fiber.Ctx) error {
+	filter, err := parseReportFilter(c)
+	if err != nil {
+		return err
+	}
+
+	rows, err := h.repos.ReportCallsPerDay(filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(rows)
+}
+
+// GetRingGroupCallsReport возвращает отвеченные и пропущенные звонки по ринг-группам
+func (h *Handler) GetRingGroupCallsReport(c *fiber.Ctx) error {
+	filter, err := parseReportFilter(c)
+	if err != nil {
+		return err
+	}
+
+	rows, err := h.repos.ReportRingGroupCalls(filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(rows)
+}
+
+// GetCallDurationsReport возвращает среднее время ожидания и разговора
+func (h *Handler) GetCallDurationsReport(c *fiber.Ctx) error {
+	filter, err := parseReportFilter(c)
+	if err != nil {
+		return err
+	}
+
+	rows, err := h.repos.ReportCallDurations(filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(rows)
+}
+
+// GetBusiestHoursReport возвращает распределение звонков по часам
+func (h *Handler) GetBusiestHoursReport(c *fiber.Ctx) error {
+	filter, err := parseReportFilter(c)
+	if err != nil {
+		return err
+	}
+
+	rows, err := h.repos.ReportBusiestHours(filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(rows)
+}
+
+// GetTopCallersReport возвращает самых частых внешних абонентов по городским номерам
+func (h *Handler) GetTopCallersReport(c *fiber.Ctx) error {
+	filter, err := parseReportFilter(c)
+	if err != nil {
+		return err
+	}
+
+	rows, err := h.repos.ReportTopCallers(filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(rows)
+}
+
+// GetUnansweredDIDsReport возвращает неотвеченные входящие на городские номера
+func (h *Handler) GetUnansweredDIDsReport(c *fiber.Ctx) error {
+	filter, err := parseReportFilter(c)
+	if err != nil {
+		return err
+	}
+
+	rows, err := h.repos.ReportUnansweredDIDs(filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(rows)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"asterisk-manager/domain"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HoldRequest запрос на установку или снятие удержания записи
+type HoldRequest struct {
+	LegalHold bool   `json:"legalHold"`
+	Reason    string `json:"reason"`
+}
+
+// GetRetentionPolicies возвращает список политик хранения
+func (h *RecordingsHandler) GetRetentionPolicies(c *fiber.Ctx) error {
+	var policies []domain.RetentionPolicy
+	if err := h.repos.FindAll(&policies); err != nil {
+		return err
+	}
+	return c.JSON(policies)
+}
+
+// CreateRetentionPolicy создает политику хранения
+func (h *RecordingsHandler) CreateRetentionPolicy(c *fiber.Ctx) error {
+	var policy domain.RetentionPolicy
+	if err := c.BodyParser(&policy); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if policy.Days < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Retention days must be positive")
+	}
+
+	if err := h.repos.Save(&policy); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(policy)
+}
+
+// UpdateRetentionPolicy обновляет политику хранения
+func (h *RecordingsHandler) UpdateRetentionPolicy(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var policy domain.RetentionPolicy
+
+	// Проверяем существование
+	if err := h.repos.FindByID(&policy, id); err != nil {
+		return err
+	}
+
+	// Парсим новые данные
+	if err := c.BodyParser(&policy); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if policy.Days < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Retention days must be positive")
+	}
+
+	// Сохраняем
+	if err := h.repos.Save(&policy); err != nil {
+		return err
+	}
+
+	return c.JSON(policy)
+}
+
+// DeleteRetentionPolicy удаляет политику хранения
+func (h *RecordingsHandler) DeleteRetentionPolicy(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var policy domain.RetentionPolicy
+
+	// Проверяем существование
+	if err := h.repos.FindByID(&policy, id); err != nil {
+		return err
+	}
+
+	// Удаляем
+	if err := h.repos.Delete(&policy); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SetRecordingHold устанавливает или снимает удержание записи (legal hold)
+func (h *RecordingsHandler) SetRecordingHold(c *fiber.Ctx) error {
+	var req HoldRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	recording, err := h.findRecording(c)
+	if err != nil {
+		return err
+	}
+
+	recording.LegalHold = req.LegalHold
+	recording.HoldReason = ""
+	if req.LegalHold {
+		recording.HoldReason = req.Reason
+	}
+
+	if err := h.repos.Save(recording); err != nil {
+		return err
+	}
+
+	return c.JSON(recording)
+}
+
+// PurgeRecordings удаляет записи с истёкшим сроком хранения (?dryRun=true - только отчёт)
+func (h *RecordingsHandler) PurgeRecordings(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*services.JWTClaims)
+	report, err := h.retentionService.Purge(c.QueryBool("dryRun"), claims.Username)
+	if err != nil {
+		return err
+	}
+	return c.JSON(report)
+}
+
+// GetRecordingDeletions возвращает журнал удалений записей
+func (h *RecordingsHandler) GetRecordingDeletions(c *fiber.Ctx) error {
+	pagination, ok := c.Locals("pagination").(*domain.PaginationInput)
+	if !ok {
+		return fiber.NewError(fiber.StatusInternalServerError, "Pagination not found in context")
+	}
+
+	deletions, total, err := h.repos.FindRecordingDeletions(pagination)
+	if err != nil {
+		return err
+	}
+
+	paginationResponse := domain.PaginationResponse{
+		Total:   total,
+		Page:    pagination.Page,
+		PerPage: pagination.PerPage,
+	}
+	paginationResponse.CalculatePages()
+
+	return c.JSON(domain.PaginatedResult{
+		Data:       deletions,
+		Pagination: paginationResponse,
+	})
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// revisionEntityID возвращает идентификатор сущности из параметра маршрута (ID или MAC)
+func revisionEntityID(c *fiber.Ctx, entityType domain.AuditEntityType, param string) string {
+	if entityType == domain.AuditEntityDevice {
+		return domain.NormalizeMAC(c.Params(param))
+	}
+	return c.Params(param)
+}
+
+// GetRevisions возвращает историю ревизий сущности, новые сверху
+func (h *Handler) GetRevisions(entityType domain.AuditEntityType, param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		revisions, err := h.repos.FindRevisions(entityType, revisionEntityID(c, entityType, param))
+		if err != nil {
+			return err
+		}
+		if len(revisions) == 0 {
+			return fiber.NewError(fiber.StatusNotFound, "No revisions found")
+		}
+		return c.JSON(revisions)
+	}
+}
+
+// GetRevision возвращает одну ревизию сущности с полным состоянием
+func (h *Handler) GetRevision(entityType domain.AuditEntityType, param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		number, err := c.ParamsInt("rev")
+		if err != nil || number < 1 {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid revision number")
+		}
+
+		var revision domain.Revision
+		if err := h.repos.FindRevision(&revision, entityType, revisionEntityID(c, entityType, param), number); err != nil {
+			return err
+		}
+		return c.JSON(revision)
+	}
+}
+
+// RestoreRevision возвращает сущность к состоянию ревизии; сущность из корзины восстанавливается,
+// окончательно удалённая создаётся заново с прежним идентификатором.
+// Восстановление само становится новой ревизией.
+func (h *Handler) RestoreRevision(entityType domain.AuditEntityType, param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		number, err := c.ParamsInt("rev")
+		if err != nil || number < 1 {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid revision number")
+		}
+		entityID := revisionEntityID(c, entityType, param)
+
+		var revision domain.Revision
+		if err := h.repos.FindRevision(&revision, entityType, entityID, number); err != nil {
+			return err
+		}
+
+		restored, err := domain.NewAuditable(entityType)
+		if err != nil {
+			return err
+		}
+		if err := revision.Decode(restored); err != nil {
+			return err
+		}
+
+		current, err := domain.NewAuditable(entityType)
+		if err != nil {
+			return err
+		}
+		err = h.repos.FindEntity(current, entityID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			current = nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := h.checkRestorable(restored); err != nil {
+			return err
+		}
+
+		err = h.audited(c, domain.AuditActionRestore, current, restored, func(tx *repositories.Repos) error {
+			return tx.SaveUnscoped(restored)
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(restored)
+	}
+}
+
+// GetSnapshot возвращает профили, устройства и локации в том виде, какими они были
+// на момент ?at= (YYYY-MM-DD - на конец дня, или RFC 3339)
+func (h *Handler) GetSnapshot(c *fiber.Ctx) error {
+	at, err := parseSnapshotTime(c)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := h.repos.FindSnapshot(at)
+	if err != nil {
+		return err
+	}
+	return c.JSON(snapshot)
+}
+
+// parseSnapshotTime разбирает параметр at; без него - текущий момент.
+// Дата без времени означает конец этого дня.
+func parseSnapshotTime(c *fiber.Ctx) (time.Time, error) {
+	at, dateOnly, err := parseTimeParam(c, "at")
+	if err != nil {
+		return time.Time{}, err
+	}
+	if at == nil {
+		return time.Now(), nil
+	}
+	if dateOnly {
+		return at.AddDate(0, 0, 1), nil
+	}
+	return *at, nil
+}
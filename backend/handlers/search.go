@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"asterisk-manager/domain"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SearchResponse результат поиска
+type SearchResponse struct {
+	Query string             `json:"query"`
+	Hits  []domain.SearchHit `json:"hits"`
+}
+
+// searchPermissions права на чтение, без которых тип сущности не ищется
+var searchPermissions = map[domain.SearchHitType]domain.Permission{
+	domain.SearchHitProfile:   domain.PermissionProfilesRead,
+	domain.SearchHitDevice:    domain.PermissionDevicesRead,
+	domain.SearchHitLocation:  domain.PermissionLocationsRead,
+	domain.SearchHitRingGroup: domain.PermissionProfilesRead,
+}
+
+// Search ищет по профилям, устройствам, локациям и группам вызова (?q=иванов&limit=20).
+// Номера телефонов сравниваются только по цифрам ("24-48" находит 2448 и 22-44-87),
+// MAC - в любом формате записи.
+func (h *Handler) Search(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*services.JWTClaims)
+
+	types := []domain.SearchHitType{}
+	for _, hitType := range []domain.SearchHitType{
+		domain.SearchHitProfile, domain.SearchHitDevice, domain.SearchHitLocation, domain.SearchHitRingGroup,
+	} {
+		if claims.Can(searchPermissions[hitType]) {
+			types = append(types, hitType)
+		}
+	}
+
+	query, err := domain.NewSearchQuery(c.Query("q"), c.QueryInt("limit", domain.DefaultSearchLimit), types)
+	if err != nil {
+		return err
+	}
+
+	hits, err := h.repos.Search(query)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(SearchResponse{Query: c.Query("q"), Hits: hits})
+}
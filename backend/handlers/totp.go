@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TOTPCodeRequest код из приложения-аутентификатора
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// DisableTOTPRequest запрос на отключение второго фактора. Локальные пользователи
+// подтверждают его паролем и кодом TOTP (или кодом восстановления).
+type DisableTOTPRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// RecoveryCodesResponse коды восстановления; показываются один раз
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// EnableTOTPResponse ответ на подключение второго фактора: коды восстановления и
+// новый access-токен, в котором снято требование подключить TOTP
+type EnableTOTPResponse struct {
+	LoginResponse
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// SetupTOTP начинает подключение второго фактора: возвращает секрет, otpauth:// URI и QR-код
+func (h *AuthHandler) SetupTOTP(c *fiber.Ctx) error {
+	user, err := h.currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	setup, err := h.authService.BeginTOTPSetup(user)
+	if err != nil {
+		return totpError(err)
+	}
+	return c.JSON(setup)
+}
+
+// EnableTOTP включает второй фактор после проверки первого кода
+func (h *AuthHandler) EnableTOTP(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*services.JWTClaims)
+
+	var req TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Code is required")
+	}
+
+	user, err := h.currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	codes, err := h.authService.EnableTOTP(user, req.Code)
+	if err != nil {
+		return totpError(err)
+	}
+
+	token, expiresAt, err := h.authService.GenerateToken(user, claims.SessionID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to generate token")
+	}
+
+	return c.JSON(EnableTOTPResponse{
+		LoginResponse: LoginResponse{
+			Token:     token,
+			ExpiresAt: expiresAt,
+			User:      user.ToResponse(),
+		},
+		RecoveryCodes: codes,
+	})
+}
+
+// DisableTOTP отключает второй фактор, если он не обязателен для роли пользователя
+func (h *AuthHandler) DisableTOTP(c *fiber.Ctx) error {
+	var req DisableTOTPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Code is required")
+	}
+
+	user, err := h.currentUser(c)
+	if err != nil {
+		return err
+	}
+	if !user.IsExternal() && !user.CheckPassword(req.Password) {
+		return fiber.NewError(fiber.StatusBadRequest, "Current password is incorrect")
+	}
+
+	if err := h.authService.DisableTOTP(user, req.Code, req.RecoveryCode); err != nil {
+		return totpError(err)
+	}
+	return c.JSON(user.ToResponse())
+}
+
+// RegenerateRecoveryCodes выдаёт новые коды восстановления; старые перестают действовать
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	var req TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Code is required")
+	}
+
+	user, err := h.currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled {
+		return totpError(services.ErrTOTPNotSetUp)
+	}
+	if err := h.authService.CheckTOTP(user, req.Code); err != nil {
+		return totpError(err)
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(user)
+	if err != nil {
+		return totpError(err)
+	}
+	return c.JSON(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// ResetUserTOTP сбрасывает второй фактор пользователя, потерявшего устройство и коды
+// восстановления, и завершает его сессии. Если второй фактор обязателен для роли,
+// пользователь подключит его заново при следующем входе.
+func (h *AuthHandler) ResetUserTOTP(c *fiber.Ctx) error {
+	var user domain.User
+	if err := h.repos.FindByID(&user, c.Params("id")); err != nil {
+		return err
+	}
+
+	if err := h.authService.ResetTOTP(&user); err != nil {
+		return err
+	}
+	if err := h.authService.RevokeUserSessions(user.ID, 0, services.RevokeReasonTOTPReset); err != nil {
+		return err
+	}
+
+	return c.JSON(user.ToResponse())
+}
+
+// currentUser загружает текущего пользователя из БД
+func (h *AuthHandler) currentUser(c *fiber.Ctx) (*domain.User, error) {
+	claims := c.Locals("user").(*services.JWTClaims)
+
+	var user domain.User
+	if err := h.repos.FindByID(&user, claims.UserID); err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	return &user, nil
+}
+
+// totpError переводит ошибки второго фактора в HTTP-ответы
+func totpError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode):
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid two-factor code")
+	case errors.Is(err, services.ErrTOTPAlreadyEnabled),
+		errors.Is(err, services.ErrTOTPNotSetUp),
+		errors.Is(err, services.ErrTOTPExternal):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrTOTPRequired):
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	}
+	return err
+}
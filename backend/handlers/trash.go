@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetTrash возвращает удалённые сущности заданного типа, недавно удалённые сверху
+func (h *Handler) GetTrash(entityType domain.AuditEntityType) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var deleted interface{}
+		switch entityType {
+		case domain.AuditEntityProfile:
+			deleted = &[]domain.Profile{}
+		case domain.AuditEntityDevice:
+			deleted = &[]domain.Device{}
+		case domain.AuditEntityLocation:
+			deleted = &[]domain.Location{}
+		}
+
+		if err := h.repos.FindDeleted(deleted); err != nil {
+			return err
+		}
+		return c.JSON(deleted)
+	}
+}
+
+// RestoreDeleted возвращает сущность из корзины
+func (h *Handler) RestoreDeleted(entityType domain.AuditEntityType, param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		entity, err := h.findDeleted(c, entityType, param)
+		if err != nil {
+			return err
+		}
+
+		restored, err := h.findDeleted(c, entityType, param)
+		if err != nil {
+			return err
+		}
+		setDeletedAt(restored, gorm.DeletedAt{})
+
+		if err := h.checkRestorable(restored); err != nil {
+			return err
+		}
+
+		err = h.audited(c, domain.AuditActionRestore, entity, restored, func(tx *repositories.Repos) error {
+			return tx.SaveUnscoped(restored)
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(restored)
+	}
+}
+
+// PurgeDeleted окончательно удаляет сущность из корзины
+func (h *Handler) PurgeDeleted(entityType domain.AuditEntityType, param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		entity, err := h.findDeleted(c, entityType, param)
+		if err != nil {
+			return err
+		}
+
+		err = h.audited(c, domain.AuditActionPurge, entity, nil, func(tx *repositories.Repos) error {
+			return tx.Purge(entity)
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// findDeleted загружает сущность из корзины по параметру маршрута
+func (h *Handler) findDeleted(c *fiber.Ctx, entityType domain.AuditEntityType, param string) (domain.Auditable, error) {
+	entity, err := domain.NewAuditable(entityType)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.repos.FindDeletedEntity(entity, revisionEntityID(c, entityType, param)); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// checkRestorable проверяет, что восстановленный профиль будет рабочим: его внутренний номер
+// мог быть выдан другому сотруднику или забронирован, а устройство или локация - остаться в корзине
+// (такой профиль не попал бы в генерацию)
+func (h *Handler) checkRestorable(entity domain.Auditable) error {
+	profile, ok := entity.(*domain.Profile)
+	if !ok {
+		return nil
+	}
+
+	taken, err := h.repos.InternalNumberTaken(profile.InternalNumber, profile.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fiber.NewError(fiber.StatusConflict, "Internal number is already in use by another profile")
+	}
+	// Пока профиль был в корзине, его номер могли забронировать
+	if err := numberNotReserved(h.repos, profile.InternalNumber); err != nil {
+		return err
+	}
+
+	if profile.Device != nil {
+		var device domain.Device
+		err := h.repos.FindDeletedEntity(&device, *profile.Device)
+		if err == nil {
+			return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Device %s is in the trash, restore it first", device.MAC))
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	if profile.LocationID != nil {
+		var location domain.Location
+		err := h.repos.FindDeletedEntity(&location, strconv.FormatUint(uint64(*profile.LocationID), 10))
+		if err == nil {
+			return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Location %q is in the trash, restore it first", location.Name))
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// setDeletedAt меняет отметку удаления сущности
+func setDeletedAt(entity domain.Auditable, deletedAt gorm.DeletedAt) {
+	switch e := entity.(type) {
+	case *domain.Profile:
+		e.DeletedAt = deletedAt
+	case *domain.Device:
+		e.DeletedAt = deletedAt
+	case *domain.Location:
+		e.DeletedAt = deletedAt
+	}
+}
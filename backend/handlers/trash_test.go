@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories/dbtest"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreProfileWithTrashedDependencies(t *testing.T) {
+	repos := dbtest.Open(t)
+	h := NewHandler(repos)
+	app := fiber.New(fiber.Config{ErrorHandler: h.ErrorHandler})
+	app.Post("/profiles/:id/restore", h.RestoreDeleted(domain.AuditEntityProfile, "id"))
+	app.Post("/devices/:mac/restore", h.RestoreDeleted(domain.AuditEntityDevice, "mac"))
+	app.Post("/locations/:id/restore", h.RestoreDeleted(domain.AuditEntityLocation, "id"))
+
+	location := domain.Location{Name: "ЗАГС", Server: "10.0.0.1", Subnet: "10.0.0.0/24", VoipVLAN: 10, VLAN: 20}
+	require.NoError(t, repos.Create(&location))
+	device := domain.Device{MAC: "80:5e:c0:18:ab:ac", DeviceModel: domain.DeviceModelFanvil}
+	require.NoError(t, repos.Create(&device))
+	profile := domain.Profile{Name: "Иванов", InternalNumber: 6101, Device: &device.MAC, LocationID: &location.ID, IsActive: true}
+	require.NoError(t, repos.Create(&profile))
+	for _, entity := range []interface{}{&profile, &device, &location} {
+		require.NoError(t, repos.Delete(entity))
+	}
+
+	restore := func(path string) (int, ErrorResponse) {
+		resp, err := app.Test(httptest.NewRequest("POST", path, nil))
+		require.NoError(t, err)
+		var body ErrorResponse
+		if resp.StatusCode != fiber.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		}
+		return resp.StatusCode, body
+	}
+
+	status, body := restore("/profiles/1/restore")
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, "Device 80:5e:c0:18:ab:ac is in the trash, restore it first", body.Error)
+
+	status, _ = restore("/devices/80:5e:c0:18:ab:ac/restore")
+	require.Equal(t, fiber.StatusOK, status)
+
+	status, body = restore("/profiles/1/restore")
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, `Location "ЗАГС" is in the trash, restore it first`, body.Error)
+
+	status, _ = restore("/locations/1/restore")
+	require.Equal(t, fiber.StatusOK, status)
+
+	status, _ = restore("/profiles/1/restore")
+	assert.Equal(t, fiber.StatusOK, status)
+	exists, err := repos.Exists(&domain.Profile{}, "id = ?", profile.ID)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// Внутренний номер, выданный другому сотруднику, тоже не даёт восстановить
+	require.NoError(t, repos.Delete(&profile))
+	other := domain.Profile{Name: "Петров", InternalNumber: 6101, IsActive: true}
+	require.NoError(t, repos.Create(&other))
+	status, _ = restore("/profiles/1/restore")
+	assert.Equal(t, fiber.StatusConflict, status)
+}
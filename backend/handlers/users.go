@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// CreateUserRequest запрос на создание пользователя
+type CreateUserRequest struct {
+	Username   string          `json:"username"`
+	Password   string          `json:"password"`
+	Role       domain.UserRole `json:"role"`
+	LocationID *uint           `json:"locationId"`
+}
+
+// UpdateUserRequest запрос на изменение пользователя; пустые поля не меняются
+type UpdateUserRequest struct {
+	Role       *domain.UserRole `json:"role"`
+	LocationID *uint            `json:"locationId"`
+	IsActive   *bool            `json:"isActive"`
+}
+
+// ResetPasswordRequest запрос на сброс пароля администратором
+type ResetPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// GetUsers возвращает список пользователей
+func (h *AuthHandler) GetUsers(c *fiber.Ctx) error {
+	var users []domain.User
+	if err := h.repos.FindUsers(&users); err != nil {
+		return err
+	}
+
+	response := make([]domain.UserResponse, 0, len(users))
+	for i := range users {
+		response = append(response, users[i].ToResponse())
+	}
+	return c.JSON(response)
+}
+
+// GetUser возвращает пользователя по ID
+func (h *AuthHandler) GetUser(c *fiber.Ctx) error {
+	var user domain.User
+	if err := h.repos.FindByID(&user, c.Params("id")); err != nil {
+		return err
+	}
+	return c.JSON(user.ToResponse())
+}
+
+// CreateUser создает пользователя; выданный пароль нужно сменить при первом входе
+func (h *AuthHandler) CreateUser(c *fiber.Ctx) error {
+	var req CreateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Username == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Username is required")
+	}
+	if req.Role == "" {
+		req.Role = domain.UserRoleUser
+	}
+	if !req.Role.IsValid() {
+		return fiber.NewError(fiber.StatusBadRequest, "Unknown role")
+	}
+	if err := h.authService.PasswordPolicy().Validate(req.Password, req.Username); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var existing domain.User
+	err := h.repos.FindUserByUsername(&existing, req.Username)
+	if err == nil {
+		return fiber.NewError(fiber.StatusConflict, "Username already exists")
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	user := domain.User{
+		Username:           req.Username,
+		Role:               req.Role,
+		LocationID:         req.LocationID,
+		AuthSource:         domain.AuthSourceLocal,
+		IsActive:           true,
+		MustChangePassword: true,
+	}
+	if err := user.SetPassword(req.Password); err != nil {
+		return err
+	}
+
+	if err := h.repos.Create(&user); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(user.ToResponse())
+}
+
+// UpdateUser меняет роль, локацию или блокирует пользователя
+func (h *AuthHandler) UpdateUser(c *fiber.Ctx) error {
+	var req UpdateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	var user domain.User
+	if err := h.repos.FindByID(&user, c.Params("id")); err != nil {
+		return err
+	}
+
+	// Сессии с прежней ролью или заблокированного пользователя отзываются
+	revokeReason := ""
+	if req.Role != nil {
+		if !req.Role.IsValid() {
+			return fiber.NewError(fiber.StatusBadRequest, "Unknown role")
+		}
+		if user.IsExternal() && *req.Role != user.Role {
+			return fiber.NewError(fiber.StatusBadRequest, "Role of external user is managed by the identity provider")
+		}
+		if *req.Role != user.Role {
+			revokeReason = services.RevokeReasonRoleChanged
+		}
+		user.Role = *req.Role
+	}
+	if req.LocationID != nil {
+		user.LocationID = req.LocationID
+		if *req.LocationID == 0 {
+			user.LocationID = nil
+		}
+	}
+	if req.IsActive != nil {
+		if user.IsActive && !*req.IsActive {
+			revokeReason = services.RevokeReasonDisabled
+		}
+		user.IsActive = *req.IsActive
+	}
+
+	if user.Role != domain.UserRoleAdmin || !user.IsActive {
+		if err := h.ensureAnotherAdmin(c, user.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := h.repos.Save(&user); err != nil {
+		return err
+	}
+	if revokeReason != "" {
+		if err := h.authService.RevokeUserSessions(user.ID, 0, revokeReason); err != nil {
+			return err
+		}
+	}
+
+	return c.JSON(user.ToResponse())
+}
+
+// ResetUserPassword задаёт пользователю временный пароль
+func (h *AuthHandler) ResetUserPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	var user domain.User
+	if err := h.repos.FindByID(&user, c.Params("id")); err != nil {
+		return err
+	}
+
+	if user.IsExternal() {
+		return fiber.NewError(fiber.StatusBadRequest, "Password of external user is managed by the identity provider")
+	}
+	if err := h.authService.PasswordPolicy().Validate(req.Password, user.Username); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := user.SetPassword(req.Password); err != nil {
+		return err
+	}
+	user.MustChangePassword = true
+
+	if err := h.repos.Save(&user); err != nil {
+		return err
+	}
+	if err := h.authService.RevokeUserSessions(user.ID, 0, services.RevokeReasonPasswordReset); err != nil {
+		return err
+	}
+
+	return c.JSON(user.ToResponse())
+}
+
+// DeleteUser удаляет пользователя
+func (h *AuthHandler) DeleteUser(c *fiber.Ctx) error {
+	var user domain.User
+	if err := h.repos.FindByID(&user, c.Params("id")); err != nil {
+		return err
+	}
+
+	if err := h.ensureAnotherAdmin(c, user.ID); err != nil {
+		return err
+	}
+
+	if err := h.authService.RevokeUserSessions(user.ID, 0, services.RevokeReasonDisabled); err != nil {
+		return err
+	}
+	if err := h.repos.Delete(&user); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetUserSessions возвращает действующие сессии пользователя
+func (h *AuthHandler) GetUserSessions(c *fiber.Ctx) error {
+	var user domain.User
+	if err := h.repos.FindByID(&user, c.Params("id")); err != nil {
+		return err
+	}
+
+	claims := c.Locals("user").(*services.JWTClaims)
+	return h.sendSessions(c, user.ID, claims.SessionID)
+}
+
+// RevokeUserSessions завершает все сессии пользователя
+func (h *AuthHandler) RevokeUserSessions(c *fiber.Ctx) error {
+	var user domain.User
+	if err := h.repos.FindByID(&user, c.Params("id")); err != nil {
+		return err
+	}
+
+	if err := h.authService.RevokeUserSessions(user.ID, 0, services.RevokeReasonAdmin); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ensureAnotherAdmin запрещает администратору лишать прав самого себя и
+// не даёт отключить последнего активного администратора
+func (h *AuthHandler) ensureAnotherAdmin(c *fiber.Ctx, userID uint) error {
+	claims := c.Locals("user").(*services.JWTClaims)
+	if claims.UserID == userID {
+		return fiber.NewError(fiber.StatusConflict, "Cannot disable, demote or delete your own account")
+	}
+
+	admins, err := h.repos.CountActiveAdmins(userID)
+	if err != nil {
+		return err
+	}
+	if admins == 0 {
+		return fiber.NewError(fiber.StatusConflict, "At least one active admin is required")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+
+	"gorm.io/gorm"
+)
+
+// validateProfile проверяет профиль: формат полей, существование устройства и локации
+// и то, что внутренний номер не занят другим профилем. Внутри транзакции repos - её tx,
+// иначе проверка не видит изменений транзакции и занимает второе соединение пула.
+// С checkReservation забронированный номер - 409: его можно занять только по брони.
+func (h *Handler) validateProfile(repos *repositories.Repos, profile *domain.Profile, checkReservation bool) error {
+	errs := profile.Validate()
+
+	if _, failed := errs["device"]; !failed && profile.Device != nil {
+		exists, err := repos.Exists(&domain.Device{}, "mac = ?", *profile.Device)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			errs.Add("device", "Device not found")
+		}
+	}
+
+	if profile.LocationID != nil {
+		exists, err := repos.Exists(&domain.Location{}, "id = ?", *profile.LocationID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			errs.Add("locationId", "Location not found")
+		}
+	}
+
+	if _, failed := errs["internalNumber"]; !failed {
+		taken, err := repos.InternalNumberTaken(profile.InternalNumber, profile.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			errs.Add("internalNumber", "Already in use by another profile")
+		}
+	}
+
+	if err := errs.Err(); err != nil || !checkReservation {
+		return err
+	}
+	return numberNotReserved(repos, profile.InternalNumber)
+}
+
+// validateDevice проверяет устройство; при создании MAC не должен быть занят
+func (h *Handler) validateDevice(device *domain.Device, create bool) error {
+	errs := device.Validate()
+
+	if _, failed := errs["mac"]; !failed && create {
+		exists, err := h.repos.Exists(&domain.Device{}, "mac = ?", device.MAC)
+		if err != nil {
+			return err
+		}
+		if exists {
+			errs.Add("mac", "Device with this MAC already exists")
+		}
+	}
+
+	return errs.Err()
+}
+
+// validateLocation проверяет локацию
+func (h *Handler) validateLocation(location *domain.Location) error {
+	return location.Validate().Err()
+}
+
+// validateNumberPool проверяет пул номеров: существование локации и то,
+// что диапазон не пересекается с другими пулами
+func (h *Handler) validateNumberPool(pool *domain.NumberPool) error {
+	errs := pool.Validate()
+
+	if pool.LocationID != nil {
+		exists, err := h.repos.Exists(&domain.Location{}, "id = ?", *pool.LocationID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			errs.Add("locationId", "Location not found")
+		}
+	}
+
+	if len(errs) == 0 {
+		var other domain.NumberPool
+		err := h.repos.FindOne(&other, "id <> ? AND range_start <= ? AND range_end >= ?", pool.ID, pool.RangeEnd, pool.RangeStart)
+		switch {
+		case err == nil:
+			errs.Add("rangeStart", fmt.Sprintf("Overlaps pool %s (%d-%d)", other.Name, other.RangeStart, other.RangeEnd))
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+	}
+
+	return errs.Err()
+}
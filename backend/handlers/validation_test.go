@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileValidate(t *testing.T) {
+	mac := "xyz"
+	ringGroup := -1
+	profile := domain.Profile{Name: " ", Email: "Иванов <ivanov@example.com>", InternalNumber: 1234567, Device: &mac, RingGroup: &ringGroup}
+
+	errs := profile.Validate()
+	assert.Equal(t, []string{"device", "email", "internalNumber", "name", "ringGroup"}, fieldNames(errs))
+
+	mac = "00:15:65:AA:BB:CC"
+	valid := domain.Profile{Name: "Иванов", Email: "ivanov@example.com", InternalNumber: 1001, Device: &mac}
+	assert.Empty(t, valid.Validate())
+}
+
+func TestDeviceValidate(t *testing.T) {
+	assert.Empty(t, (&domain.Device{MAC: "00-15-65-aa-bb-cc", DeviceModel: domain.DeviceModelFanvil}).Validate())
+
+	errs := (&domain.Device{MAC: "00:15:65:aa:bb", DeviceModel: "Panasonic"}).Validate()
+	assert.Equal(t, []string{"deviceModel", "mac"}, fieldNames(errs))
+}
+
+func TestLocationValidate(t *testing.T) {
+	// Сервер вне подсети телефонов допустим
+	valid := domain.Location{Name: "Zags", Server: "10.16.0.102", Subnet: "10.1.191.0/26", VoipVLAN: 5, VLAN: 601}
+	assert.Empty(t, valid.Validate())
+	inSubnet := valid
+	inSubnet.Server = "10.1.191.1"
+	assert.Empty(t, inSubnet.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(l *domain.Location)
+		field  string
+	}{
+		{"subnet without prefix", func(l *domain.Location) { l.Subnet = "10.1.191.0" }, "subnet"},
+		{"subnet with host bits", func(l *domain.Location) { l.Subnet = "10.1.191.5/26" }, "subnet"},
+		{"server is not an IP", func(l *domain.Location) { l.Server = "sip.local" }, "server"},
+		{"server is broadcast of subnet", func(l *domain.Location) { l.Server = "10.1.191.63" }, "server"},
+		{"server is network of subnet", func(l *domain.Location) { l.Server = "10.1.191.0" }, "server"},
+		{"server of other IP version", func(l *domain.Location) { l.Server = "fd00::1" }, "server"},
+		{"VLAN out of range", func(l *domain.Location) { l.VLAN = 9999 }, "vlan"},
+		{"VoIP VLAN zero", func(l *domain.Location) { l.VoipVLAN = 0 }, "voipVlan"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := valid
+			tt.modify(&location)
+			assert.Equal(t, []string{tt.field}, fieldNames(location.Validate()))
+		})
+	}
+}
+
+func TestErrorHandlerValidation(t *testing.T) {
+	handler := NewHandler(&repositories.Repos{})
+	app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler})
+	app.Get("/test", func(c *fiber.Ctx) error {
+		return domain.ValidationErrors{"mac": "Invalid MAC address"}
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/test", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+
+	var body ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "Validation failed", body.Error)
+	assert.Equal(t, map[string]string{"mac": "Invalid MAC address"}, body.Fields)
+}
+
+func fieldNames(errs domain.ValidationErrors) []string {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
+	"asterisk-manager/domain"
 	"asterisk-manager/handlers"
 	"asterisk-manager/repositories"
+	"asterisk-manager/services"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 )
 
 func main() {
@@ -30,6 +36,13 @@ func main() {
 	}
 	fmt.Println("✅ Миграции выполнены успешно")
 
+	// Первая ревизия для сущностей без истории (созданных до её появления или seed)
+	if created, err := repos.BaselineRevisions(); err != nil {
+		log.Fatalf("❌ Ошибка создания истории ревизий: %v", err)
+	} else if created > 0 {
+		fmt.Printf("🕰️  Создано начальных ревизий: %d\n", created)
+	}
+
 	// Создаем дефолтного админа
 	fmt.Println("\n👤 Проверка пользователя admin...")
 	if err := repos.CreateDefaultAdmin(); err != nil {
@@ -37,28 +50,117 @@ func main() {
 	}
 	fmt.Println("✅ Пользователь admin готов")
 
+	// Запускаем приём CDR
+	cdrConfig := services.CDRConfigFromEnv()
+	if cdrConfig.Source != "" {
+		fmt.Printf("\n📞 Приём CDR из источника: %s\n", cdrConfig.Source)
+		cdrService := services.NewCDRService(repos, cdrConfig)
+		go func() {
+			if err := cdrService.Run(context.Background()); err != nil {
+				log.Printf("❌ Ошибка приёма CDR: %v", err)
+			}
+		}()
+	}
+
+	// Индекс записей разговоров и политики хранения
+	recordingService := services.NewRecordingService(repos, services.RecordingConfigFromEnv())
+	retentionService := services.NewRetentionService(repos)
+	if _, err := os.Stat(recordingService.Config().Dir); err == nil {
+		fmt.Printf("\n🎙️  Индексация записей из %s\n", recordingService.Config().Dir)
+		go services.RunPeriodically(context.Background(), "Recordings", recordingService.Config().ScanInterval, func() error {
+			_, err := recordingService.Scan()
+			return err
+		})
+		go services.RunPeriodically(context.Background(), "Retention", services.PurgeIntervalFromEnv(), func() error {
+			report, err := retentionService.Purge(false, "scheduler")
+			if err == nil && report.Deleted+report.Failed > 0 {
+				log.Printf("Retention: удалено %d, ошибок %d", report.Deleted, report.Failed)
+			}
+			return err
+		})
+	} else {
+		fmt.Printf("\n⚠️  Каталог записей %s недоступен, индексация выключена\n", recordingService.Config().Dir)
+	}
+
+	// Рассылка факсов по почте
+	var faxDeliveryService *services.FaxDeliveryService
+	faxDeliveryConfig := services.FaxDeliveryConfigFromEnv()
+	if faxDeliveryConfig.SMTP.Enabled() {
+		fmt.Printf("\n✉️  Рассылка факсов через SMTP %s\n", faxDeliveryConfig.SMTP.Host)
+		faxDeliveryService = services.NewFaxDeliveryService(repos, faxDeliveryConfig)
+		go services.RunPeriodically(context.Background(), "Fax delivery", faxDeliveryConfig.PollInterval, faxDeliveryService.ProcessPending)
+	}
+
+	// Индекс принятых факсов
+	faxService := services.NewFaxService(repos, services.FaxConfigFromEnv(), faxDeliveryService)
+	if _, err := os.Stat(faxService.Config().Dir); err == nil {
+		fmt.Printf("\n📠 Индексация факсов из %s\n", faxService.Config().Dir)
+		go services.RunPeriodically(context.Background(), "Faxes", faxService.Config().ScanInterval, func() error {
+			_, err := faxService.Scan()
+			return err
+		})
+	} else {
+		fmt.Printf("\n⚠️  Каталог факсов %s недоступен, индексация выключена\n", faxService.Config().Dir)
+	}
+
+	// Истёкшие брони номеров пулов не учитываются при выдаче номеров, таблица чистится раз в час
+	go services.RunPeriodically(context.Background(), "Number reservations", time.Hour, func() error {
+		_, err := repos.DeleteExpiredReservations(time.Now())
+		return err
+	})
+
 	// Создаём handler
 	h := handlers.NewHandler(repos)
 	authHandler := handlers.NewAuthHandler(h)
+	recordingsHandler := handlers.NewRecordingsHandler(h, recordingService, retentionService)
+	faxesHandler := handlers.NewFaxesHandler(h, faxService, faxDeliveryService)
+	numberPoolsHandler := handlers.NewNumberPoolsHandler(h, reservationTTLFromEnv())
+
+	if authHandler.GetAuthService().LDAPEnabled() {
+		fmt.Printf("\n🔑 Вход через LDAP: %s\n", services.LDAPConfigFromEnv().URL)
+	}
+
+	if oidc := authHandler.GetAuthService().OIDC(); oidc != nil {
+		fmt.Printf("\n🔑 Вход через OIDC: %s\n", oidc.Config().Issuer)
+	}
+
+	if roles := authHandler.GetAuthService().MFAPolicy().RequiredRoles; len(roles) > 0 {
+		fmt.Printf("\n🔐 TOTP обязателен для ролей: %v\n", roles)
+	}
+
+	// Очистка истёкших сессий и старого журнала попыток входа
+	go services.RunPeriodically(context.Background(), "Sessions", time.Hour, authHandler.GetAuthService().CleanupSessions)
+	go services.RunPeriodically(context.Background(), "Login attempts", 24*time.Hour, authHandler.GetLoginGuard().Cleanup)
+
+	// Окончательное удаление из корзины
+	trashService := services.NewTrashService(repos)
+	fmt.Printf("\n🗑️  Корзина очищается от записей старше %s\n", trashService.Retention())
+	go services.RunPeriodically(context.Background(), "Trash", 24*time.Hour, trashService.Purge)
 
 	// Создаём Fiber приложение
+	// IP клиента берётся из X-Real-IP только от доверенных прокси (nginx фронтенда)
 	app := fiber.New(fiber.Config{
-		ErrorHandler: h.ErrorHandler,
-		AppName:      "Asterisk Manager API",
+		ErrorHandler:            h.ErrorHandler,
+		AppName:                 "Asterisk Manager API",
+		ProxyHeader:             "X-Real-IP",
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          trustedProxiesFromEnv(),
 	})
 
 	// Middleware
 	app.Use(recover.New())
+	app.Use(requestid.New())
 	app.Use(logger.New(logger.Config{
-		Format: "${time} | ${status} | ${latency} | ${method} ${path}\n",
+		Format: "${time} | ${locals:requestid} | ${status} | ${latency} | ${method} ${path}\n",
 	}))
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, X-API-Key, X-Request-ID",
+		ExposeHeaders: "X-Request-ID",
 	}))
 
 	// Инициализируем роуты
-	initRoutes(app, h, authHandler)
+	initRoutes(app, h, authHandler, recordingsHandler, faxesHandler, numberPoolsHandler)
 
 	// Запускаем сервер
 	port := os.Getenv("APP_PORT")
@@ -71,3 +173,21 @@ func main() {
 		log.Fatalf("❌ Ошибка запуска сервера: %v", err)
 	}
 }
+
+// trustedProxiesFromEnv возвращает адреса прокси, которым доверяется X-Real-IP
+func trustedProxiesFromEnv() []string {
+	value := os.Getenv("TRUSTED_PROXIES")
+	if value == "" {
+		value = "127.0.0.1,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16"
+	}
+	return strings.Split(value, ",")
+}
+
+// reservationTTLFromEnv возвращает срок брони номера пула по умолчанию
+func reservationTTLFromEnv() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("NUMBER_RESERVATION_TTL"))
+	if err != nil || ttl <= 0 || ttl > domain.MaxReservationTTL {
+		return 24 * time.Hour
+	}
+	return ttl
+}
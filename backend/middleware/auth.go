@@ -1,24 +1,34 @@
 package middleware
 
 import (
+	"log"
 	"strings"
 
+	"asterisk-manager/domain"
 	"asterisk-manager/services"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// JWTAuth middleware для проверки JWT токена
+// JWTAuth middleware для проверки JWT токена или API-ключа.
+// Ключ передаётся заголовком "X-API-Key: <key>" или "Authorization: ApiKey <key>".
 func JWTAuth(authService *services.AuthService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		if key := c.Get("X-API-Key"); key != "" {
+			return apiKeyAuth(c, authService, key)
+		}
+
 		// Получаем токен из заголовка Authorization
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
 			return fiber.NewError(fiber.StatusUnauthorized, "Authorization header required")
 		}
 
-		// Проверяем формат "Bearer <token>"
+		// Проверяем формат "Bearer <token>" или "ApiKey <key>"
 		parts := strings.Split(authHeader, " ")
+		if len(parts) == 2 && parts[0] == "ApiKey" {
+			return apiKeyAuth(c, authService, parts[1])
+		}
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			return fiber.NewError(fiber.StatusUnauthorized, "Invalid authorization header format")
 		}
@@ -37,3 +47,88 @@ func JWTAuth(authService *services.AuthService) fiber.Handler {
 		return c.Next()
 	}
 }
+
+// apiKeyAuth проверяет API-ключ, выполняет запрос и записывает его в журнал ключа
+func apiKeyAuth(c *fiber.Ctx, authService *services.AuthService, key string) error {
+	claims, err := authService.ValidateAPIKey(key)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired API key")
+	}
+
+	c.Locals("user", claims)
+
+	// Ошибку сразу превращаем в ответ, чтобы записать в журнал настоящий статус
+	if err := c.Next(); err != nil {
+		if err := c.App().ErrorHandler(c, err); err != nil {
+			return err
+		}
+	}
+
+	status := c.Response().StatusCode()
+	if err := authService.LogAPIKeyUsage(claims.APIKeyID, c.Method(), c.Path(), status, c.IP()); err != nil {
+		log.Printf("API key %d: не удалось записать использование: %v", claims.APIKeyID, err)
+	}
+	return nil
+}
+
+// RequirePermission middleware для проверки права текущего пользователя.
+// Должен стоять после JWTAuth.
+func RequirePermission(permission domain.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*services.JWTClaims)
+		if !ok {
+			return fiber.NewError(fiber.StatusUnauthorized, "Authorization required")
+		}
+
+		if !claims.Can(permission) {
+			return fiber.NewError(fiber.StatusForbidden, "Permission denied: "+string(permission))
+		}
+
+		return c.Next()
+	}
+}
+
+// RequirePasswordChanged не пускает к API пользователя, который обязан сменить пароль.
+// Должен стоять после JWTAuth.
+func RequirePasswordChanged(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*services.JWTClaims)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization required")
+	}
+
+	if claims.MustChangePassword {
+		return fiber.NewError(fiber.StatusForbidden, "Password change required")
+	}
+
+	return c.Next()
+}
+
+// RequireMFAEnrolled не пускает к API пользователя, роль которого требует второй фактор,
+// пока он его не подключит. Должен стоять после JWTAuth.
+func RequireMFAEnrolled(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*services.JWTClaims)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization required")
+	}
+
+	if claims.MFAEnrollmentRequired {
+		return fiber.NewError(fiber.StatusForbidden, "Two-factor authentication enrollment required")
+	}
+
+	return c.Next()
+}
+
+// DenyAPIKeys закрывает эндпоинты, которые имеют смысл только для сессии человека
+// (смена пароля, выход, список сессий). Должен стоять после JWTAuth.
+func DenyAPIKeys(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*services.JWTClaims)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization required")
+	}
+
+	if claims.APIKeyID != 0 {
+		return fiber.NewError(fiber.StatusForbidden, "Not available for API keys")
+	}
+
+	return c.Next()
+}
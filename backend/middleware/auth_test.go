@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestApp приложение, в котором запрос выполняется с claims, как после JWTAuth (nil - без авторизации)
+func newTestApp(claims *services.JWTClaims, handlers ...fiber.Handler) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if claims != nil {
+			c.Locals("user", claims)
+		}
+		return c.Next()
+	})
+	handlers = append(handlers, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Post("/api-keys", handlers...)
+	return app
+}
+
+func status(t *testing.T, app *fiber.App) int {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("POST", "/api-keys", nil))
+	require.NoError(t, err)
+	return resp.StatusCode
+}
+
+func TestRequirePermissionAPIKeyScopes(t *testing.T) {
+	// Роль создателя разрешает, но scope ключу не выдан
+	key := &services.JWTClaims{
+		Role:     domain.UserRoleAdmin,
+		APIKeyID: 1,
+		Scopes:   []domain.Permission{domain.PermissionProfilesRead},
+	}
+	assert.Equal(t, fiber.StatusForbidden, status(t, newTestApp(key, RequirePermission(domain.PermissionProfilesWrite))))
+	assert.Equal(t, fiber.StatusOK, status(t, newTestApp(key, RequirePermission(domain.PermissionProfilesRead))))
+
+	user := &services.JWTClaims{Role: domain.UserRoleAdmin}
+	assert.Equal(t, fiber.StatusOK, status(t, newTestApp(user, RequirePermission(domain.PermissionProfilesWrite))))
+}
+
+func TestDenyAPIKeysOnKeyManagement(t *testing.T) {
+	// Ключ с apikeys:manage не может создать ключ с правами роли создателя
+	key := &services.JWTClaims{
+		Role:     domain.UserRoleAdmin,
+		APIKeyID: 1,
+		Scopes:   []domain.Permission{domain.PermissionAPIKeysManage},
+	}
+	handlers := []fiber.Handler{DenyAPIKeys, RequirePermission(domain.PermissionAPIKeysManage)}
+	assert.Equal(t, fiber.StatusForbidden, status(t, newTestApp(key, handlers...)))
+
+	user := &services.JWTClaims{Role: domain.UserRoleAdmin}
+	assert.Equal(t, fiber.StatusOK, status(t, newTestApp(user, handlers...)))
+
+	assert.Equal(t, fiber.StatusUnauthorized, status(t, newTestApp(nil, handlers...)))
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+)
+
+// FindAPIKeyByHash находит ключ по хешу
+func (rs *Repos) FindAPIKeyByHash(dest *domain.APIKey, hash string) error {
+	return rs.db.Where("key_hash = ?", hash).First(dest).Error
+}
+
+// FindAPIKeys находит все ключи, новые сверху
+func (rs *Repos) FindAPIKeys() ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := rs.db.Order("created_at DESC, id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// LogAPIKeyUsage записывает использование ключа в журнал и обновляет время последнего использования
+func (rs *Repos) LogAPIKeyUsage(usage *domain.APIKeyUsage) error {
+	return rs.Transaction(func(tx *Repos) error {
+		if err := tx.Create(usage); err != nil {
+			return err
+		}
+		return tx.db.Model(&domain.APIKey{}).
+			Where("id = ?", usage.APIKeyID).
+			Updates(map[string]interface{}{"last_used_at": usage.CreatedAt, "last_used_ip": usage.IP}).Error
+	})
+}
+
+// FindAPIKeyUsage возвращает журнал использования ключа с пагинацией
+func (rs *Repos) FindAPIKeyUsage(keyID uint, pagination *domain.PaginationInput) ([]domain.APIKeyUsage, int64, error) {
+	var usage []domain.APIKeyUsage
+	var total int64
+
+	query := rs.db.Model(&domain.APIKeyUsage{}).Where("api_key_id = ?", keyID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Order("created_at DESC, id DESC")
+	query = applyPagination(query, pagination)
+
+	err := query.Find(&usage).Error
+	return usage, total, err
+}
+
+// RevokeAPIKey отзывает ключ
+func (rs *Repos) RevokeAPIKey(id uint, now time.Time) error {
+	return rs.db.Model(&domain.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", now).Error
+}
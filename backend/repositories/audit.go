@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"asterisk-manager/domain"
+)
+
+// FindAuditEntries возвращает журнал аудита с фильтром и пагинацией, новые сверху
+func (rs *Repos) FindAuditEntries(filter *domain.AuditFilter, pagination *domain.PaginationInput) ([]domain.AuditEntry, int64, error) {
+	var entries []domain.AuditEntry
+	var total int64
+
+	query := rs.db.Model(&domain.AuditEntry{})
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Username != "" {
+		query = query.Where("username = ?", filter.Username)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Order("created_at DESC, id DESC")
+	query = applyPagination(query, pagination)
+
+	err := query.Find(&entries).Error
+	return entries, total, err
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"asterisk-manager/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveCDR сохраняет запись о звонке, повторы по uniqueid игнорируются
+func (rs *Repos) SaveCDR(cdr *domain.CDR) error {
+	return rs.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "unique_id"}},
+		DoNothing: true,
+	}).Create(cdr).Error
+}
+
+// FindProfileByInternalNumber находит профиль по внутреннему номеру
+func (rs *Repos) FindProfileByInternalNumber(dest *domain.Profile, number int) error {
+	return rs.db.Where("internal_number = ?", number).First(dest).Error
+}
+
+// FindProfileByCityNumber находит первый профиль с указанным городским номером.
+// Номер сравнивается только по цифрам, как в PhoneRecord.CityNumber.
+func (rs *Repos) FindProfileByCityNumber(dest *domain.Profile, cityNumber string) error {
+	return rs.db.
+		Where("regexp_replace(external_number, '[^0-9]', '', 'g') = ?", cityNumber).
+		Order("id ASC").
+		First(dest).Error
+}
+
+// applyCDRFilter добавляет условия фильтра к запросу по sipadmin.cdr
+func applyCDRFilter(query *gorm.DB, filter *domain.CDRFilter) *gorm.DB {
+	if filter == nil {
+		return query
+	}
+
+	if filter.Extension != "" {
+		query = query.Where("(extension = ? OR src = ? OR dst = ?)", filter.Extension, filter.Extension, filter.Extension)
+	}
+	if filter.Number != "" {
+		like := "%" + filter.Number + "%"
+		query = query.Where("(src LIKE ? OR dst LIKE ? OR city_number LIKE ? OR caller_id LIKE ?)", like, like, like, like)
+	}
+	if filter.From != nil {
+		query = query.Where("start >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("start < ?", *filter.To)
+	}
+	if filter.Disposition != "" {
+		query = query.Where("disposition = ?", filter.Disposition)
+	}
+	if filter.Direction != "" {
+		query = query.Where("direction = ?", filter.Direction)
+	}
+
+	return query
+}
+
+// FindCDR находит записи о звонках по фильтру с пагинацией
+func (rs *Repos) FindCDR(filter *domain.CDRFilter, pagination *domain.PaginationInput) ([]domain.CDR, int64, error) {
+	var records []domain.CDR
+	var total int64
+
+	countQuery := applyCDRFilter(rs.db.Model(&domain.CDR{}), filter)
+	if err := countQuery.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := applyCDRFilter(rs.db.Model(&domain.CDR{}), filter)
+	query = query.Order("start DESC, id DESC")
+	query = applyPagination(query, pagination)
+
+	err := query.Find(&records).Error
+	return records, total, err
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"reflect"
+	"strings"
 	"time"
 
 	"asterisk-manager/domain"
@@ -89,6 +90,23 @@ func (rs *Repos) MigrateDB() error {
 		&domain.Device{},
 		&domain.Profile{},
 		&domain.User{},
+		&domain.CDR{},
+		&domain.Recording{},
+		&domain.RetentionPolicy{},
+		&domain.RecordingDeletion{},
+		&domain.Fax{},
+		&domain.FaxRecipient{},
+		&domain.FaxDelivery{},
+		&domain.Session{},
+		&domain.APIKey{},
+		&domain.APIKeyUsage{},
+		&domain.LoginAttempt{},
+		&domain.Lockout{},
+		&domain.RecoveryCode{},
+		&domain.AuditEntry{},
+		&domain.Revision{},
+		&domain.NumberPool{},
+		&domain.NumberReservation{},
 	)
 	if err != nil {
 		return errors.WithStack(err)
@@ -100,16 +118,35 @@ func (rs *Repos) MigrateDB() error {
 		return errors.Wrap(err, "failed to create indexes")
 	}
 
+	// Создаём внешние ключи
+	err = rs.createForeignKeys()
+	if err != nil {
+		return errors.Wrap(err, "failed to create foreign keys")
+	}
+
 	return nil
 }
 
 // createIndexes создаёт дополнительные индексы
 func (rs *Repos) createIndexes() error {
 	indexes := []string{
+		// Уникальность внутреннего номера теперь только среди неудалённых профилей
+		// (idx_profiles_internal_number_active), прежний индекс по всей таблице не нужен
+		"DROP INDEX IF EXISTS sipadmin.idx_sipadmin_profiles_internal_number",
 		"CREATE INDEX IF NOT EXISTS idx_profiles_location ON sipadmin.profiles(location_id)",
 		"CREATE INDEX IF NOT EXISTS idx_profiles_device ON sipadmin.profiles(device)",
 		"CREATE INDEX IF NOT EXISTS idx_profiles_internal ON sipadmin.profiles(internal_number)",
 		"CREATE INDEX IF NOT EXISTS idx_profiles_external ON sipadmin.profiles(external_number)",
+		// Нечёткий и полнотекстовый поиск (Repos.Search)
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_profiles_name_trgm ON sipadmin.profiles USING gin (lower(name) gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_profiles_email_trgm ON sipadmin.profiles USING gin (lower(email) gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_profiles_internal_trgm ON sipadmin.profiles USING gin ((internal_number::text) gin_trgm_ops)",
+		`CREATE INDEX IF NOT EXISTS idx_profiles_external_digits_trgm ON sipadmin.profiles
+		 USING gin ((regexp_replace(external_number, '\D', '', 'g')) gin_trgm_ops)`,
+		"CREATE INDEX IF NOT EXISTS idx_profiles_fts ON sipadmin.profiles USING gin (to_tsvector('simple', name || ' ' || email))",
+		"CREATE INDEX IF NOT EXISTS idx_devices_mac_trgm ON sipadmin.devices USING gin ((replace(mac::text, ':', '')) gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_locations_name_trgm ON sipadmin.locations USING gin (lower(name) gin_trgm_ops)",
 	}
 
 	for _, idx := range indexes {
@@ -121,6 +158,45 @@ func (rs *Repos) createIndexes() error {
 	return nil
 }
 
+// createForeignKeys добавляет внешние ключи профилей на устройства и локации и ключи пулов номеров.
+// Ссылки на несуществующие записи, оставшиеся с тех пор, когда ключей не было, обнуляются:
+// такие профили и так не попадали в генерацию. Удаление устройств и локаций мягкое,
+// поэтому ключи запрещают только окончательное удаление записи, на которую ссылаются.
+func (rs *Repos) createForeignKeys() error {
+	statements := []string{
+		`UPDATE sipadmin.profiles p SET device = NULL
+		 WHERE p.device IS NOT NULL AND NOT EXISTS (SELECT 1 FROM sipadmin.devices d WHERE d.mac = p.device)`,
+		`UPDATE sipadmin.profiles p SET location_id = NULL
+		 WHERE p.location_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM sipadmin.locations l WHERE l.id = p.location_id)`,
+		addConstraint("sipadmin.profiles", "fk_profiles_device",
+			"FOREIGN KEY (device) REFERENCES sipadmin.devices(mac) ON UPDATE CASCADE ON DELETE RESTRICT"),
+		addConstraint("sipadmin.profiles", "fk_profiles_location",
+			"FOREIGN KEY (location_id) REFERENCES sipadmin.locations(id) ON DELETE RESTRICT"),
+		// Пул номеров переживает окончательное удаление своей локации, брони удаляются вместе с пулом
+		addConstraint("sipadmin.number_pools", "fk_number_pools_location",
+			"FOREIGN KEY (location_id) REFERENCES sipadmin.locations(id) ON DELETE SET NULL"),
+		addConstraint("sipadmin.number_reservations", "fk_number_reservations_pool",
+			"FOREIGN KEY (pool_id) REFERENCES sipadmin.number_pools(id) ON DELETE CASCADE"),
+	}
+
+	for _, statement := range statements {
+		if err := rs.db.Exec(statement).Error; err != nil {
+			return errors.Wrapf(err, "failed to execute: %s", statement)
+		}
+	}
+
+	return nil
+}
+
+// addConstraint возвращает SQL, добавляющий ограничение, если его ещё нет
+func addConstraint(table, name, definition string) string {
+	return fmt.Sprintf(`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '%s') THEN
+			ALTER TABLE %s ADD CONSTRAINT %s %s;
+		END IF;
+	END $$`, name, table, name, definition)
+}
+
 // Save сохраняет объект (создаёт или обновляет)
 func (rs *Repos) Save(object interface{}) error {
 	rt := reflect.TypeOf(object)
@@ -149,9 +225,16 @@ func (rs *Repos) Delete(object interface{}) error {
 	return rs.db.Delete(object).Error
 }
 
-// DeleteAll удаляет все записи из таблицы
+// Transaction выполняет fn в транзакции; репозитории внутри fn работают через неё
+func (rs *Repos) Transaction(fn func(tx *Repos) error) error {
+	return rs.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Repos{db: tx})
+	})
+}
+
+// DeleteAll удаляет все записи из таблицы, включая корзину
 func (rs *Repos) DeleteAll(model interface{}) error {
-	return rs.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(model).Error
+	return rs.db.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(model).Error
 }
 
 // Create создает новую запись в базе данных
@@ -174,39 +257,48 @@ func (rs *Repos) FindByID(dest interface{}, id interface{}) error {
 	return rs.db.First(dest, id).Error
 }
 
+// Exists проверяет, есть ли записи модели по условию
+func (rs *Repos) Exists(model interface{}, condition string, args ...interface{}) (bool, error) {
+	var count int64
+	err := rs.db.Model(model).Where(condition, args...).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
 // FindOne находит одну запись по условию
 func (rs *Repos) FindOne(dest interface{}, condition string, args ...interface{}) error {
 	return rs.db.Where(condition, args...).First(dest).Error
 }
 
-// FindProfilesWithLocations находит профили с джойном к локациям
-func (rs *Repos) FindProfilesWithLocations(isActive *bool, pagination *domain.PaginationInput) ([]domain.ProfileWithLocation, int64, error) {
+// FindProfilesWithLocations находит профили с джойном к локациям и устройствам.
+// Фильтр, поиск и сортировка (filter может быть nil) применяются и к подсчёту total.
+func (rs *Repos) FindProfilesWithLocations(filter *domain.ProfileFilter, pagination *domain.PaginationInput) ([]domain.ProfileWithLocation, int64, error) {
 	var profiles []domain.ProfileWithLocation
 	var total int64
 
-	query := rs.db.Table("sipadmin.profiles AS p").
-		Select(`
-			p.id, p.name, p.email, p.device, p.location_id, p.internal_number,
-			p.external_number, p.ring_group, p.pickup_group, p.is_active,
-			p.created_at, p.updated_at,
-			l.name AS location_name, l.server, l.subnet, l.voip_vlan, l.vlan
-		`).
-		Joins("LEFT JOIN sipadmin.locations AS l ON p.location_id = l.id")
-
-	if isActive != nil {
-		query = query.Where("p.is_active = ?", *isActive)
-	}
+	query := rs.profilesQuery(filter)
 
 	// Get total count before pagination
-	countQuery := rs.db.Table("sipadmin.profiles AS p")
-	if isActive != nil {
-		countQuery = countQuery.Where("p.is_active = ?", *isActive)
-	}
-	if err := countQuery.Count(&total).Error; err != nil {
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
+	query = query.Select(`
+		p.id, p.name, p.email, p.device, p.location_id, p.internal_number,
+		p.external_number, p.ring_group, p.pickup_group, p.is_active,
+		p.created_at, p.updated_at, d.device_model,
+		l.name AS location_name, l.server, l.subnet, l.voip_vlan, l.vlan
+	`)
+
 	// Apply sorting and pagination
+	if filter != nil {
+		if field, desc := filter.SortField(); field != "" {
+			column := domain.ProfileSortColumns[field]
+			if desc {
+				column += " DESC NULLS LAST"
+			}
+			query = query.Order(column)
+		}
+	}
 	query = query.Order("p.id ASC")
 	query = applyPagination(query, pagination)
 
@@ -214,6 +306,62 @@ func (rs *Repos) FindProfilesWithLocations(isActive *bool, pagination *domain.Pa
 	return profiles, total, err
 }
 
+// FindProfileIDs находит ID неудалённых профилей по фильтру, по возрастанию; сортировка фильтра не учитывается
+func (rs *Repos) FindProfileIDs(filter *domain.ProfileFilter) ([]uint, error) {
+	var ids []uint
+	err := rs.profilesQuery(filter).Order("p.id ASC").Pluck("p.id", &ids).Error
+	return ids, err
+}
+
+// profilesQuery запрос неудалённых профилей с джойнами к локациям и устройствам и фильтром
+func (rs *Repos) profilesQuery(filter *domain.ProfileFilter) *gorm.DB {
+	query := rs.db.Table("sipadmin.profiles AS p").
+		Joins("LEFT JOIN sipadmin.locations AS l ON p.location_id = l.id AND l.deleted_at IS NULL").
+		Joins("LEFT JOIN sipadmin.devices AS d ON p.device = d.mac AND d.deleted_at IS NULL").
+		Where("p.deleted_at IS NULL")
+	if filter != nil {
+		query = applyProfileFilter(query, filter)
+	}
+	return query
+}
+
+// applyProfileFilter добавляет к запросу профилей условия фильтра
+func applyProfileFilter(query *gorm.DB, filter *domain.ProfileFilter) *gorm.DB {
+	if filter.LocationID != nil {
+		query = query.Where("p.location_id = ?", *filter.LocationID)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("p.is_active = ?", *filter.IsActive)
+	}
+	if filter.RingGroup != nil {
+		query = query.Where("p.ring_group = ?", *filter.RingGroup)
+	}
+	if filter.PickupGroup != nil {
+		query = query.Where("p.pickup_group = ?", *filter.PickupGroup)
+	}
+	if filter.DeviceModel != "" {
+		query = query.Where("d.device_model = ?", filter.DeviceModel)
+	}
+	if filter.HasDevice != nil {
+		if *filter.HasDevice {
+			query = query.Where("p.device IS NOT NULL")
+		} else {
+			query = query.Where("p.device IS NULL")
+		}
+	}
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + likeEscaper.Replace(search) + "%"
+		query = query.Where(
+			"(p.name ILIKE ? OR p.email ILIKE ? OR p.internal_number::text LIKE ? OR p.external_number ILIKE ?)",
+			pattern, pattern, pattern, pattern,
+		)
+	}
+	return query
+}
+
+// likeEscaper экранирует спецсимволы шаблона LIKE в пользовательском вводе
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // Exec выполняет raw SQL запрос
 func (rs *Repos) Exec(sql string) error {
 	return rs.db.Exec(sql).Error
@@ -224,19 +372,40 @@ func (rs *Repos) FindUserByUsername(dest *domain.User, username string) error {
 	return rs.db.Where("username = ?", username).First(dest).Error
 }
 
+// FindUsers находит всех пользователей с сортировкой по username
+func (rs *Repos) FindUsers(dest *[]domain.User) error {
+	return rs.db.Order("username ASC").Find(dest).Error
+}
+
+// CountActiveAdmins считает активных администраторов, кроме указанного пользователя
+func (rs *Repos) CountActiveAdmins(exceptID uint) (int64, error) {
+	var count int64
+	err := rs.db.Model(&domain.User{}).
+		Where("role = ? AND is_active AND id <> ?", domain.UserRoleAdmin, exceptID).
+		Count(&count).Error
+	return count, err
+}
+
 // CreateDefaultAdmin создает пользователя admin если его нет
 func (rs *Repos) CreateDefaultAdmin() error {
 	var user domain.User
 	err := rs.FindUserByUsername(&user, "admin")
 	if err == nil {
-		// Пользователь уже существует
+		// Пользователь уже существует; если у него остался стартовый пароль,
+		// требуем сменить его
+		if user.CheckPassword("admin") && !user.MustChangePassword {
+			user.MustChangePassword = true
+			return rs.Save(&user)
+		}
 		return nil
 	}
 
-	// Создаем админа
+	// Создаем админа; стартовый пароль нужно сменить при первом входе
 	admin := domain.User{
-		Username: "admin",
-		Role:     domain.UserRoleAdmin,
+		Username:           "admin",
+		Role:               domain.UserRoleAdmin,
+		IsActive:           true,
+		MustChangePassword: true,
 	}
 	if err := admin.SetPassword("admin"); err != nil {
 		return errors.Wrap(err, "failed to set admin password")
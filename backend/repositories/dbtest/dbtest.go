@@ -0,0 +1,47 @@
+// Package dbtest подключает тесты к тестовой базе PostgreSQL из DB_TEST_URL (make test-db).
+// Без DB_TEST_URL тесты пропускаются. Каждый тест очищает таблицы схемы sipadmin,
+// поэтому пакеты с такими тестами запускаются по одному (go test -p 1).
+package dbtest
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"asterisk-manager/repositories"
+
+	"github.com/stretchr/testify/require"
+)
+
+// truncateSQL очищает все таблицы схемы sipadmin и сбрасывает последовательности
+const truncateSQL = `DO $$ DECLARE tables text; BEGIN
+	SELECT string_agg(format('%I.%I', schemaname, tablename), ', ') INTO tables
+	FROM pg_tables WHERE schemaname = 'sipadmin';
+	IF tables IS NOT NULL THEN
+		EXECUTE 'TRUNCATE ' || tables || ' RESTART IDENTITY CASCADE';
+	END IF;
+END $$`
+
+var (
+	once       sync.Once
+	repos      *repositories.Repos
+	migrateErr error
+)
+
+// Open возвращает репозитории тестовой базы с применёнными миграциями и пустыми таблицами.
+// Подключение и миграция выполняются один раз на пакет.
+func Open(t *testing.T) *repositories.Repos {
+	t.Helper()
+	url := os.Getenv("DB_TEST_URL")
+	if url == "" {
+		t.Skip("DB_TEST_URL is not set")
+	}
+
+	once.Do(func() {
+		repos = repositories.InitRepos(url)
+		migrateErr = repos.MigrateDB()
+	})
+	require.NoError(t, migrateErr)
+	require.NoError(t, repos.Exec(truncateSQL))
+	return repos
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+
+	"gorm.io/gorm"
+)
+
+// FindFaxPaths возвращает пути всех проиндексированных факсов с их ID
+func (rs *Repos) FindFaxPaths() (map[string]uint, error) {
+	var rows []domain.Fax
+	if err := rs.db.Select("id", "path").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]uint, len(rows))
+	for _, row := range rows {
+		paths[row.Path] = row.ID
+	}
+	return paths, nil
+}
+
+// DeleteFaxesByIDs удаляет записи индекса факсов по ID
+func (rs *Repos) DeleteFaxesByIDs(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return rs.db.Delete(&domain.Fax{}, ids).Error
+}
+
+// applyFaxFilter добавляет условия фильтра к запросу по sipadmin.faxes
+func applyFaxFilter(query *gorm.DB, filter *domain.FaxFilter) *gorm.DB {
+	if filter == nil {
+		return query
+	}
+
+	if filter.Search != "" {
+		like := "%" + filter.Search + "%"
+		query = query.Where("(sender LIKE ? OR city_number LIKE ? OR file_name ILIKE ?)", like, like, like)
+	}
+	if filter.CityNumber != "" {
+		query = query.Where("city_number = ?", domain.CleanPhoneNumber(filter.CityNumber))
+	}
+	if filter.RingGroup != nil {
+		query = query.Where("ring_group = ?", *filter.RingGroup)
+	}
+	if filter.LocationID != nil {
+		query = query.Where("location_id = ?", *filter.LocationID)
+	}
+	if filter.Unread {
+		query = query.Where("is_read = ?", false)
+	}
+	if filter.From != nil {
+		query = query.Where("received_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("received_at < ?", *filter.To)
+	}
+
+	return query
+}
+
+// FindFaxes находит факсы по фильтру с пагинацией
+func (rs *Repos) FindFaxes(filter *domain.FaxFilter, pagination *domain.PaginationInput) ([]domain.Fax, int64, error) {
+	var faxes []domain.Fax
+	var total int64
+
+	countQuery := applyFaxFilter(rs.db.Model(&domain.Fax{}), filter)
+	if err := countQuery.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := applyFaxFilter(rs.db.Model(&domain.Fax{}), filter)
+	query = query.Order("received_at DESC, id DESC")
+	query = applyPagination(query, pagination)
+
+	err := query.Find(&faxes).Error
+	return faxes, total, err
+}
+
+// FindFaxRecipients находит адреса рассылки факсов ринг-группы
+func (rs *Repos) FindFaxRecipients(ringGroup int) ([]domain.FaxRecipient, error) {
+	var recipients []domain.FaxRecipient
+	err := rs.db.Where("ring_group = ?", ringGroup).Order("id ASC").Find(&recipients).Error
+	return recipients, err
+}
+
+// FindRingGroupEmails возвращает email сотрудников ринг-группы
+func (rs *Repos) FindRingGroupEmails(ringGroup int) ([]string, error) {
+	var emails []string
+	err := rs.db.Model(&domain.Profile{}).
+		Where("ring_group = ? AND is_active = ? AND email <> ''", ringGroup, true).
+		Order("id ASC").
+		Pluck("email", &emails).Error
+	return emails, err
+}
+
+// FindDueFaxDeliveries находит доставки, ожидающие отправки к моменту now
+func (rs *Repos) FindDueFaxDeliveries(now time.Time) ([]domain.FaxDelivery, error) {
+	var deliveries []domain.FaxDelivery
+	err := rs.db.
+		Where("status = ? AND next_attempt_at <= ?", domain.FaxDeliveryPending, now).
+		Order("next_attempt_at ASC").
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// FindFaxDeliveries возвращает историю доставок факса
+func (rs *Repos) FindFaxDeliveries(faxID uint) ([]domain.FaxDelivery, error) {
+	var deliveries []domain.FaxDelivery
+	err := rs.db.Where("fax_id = ?", faxID).Order("id DESC").Find(&deliveries).Error
+	return deliveries, err
+}
@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"asterisk-manager/domain"
+)
+
+// FindDependentProfiles находит неудалённые профили, ссылающиеся на устройство или локацию
+func (rs *Repos) FindDependentProfiles(entityType domain.AuditEntityType, entityID string) ([]domain.Profile, error) {
+	query := rs.db.Order("internal_number ASC")
+	if entityType == domain.AuditEntityDevice {
+		query = query.Where("device = ?", entityID)
+	} else {
+		query = query.Where("location_id = ?", entityID)
+	}
+
+	profiles := []domain.Profile{}
+	err := query.Find(&profiles).Error
+	return profiles, err
+}
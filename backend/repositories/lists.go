@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"asterisk-manager/domain"
+
+	"gorm.io/gorm"
+)
+
+// FindDevices находит устройства с фильтром и пагинацией, сортировка по MAC
+func (rs *Repos) FindDevices(filter *domain.DeviceFilter, pagination *domain.PaginationInput) ([]domain.DeviceWithUsage, int64, error) {
+	var devices []domain.DeviceWithUsage
+	var total int64
+
+	// Профили, которым назначено устройство
+	assigned := "SELECT 1 FROM sipadmin.profiles p WHERE p.device = d.mac AND p.deleted_at IS NULL"
+
+	query := rs.db.Table("sipadmin.devices AS d").Where("d.deleted_at IS NULL")
+	if filter.DeviceModel != "" {
+		query = query.Where("d.device_model = ?", filter.DeviceModel)
+	}
+	if filter.Assigned != nil {
+		if *filter.Assigned {
+			query = query.Where("EXISTS (" + assigned + ")")
+		} else {
+			query = query.Where("NOT EXISTS (" + assigned + ")")
+		}
+	}
+	if filter.LocationID != nil {
+		query = query.Where("EXISTS ("+assigned+" AND p.location_id = ?)", *filter.LocationID)
+	}
+
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.
+		Select("d.*, (SELECT COUNT(*) FROM sipadmin.profiles p WHERE p.device = d.mac AND p.deleted_at IS NULL) AS profile_count").
+		Order("d.mac ASC")
+	query = applyPagination(query, pagination)
+
+	err := query.Scan(&devices).Error
+	return devices, total, err
+}
+
+// FindLocationsWithCounts находит локации с числом профилей и устройств, сортировка по ID
+func (rs *Repos) FindLocationsWithCounts(pagination *domain.PaginationInput) ([]domain.LocationWithCounts, int64, error) {
+	var locations []domain.LocationWithCounts
+	var total int64
+
+	query := rs.db.Table("sipadmin.locations AS l").Where("l.deleted_at IS NULL")
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.
+		Select(`l.*,
+			(SELECT COUNT(*) FROM sipadmin.profiles p
+			 WHERE p.location_id = l.id AND p.deleted_at IS NULL) AS profile_count,
+			(SELECT COUNT(DISTINCT p.device) FROM sipadmin.profiles p
+			 JOIN sipadmin.devices d ON d.mac = p.device AND d.deleted_at IS NULL
+			 WHERE p.location_id = l.id AND p.deleted_at IS NULL) AS device_count`).
+		Order("l.id ASC")
+	query = applyPagination(query, pagination)
+
+	err := query.Scan(&locations).Error
+	return locations, total, err
+}
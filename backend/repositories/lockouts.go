@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FindLockouts находит счётчики по ключам указанного вида
+func (rs *Repos) FindLockouts(kind domain.LockoutKind, keys ...string) ([]domain.Lockout, error) {
+	var lockouts []domain.Lockout
+	err := rs.db.Where("kind = ? AND key IN ?", kind, keys).Find(&lockouts).Error
+	return lockouts, err
+}
+
+// IncrementLockout атомарно увеличивает счётчик неудач; счётчик, не обновлявшийся
+// дольше window, начинается заново. Возвращает актуальное состояние.
+func (rs *Repos) IncrementLockout(kind domain.LockoutKind, key string, now time.Time, window time.Duration) (*domain.Lockout, error) {
+	lockout := domain.Lockout{
+		Kind:          kind,
+		Key:           key,
+		Failures:      1,
+		LastFailureAt: now,
+	}
+
+	err := rs.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "kind"}, {Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":        gorm.Expr("CASE WHEN lockouts.last_failure_at < ? THEN 1 ELSE lockouts.failures + 1 END", now.Add(-window)),
+				"last_failure_at": now,
+			}),
+		},
+		clause.Returning{},
+	).Create(&lockout).Error
+	if err != nil {
+		return nil, err
+	}
+	return &lockout, nil
+}
+
+// LockUntil блокирует вход по счётчику до указанного времени
+func (rs *Repos) LockUntil(id uint, until time.Time) error {
+	return rs.db.Model(&domain.Lockout{}).Where("id = ?", id).Update("locked_until", until).Error
+}
+
+// ResetLockout сбрасывает счётчик
+func (rs *Repos) ResetLockout(kind domain.LockoutKind, key string) error {
+	return rs.db.Where("kind = ? AND key = ?", kind, key).Delete(&domain.Lockout{}).Error
+}
+
+// FindActiveLockouts находит действующие блокировки и счётчики, обновлявшиеся после since
+func (rs *Repos) FindActiveLockouts(now, since time.Time) ([]domain.Lockout, error) {
+	var lockouts []domain.Lockout
+	err := rs.db.Where("locked_until > ? OR last_failure_at > ?", now, since).
+		Order("last_failure_at DESC").
+		Find(&lockouts).Error
+	return lockouts, err
+}
+
+// FindLoginAttempts возвращает журнал попыток входа с фильтром и пагинацией
+func (rs *Repos) FindLoginAttempts(filter *domain.LoginAttemptFilter, pagination *domain.PaginationInput) ([]domain.LoginAttempt, int64, error) {
+	var attempts []domain.LoginAttempt
+	var total int64
+
+	query := rs.db.Model(&domain.LoginAttempt{})
+	if filter.Username != "" {
+		query = query.Where("username = ?", filter.Username)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.Failed {
+		query = query.Where("NOT success")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Order("created_at DESC, id DESC")
+	query = applyPagination(query, pagination)
+
+	err := query.Find(&attempts).Error
+	return attempts, total, err
+}
+
+// DeleteLoginAttemptsBefore удаляет старые записи журнала попыток входа и устаревшие счётчики
+func (rs *Repos) DeleteLoginAttemptsBefore(before time.Time) error {
+	if err := rs.db.Where("created_at < ?", before).Delete(&domain.LoginAttempt{}).Error; err != nil {
+		return err
+	}
+	return rs.db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&domain.Lockout{}).Error
+}
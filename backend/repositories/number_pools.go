@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+
+	"gorm.io/gorm/clause"
+)
+
+// freeNumberCondition условие свободного номера n: нет неудалённого профиля с этим номером
+// и действующей брони
+const freeNumberCondition = `
+	NOT EXISTS (SELECT 1 FROM sipadmin.profiles p WHERE p.internal_number = n AND p.deleted_at IS NULL)
+	AND NOT EXISTS (SELECT 1 FROM sipadmin.number_reservations r WHERE r.number = n AND r.expires_at > now())`
+
+const (
+	nextFreeNumberSQL = `
+		SELECT n FROM generate_series(@start, @end) AS n
+		WHERE` + freeNumberCondition + `
+		ORDER BY n
+		LIMIT 1`
+
+	poolUtilisationSQL = `
+		SELECT np.*, COALESCE(l.name, '') AS location_name,
+			(SELECT COUNT(*) FROM sipadmin.profiles p
+			 WHERE p.deleted_at IS NULL AND p.internal_number BETWEEN np.range_start AND np.range_end) AS used,
+			(SELECT COUNT(*) FROM sipadmin.number_reservations r
+			 WHERE r.pool_id = np.id AND r.expires_at > now() AND NOT EXISTS (
+				SELECT 1 FROM sipadmin.profiles p WHERE p.internal_number = r.number AND p.deleted_at IS NULL
+			 )) AS reserved,
+			(SELECT MIN(n) FROM generate_series(np.range_start, np.range_end) AS n
+			 WHERE` + freeNumberCondition + `) AS next_free
+		FROM sipadmin.number_pools np
+		LEFT JOIN sipadmin.locations l ON l.id = np.location_id AND l.deleted_at IS NULL
+		ORDER BY np.range_start`
+)
+
+// FindNumberPools находит все пулы номеров по возрастанию диапазона
+func (rs *Repos) FindNumberPools() ([]domain.NumberPool, error) {
+	var pools []domain.NumberPool
+	err := rs.db.Order("range_start ASC").Find(&pools).Error
+	return pools, err
+}
+
+// LockNumberPool находит пул и блокирует его строку до конца транзакции,
+// чтобы параллельные запросы не выдали один и тот же номер
+func (rs *Repos) LockNumberPool(id uint) (*domain.NumberPool, error) {
+	var pool domain.NumberPool
+	if err := rs.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&pool, id).Error; err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// NextFreeNumber возвращает наименьший свободный номер пула; ok = false, если пул исчерпан
+func (rs *Repos) NextFreeNumber(pool *domain.NumberPool) (number int, ok bool, err error) {
+	var numbers []int
+	err = rs.db.Raw(nextFreeNumberSQL, map[string]interface{}{
+		"start": pool.RangeStart,
+		"end":   pool.RangeEnd,
+	}).Scan(&numbers).Error
+	if err != nil || len(numbers) == 0 {
+		return 0, false, err
+	}
+	return numbers[0], true, nil
+}
+
+// NumberReserved проверяет, есть ли на номер действующая бронь
+func (rs *Repos) NumberReserved(number int) (bool, error) {
+	return rs.Exists(&domain.NumberReservation{}, "number = ? AND expires_at > ?", number, time.Now())
+}
+
+// ReserveNumber сохраняет бронь; истёкшая бронь того же номера удаляется
+func (rs *Repos) ReserveNumber(reservation *domain.NumberReservation) error {
+	err := rs.db.Where("number = ? AND expires_at <= ?", reservation.Number, time.Now()).
+		Delete(&domain.NumberReservation{}).Error
+	if err != nil {
+		return err
+	}
+	return rs.db.Create(reservation).Error
+}
+
+// FindReservations находит действующие брони пула по возрастанию номера
+func (rs *Repos) FindReservations(poolID uint) ([]domain.NumberReservation, error) {
+	var reservations []domain.NumberReservation
+	err := rs.db.Where("pool_id = ? AND expires_at > ?", poolID, time.Now()).
+		Order("number ASC").
+		Find(&reservations).Error
+	return reservations, err
+}
+
+// ReleaseReservation снимает бронь номера (в том числе истёкшую); возвращает число снятых броней
+func (rs *Repos) ReleaseReservation(number int) (int64, error) {
+	result := rs.db.Where("number = ?", number).Delete(&domain.NumberReservation{})
+	return result.RowsAffected, result.Error
+}
+
+// ReleaseReservationsOutside снимает брони пула, оставшиеся вне его диапазона после изменения
+func (rs *Repos) ReleaseReservationsOutside(pool *domain.NumberPool) error {
+	return rs.db.Where("pool_id = ? AND number NOT BETWEEN ? AND ?", pool.ID, pool.RangeStart, pool.RangeEnd).
+		Delete(&domain.NumberReservation{}).Error
+}
+
+// DeleteExpiredReservations удаляет истёкшие брони
+func (rs *Repos) DeleteExpiredReservations(before time.Time) (int64, error) {
+	result := rs.db.Where("expires_at <= ?", before).Delete(&domain.NumberReservation{})
+	return result.RowsAffected, result.Error
+}
+
+// FindPoolUtilisation возвращает заполненность всех пулов
+func (rs *Repos) FindPoolUtilisation() ([]domain.PoolUtilisation, error) {
+	var report []domain.PoolUtilisation
+	if err := rs.db.Raw(poolUtilisationSQL).Scan(&report).Error; err != nil {
+		return nil, err
+	}
+	for i := range report {
+		report[i].Calculate()
+	}
+	return report, nil
+}
@@ -0,0 +1,75 @@
+package repositories_test
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+	"asterisk-manager/repositories/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentNumberAllocation(t *testing.T) {
+	repos := dbtest.Open(t)
+
+	pool := domain.NumberPool{Name: "ЗАГС", RangeStart: 6100, RangeEnd: 6104}
+	require.NoError(t, repos.Create(&pool))
+	require.NoError(t, repos.Create(&domain.Profile{Name: "Иванов", InternalNumber: 6100, IsActive: true}))
+	require.NoError(t, repos.ReserveNumber(&domain.NumberReservation{PoolID: pool.ID, Number: 6102, ExpiresAt: time.Now().Add(time.Hour)}))
+	// Истёкшая бронь номер не держит
+	require.NoError(t, repos.Create(&domain.NumberReservation{PoolID: pool.ID, Number: 6104, ExpiresAt: time.Now().Add(-time.Minute)}))
+
+	// Свободны 6101, 6103 и 6104; четвёртый запрос получает исчерпанный пул
+	const requests = 4
+	numbers := make([]int, 0, requests)
+	exhausted := 0
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			err := repos.Transaction(func(tx *repositories.Repos) error {
+				locked, err := tx.LockNumberPool(pool.ID)
+				if err != nil {
+					return err
+				}
+				number, ok, err := tx.NextFreeNumber(locked)
+				if err != nil {
+					return err
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				if !ok {
+					exhausted++
+					return nil
+				}
+				numbers = append(numbers, number)
+				return tx.Create(&domain.Profile{Name: "Сотрудник", InternalNumber: number, IsActive: true})
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	sort.Ints(numbers)
+	assert.Equal(t, []int{6101, 6103, 6104}, numbers)
+	assert.Equal(t, 1, exhausted)
+
+	report, err := repos.FindPoolUtilisation()
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.Equal(t, 4, report[0].Used)
+	assert.Equal(t, 1, report[0].Reserved)
+	assert.Equal(t, 0, report[0].Free)
+	assert.Nil(t, report[0].NextFree)
+}
@@ -0,0 +1,87 @@
+package repositories_test
+
+import (
+	"testing"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+	"asterisk-manager/repositories/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedProfiles создаёт две локации, два устройства и пять профилей, один из них удалённый
+func seedProfiles(t *testing.T, repos *repositories.Repos) (center, branch *domain.Location) {
+	t.Helper()
+	center = &domain.Location{Name: "Центр", Server: "10.0.0.1", Subnet: "10.0.0.0/24", VoipVLAN: 100, VLAN: 10}
+	branch = &domain.Location{Name: "Филиал", Server: "10.0.1.1", Subnet: "10.0.1.0/24", VoipVLAN: 101, VLAN: 11}
+	require.NoError(t, repos.Create(center))
+	require.NoError(t, repos.Create(branch))
+
+	fanvil := "80:5e:c0:18:ab:ac"
+	yealink := "80:5e:c0:18:ab:ad"
+	require.NoError(t, repos.Create(&domain.Device{MAC: fanvil, DeviceModel: domain.DeviceModelFanvil}))
+	require.NoError(t, repos.Create(&domain.Device{MAC: yealink, DeviceModel: domain.DeviceModelYealinkT27G}))
+
+	profiles := []domain.Profile{
+		{Name: "Иванов Иван", LocationID: &center.ID, InternalNumber: 6101, Device: &fanvil, IsActive: true},
+		{Name: "Иванова Анна", LocationID: &center.ID, InternalNumber: 6102, Device: &yealink, IsActive: true},
+		{Name: "Петров Пётр", LocationID: &center.ID, InternalNumber: 6103, IsActive: false},
+		{Name: "Сидоров Иван", LocationID: &branch.ID, InternalNumber: 6201, IsActive: true},
+		{Name: "Иванов Удалённый", LocationID: &center.ID, InternalNumber: 6104, IsActive: true},
+	}
+	for i := range profiles {
+		require.NoError(t, repos.Create(&profiles[i]))
+		// Create пропускает false из-за default:true, поэтому сохраняем статус отдельно
+		require.NoError(t, repos.Save(&profiles[i]))
+	}
+	require.NoError(t, repos.Delete(&profiles[4]))
+	return center, branch
+}
+
+func TestFindProfilesWithLocationsFilteredTotal(t *testing.T) {
+	repos := dbtest.Open(t)
+	center, _ := seedProfiles(t, repos)
+
+	isActive := true
+	hasDevice := false
+	tests := []struct {
+		name    string
+		filter  *domain.ProfileFilter
+		numbers []int
+	}{
+		{name: "no filter", filter: nil, numbers: []int{6101, 6102, 6103, 6201}},
+		{name: "location", filter: &domain.ProfileFilter{LocationID: &center.ID}, numbers: []int{6101, 6102, 6103}},
+		{name: "active in location", filter: &domain.ProfileFilter{LocationID: &center.ID, IsActive: &isActive}, numbers: []int{6101, 6102}},
+		{name: "device model", filter: &domain.ProfileFilter{DeviceModel: domain.DeviceModelFanvil}, numbers: []int{6101}},
+		{name: "without device", filter: &domain.ProfileFilter{HasDevice: &hasDevice}, numbers: []int{6103, 6201}},
+		{name: "search", filter: &domain.ProfileFilter{Search: "Иван"}, numbers: []int{6101, 6102, 6201}},
+		{name: "search number", filter: &domain.ProfileFilter{Search: "610", Sort: "-internalNumber"}, numbers: []int{6103, 6102, 6101}},
+		{name: "search wildcard", filter: &domain.ProfileFilter{Search: "%"}, numbers: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Страница меньше выборки: total считается по фильтру, а не по странице или всей таблице
+			page := &domain.PaginationInput{Page: 1, PerPage: 2}
+			profiles, total, err := repos.FindProfilesWithLocations(tt.filter, page)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.numbers)), total)
+
+			expected := tt.numbers
+			if len(expected) > 2 {
+				expected = expected[:2]
+			}
+			numbers := make([]int, 0, len(profiles))
+			for _, profile := range profiles {
+				numbers = append(numbers, profile.InternalNumber)
+			}
+			assert.Equal(t, expected, numbers)
+
+			ids, err := repos.FindProfileIDs(tt.filter)
+			require.NoError(t, err)
+			assert.Len(t, ids, len(tt.numbers))
+		})
+	}
+}
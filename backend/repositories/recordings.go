@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+
+	"gorm.io/gorm"
+)
+
+// recordingCDRWindow допустимое расхождение времени файла и начала звонка
+const recordingCDRWindow = 2 * time.Minute
+
+// FindRecordingPaths возвращает пути всех проиндексированных записей с их ID
+func (rs *Repos) FindRecordingPaths() (map[string]uint, error) {
+	var rows []domain.Recording
+	if err := rs.db.Select("id", "path").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]uint, len(rows))
+	for _, row := range rows {
+		paths[row.Path] = row.ID
+	}
+	return paths, nil
+}
+
+// DeleteRecordingsByIDs удаляет записи индекса по ID
+func (rs *Repos) DeleteRecordingsByIDs(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return rs.db.Delete(&domain.Recording{}, ids).Error
+}
+
+// FindCDRForRecording находит звонок, ближайший по времени к записи с теми же номерами
+func (rs *Repos) FindCDRForRecording(dest *domain.CDR, caller, callee string, at time.Time) error {
+	return rs.db.
+		Where("src = ? AND (dst = ? OR city_number = ? OR extension = ?)", caller, callee, callee, callee).
+		Where("start BETWEEN ? AND ?", at.Add(-recordingCDRWindow), at.Add(recordingCDRWindow)).
+		Order(gorm.Expr("ABS(EXTRACT(EPOCH FROM (start - ?)))", at)).
+		First(dest).Error
+}
+
+// applyRecordingFilter добавляет условия фильтра к запросу по sipadmin.recordings
+func applyRecordingFilter(query *gorm.DB, filter *domain.RecordingFilter) *gorm.DB {
+	if filter == nil {
+		return query
+	}
+
+	if filter.Search != "" {
+		like := "%" + filter.Search + "%"
+		query = query.Where("(caller LIKE ? OR callee LIKE ? OR file_name ILIKE ?)", like, like, like)
+	}
+	if filter.Extension != "" {
+		query = query.Where("(caller = ? OR callee = ?)", filter.Extension, filter.Extension)
+	}
+	if filter.LocationID != nil {
+		query = query.Where("location_id = ?", *filter.LocationID)
+	}
+	if filter.From != nil {
+		query = query.Where("started_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("started_at < ?", *filter.To)
+	}
+
+	return query
+}
+
+// FindRecordings находит записи разговоров по фильтру с пагинацией
+func (rs *Repos) FindRecordings(filter *domain.RecordingFilter, pagination *domain.PaginationInput) ([]domain.Recording, int64, error) {
+	var recordings []domain.Recording
+	var total int64
+
+	countQuery := applyRecordingFilter(rs.db.Model(&domain.Recording{}), filter)
+	if err := countQuery.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := applyRecordingFilter(rs.db.Model(&domain.Recording{}), filter)
+	query = query.Order("started_at DESC, id DESC")
+	query = applyPagination(query, pagination)
+
+	err := query.Find(&recordings).Error
+	return recordings, total, err
+}
+
+// FindRecordingsForPurge находит записи без удержания, начатые раньше before
+func (rs *Repos) FindRecordingsForPurge(before time.Time) ([]domain.Recording, error) {
+	var recordings []domain.Recording
+	err := rs.db.
+		Where("legal_hold = ? AND started_at < ?", false, before).
+		Order("started_at ASC").
+		Find(&recordings).Error
+	return recordings, err
+}
+
+// FindRecordingDeletions возвращает журнал удалений записей с пагинацией
+func (rs *Repos) FindRecordingDeletions(pagination *domain.PaginationInput) ([]domain.RecordingDeletion, int64, error) {
+	var deletions []domain.RecordingDeletion
+	var total int64
+
+	if err := rs.db.Model(&domain.RecordingDeletion{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := rs.db.Order("deleted_at DESC, id DESC")
+	query = applyPagination(query, pagination)
+
+	err := query.Find(&deletions).Error
+	return deletions, total, err
+}
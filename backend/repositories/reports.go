@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"asterisk-manager/domain"
+
+	"gorm.io/gorm"
+)
+
+const (
+	answeredCount = "COUNT(*) FILTER (WHERE c.disposition = 'ANSWERED') AS answered"
+	missedCount   = "COUNT(*) FILTER (WHERE c.disposition <> 'ANSWERED') AS missed"
+)
+
+// reportQuery создаёт запрос к sipadmin.cdr с джойном к локациям и фильтром отчёта
+func (rs *Repos) reportQuery(filter *domain.ReportFilter) *gorm.DB {
+	query := rs.db.Table("sipadmin.cdr AS c").
+		Joins("LEFT JOIN sipadmin.locations AS l ON c.location_id = l.id")
+
+	if filter == nil {
+		return query
+	}
+
+	if filter.From != nil {
+		query = query.Where("c.start >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("c.start < ?", *filter.To)
+	}
+	if filter.LocationID != nil {
+		query = query.Where("c.location_id = ?", *filter.LocationID)
+	}
+	if filter.RingGroup != nil {
+		query = query.Where("c.ring_group = ?", *filter.RingGroup)
+	}
+	if filter.Direction != "" {
+		query = query.Where("c.direction = ?", filter.Direction)
+	}
+
+	return query
+}
+
+// ReportCallsPerDay считает звонки по дням
+func (rs *Repos) ReportCallsPerDay(filter *domain.ReportFilter) ([]domain.CallsPerDay, error) {
+	var rows []domain.CallsPerDay
+	err := rs.reportQuery(filter).
+		Select("date_trunc('day', c.start) AS day, COUNT(*) AS total, " + answeredCount + ", " + missedCount).
+		Group("day").
+		Order("day ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ReportRingGroupCalls считает отвеченные и пропущенные входящие по ринг-группам
+func (rs *Repos) ReportRingGroupCalls(filter *domain.ReportFilter) ([]domain.RingGroupCalls, error) {
+	var rows []domain.RingGroupCalls
+	err := rs.reportQuery(filter).
+		Select("c.ring_group, c.location_id, l.name AS location_name, COUNT(*) AS total, "+answeredCount+", "+missedCount).
+		Where("c.direction = ?", domain.CDRDirectionInbound).
+		Group("c.ring_group, c.location_id, l.name").
+		Order("c.location_id ASC, c.ring_group ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ReportCallDurations считает среднее время до ответа и разговора
+func (rs *Repos) ReportCallDurations(filter *domain.ReportFilter) ([]domain.CallDurations, error) {
+	var rows []domain.CallDurations
+	err := rs.reportQuery(filter).
+		Select(`
+			c.ring_group, c.location_id, l.name AS location_name, COUNT(*) AS calls,
+			AVG(EXTRACT(EPOCH FROM (c.answer - c.start))) FILTER (WHERE c.answer IS NOT NULL) AS avg_ring_sec,
+			AVG(c.bill_sec) FILTER (WHERE c.disposition = 'ANSWERED') AS avg_talk_sec
+		`).
+		Group("c.ring_group, c.location_id, l.name").
+		Order("c.location_id ASC, c.ring_group ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ReportBusiestHours считает звонки по часам суток
+func (rs *Repos) ReportBusiestHours(filter *domain.ReportFilter) ([]domain.HourlyCalls, error) {
+	var rows []domain.HourlyCalls
+	err := rs.reportQuery(filter).
+		Select("EXTRACT(HOUR FROM c.start)::int AS hour, COUNT(*) AS total, " + answeredCount + ", " + missedCount).
+		Group("hour").
+		Order("total DESC, hour ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ReportTopCallers возвращает самых частых внешних абонентов для каждого городского номера
+func (rs *Repos) ReportTopCallers(filter *domain.ReportFilter) ([]domain.TopCaller, error) {
+	ranked := rs.reportQuery(filter).
+		Select(`
+			c.city_number, c.src AS caller, c.location_id, l.name AS location_name, COUNT(*) AS calls,
+			ROW_NUMBER() OVER (PARTITION BY c.city_number ORDER BY COUNT(*) DESC, c.src) AS rank
+		`).
+		Where("c.direction = ? AND c.city_number <> '' AND c.src <> ''", domain.CDRDirectionInbound).
+		Group("c.city_number, c.src, c.location_id, l.name")
+
+	var rows []domain.TopCaller
+	err := rs.db.Table("(?) AS ranked", ranked).
+		Select("city_number, caller, location_id, location_name, calls").
+		Where("rank <= ?", filter.TopLimit()).
+		Order("city_number ASC, calls DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ReportUnansweredDIDs считает неотвеченные входящие по городским номерам
+func (rs *Repos) ReportUnansweredDIDs(filter *domain.ReportFilter) ([]domain.UnansweredDID, error) {
+	var rows []domain.UnansweredDID
+	err := rs.reportQuery(filter).
+		Select("c.city_number, c.ring_group, c.location_id, l.name AS location_name, COUNT(*) AS unanswered, MAX(c.start) AS last_call").
+		Where("c.direction = ? AND c.city_number <> '' AND c.disposition <> 'ANSWERED'", domain.CDRDirectionInbound).
+		Group("c.city_number, c.ring_group, c.location_id, l.name").
+		Order("unanswered DESC").
+		Scan(&rows).Error
+	return rows, err
+}
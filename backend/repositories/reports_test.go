@@ -0,0 +1,97 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+	"asterisk-manager/repositories/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createCDR сохраняет отвеченный звонок абонента src на городской номер city
+func createCDR(t *testing.T, repos *repositories.Repos, start time.Time, src, city string, direction domain.CDRDirection) {
+	t.Helper()
+	cdr := domain.CDR{
+		UniqueID:    fmt.Sprintf("%d.%s.%s", start.UnixNano(), src, city),
+		Src:         src,
+		Start:       start,
+		End:         start.Add(time.Minute),
+		Disposition: domain.CDRDispositionAnswered,
+		Direction:   direction,
+		CityNumber:  city,
+	}
+	require.NoError(t, repos.Create(&cdr))
+}
+
+func TestReportCallsPerDayDateRange(t *testing.T) {
+	repos := dbtest.Open(t)
+
+	day := time.Date(2025, 1, 11, 12, 0, 0, 0, time.UTC)
+	createCDR(t, repos, day.AddDate(0, 0, -1), "100", "3430000", domain.CDRDirectionInbound)
+	createCDR(t, repos, day, "101", "3430000", domain.CDRDirectionInbound)
+	createCDR(t, repos, day.Add(time.Hour), "102", "3430000", domain.CDRDirectionInbound)
+	createCDR(t, repos, day.AddDate(0, 0, 1), "103", "3430000", domain.CDRDirectionInbound)
+
+	// To не включается: звонок 12 января в полдень не попадает
+	from := time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 12, 0, 0, 0, 0, time.UTC)
+	rows, err := repos.ReportCallsPerDay(&domain.ReportFilter{From: &from, To: &to})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, int64(2), rows[0].Total)
+	assert.Equal(t, int64(2), rows[0].Answered)
+
+	rows, err = repos.ReportCallsPerDay(nil)
+	require.NoError(t, err)
+	assert.Len(t, rows, 3)
+}
+
+func TestReportTopCallersRankingAndLimit(t *testing.T) {
+	repos := dbtest.Open(t)
+
+	start := time.Date(2025, 1, 11, 9, 0, 0, 0, time.UTC)
+	calls := []struct {
+		src   string
+		city  string
+		count int
+	}{
+		{"89120000001", "3430000", 3},
+		{"89120000002", "3430000", 2},
+		{"89120000003", "3430000", 1},
+		{"89120000004", "3431111", 1},
+	}
+	for _, c := range calls {
+		for i := 0; i < c.count; i++ {
+			start = start.Add(time.Minute)
+			createCDR(t, repos, start, c.src, c.city, domain.CDRDirectionInbound)
+		}
+	}
+	// Исходящие звонки не учитываются
+	for i := 0; i < 5; i++ {
+		start = start.Add(time.Minute)
+		createCDR(t, repos, start, "89120000003", "3430000", domain.CDRDirectionOutbound)
+	}
+
+	rows, err := repos.ReportTopCallers(&domain.ReportFilter{Limit: 2})
+	require.NoError(t, err)
+
+	type caller struct {
+		City   string
+		Caller string
+		Calls  int64
+	}
+	got := make([]caller, 0, len(rows))
+	for _, row := range rows {
+		got = append(got, caller{row.CityNumber, row.Caller, row.Calls})
+	}
+	assert.Equal(t, []caller{
+		{"3430000", "89120000001", 3},
+		{"3430000", "89120000002", 2},
+		{"3431111", "89120000004", 1},
+	}, got)
+}
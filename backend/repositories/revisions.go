@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/pkg/errors"
+)
+
+// CreateRevision записывает следующую ревизию сущности; номер назначается здесь.
+// Вызывается в транзакции изменения, уникальный индекс защищает от гонки номеров.
+func (rs *Repos) CreateRevision(revision *domain.Revision) error {
+	var last int
+	err := rs.db.Model(&domain.Revision{}).
+		Where("entity_type = ? AND entity_id = ?", revision.EntityType, revision.EntityID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&last).Error
+	if err != nil {
+		return err
+	}
+
+	revision.Revision = last + 1
+	return rs.db.Create(revision).Error
+}
+
+// FindRevisions возвращает историю сущности, новые ревизии сверху
+func (rs *Repos) FindRevisions(entityType domain.AuditEntityType, entityID string) ([]domain.Revision, error) {
+	var revisions []domain.Revision
+	err := rs.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("revision DESC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// FindRevision находит ревизию сущности по номеру
+func (rs *Repos) FindRevision(dest *domain.Revision, entityType domain.AuditEntityType, entityID string, revision int) error {
+	return rs.db.Where("entity_type = ? AND entity_id = ? AND revision = ?", entityType, entityID, revision).
+		First(dest).Error
+}
+
+// FindEntity находит текущее состояние сущности по идентификатору из ревизии (ID или MAC),
+// в том числе в корзине
+func (rs *Repos) FindEntity(dest domain.Auditable, entityID string) error {
+	entityType, _ := dest.AuditEntity()
+	if entityType == domain.AuditEntityDevice {
+		return rs.db.Unscoped().Where("mac = ?", entityID).First(dest).Error
+	}
+	return rs.db.Unscoped().Where("id = ?", entityID).First(dest).Error
+}
+
+// FindSnapshot собирает состояние профилей, устройств и локаций на момент at
+// по последним ревизиям каждой сущности, сделанным до at
+func (rs *Repos) FindSnapshot(at time.Time) (*domain.Snapshot, error) {
+	var revisions []domain.Revision
+	err := rs.db.Raw(`
+		SELECT DISTINCT ON (entity_type, entity_id) *
+		FROM sipadmin.revisions
+		WHERE created_at < ?
+		ORDER BY entity_type, entity_id, revision DESC
+	`, at).Scan(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &domain.Snapshot{
+		At:        at,
+		Profiles:  []domain.Profile{},
+		Devices:   []domain.Device{},
+		Locations: []domain.Location{},
+	}
+	for i := range revisions {
+		revision := &revisions[i]
+		if revision.IsDeleted() {
+			continue
+		}
+
+		entity, err := domain.NewAuditable(revision.EntityType)
+		if err != nil {
+			return nil, err
+		}
+		if err := revision.Decode(entity); err != nil {
+			return nil, errors.Wrapf(err, "%s %s revision %d", revision.EntityType, revision.EntityID, revision.Revision)
+		}
+
+		switch entity := entity.(type) {
+		case *domain.Profile:
+			snapshot.Profiles = append(snapshot.Profiles, *entity)
+		case *domain.Device:
+			snapshot.Devices = append(snapshot.Devices, *entity)
+		case *domain.Location:
+			snapshot.Locations = append(snapshot.Locations, *entity)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// BaselineRevisions создаёт первую ревизию для сущностей, у которых истории ещё нет
+// (созданных до её появления или напрямую в БД, например seed). Время ревизии -
+// последнее изменение сущности. Возвращает число созданных ревизий.
+func (rs *Repos) BaselineRevisions() (int, error) {
+	var profiles []domain.Profile
+	var devices []domain.Device
+	var locations []domain.Location
+	if err := rs.FindAll(&profiles); err != nil {
+		return 0, err
+	}
+	if err := rs.FindAllDevices(&devices); err != nil {
+		return 0, err
+	}
+	if err := rs.FindAll(&locations); err != nil {
+		return 0, err
+	}
+
+	type baseline struct {
+		entity    domain.Auditable
+		updatedAt time.Time
+	}
+	entities := make([]baseline, 0, len(profiles)+len(devices)+len(locations))
+	for i := range profiles {
+		entities = append(entities, baseline{&profiles[i], profiles[i].UpdatedAt})
+	}
+	for i := range devices {
+		entities = append(entities, baseline{&devices[i], devices[i].UpdatedAt})
+	}
+	for i := range locations {
+		entities = append(entities, baseline{&locations[i], locations[i].UpdatedAt})
+	}
+
+	var existing []struct {
+		EntityType domain.AuditEntityType
+		EntityID   string
+	}
+	if err := rs.db.Model(&domain.Revision{}).Distinct("entity_type", "entity_id").Scan(&existing).Error; err != nil {
+		return 0, err
+	}
+	tracked := make(map[domain.AuditEntityType]map[string]bool)
+	for _, e := range existing {
+		if tracked[e.EntityType] == nil {
+			tracked[e.EntityType] = make(map[string]bool)
+		}
+		tracked[e.EntityType][e.EntityID] = true
+	}
+
+	created := 0
+	for _, e := range entities {
+		entityType, entityID := e.entity.AuditEntity()
+		if tracked[entityType][entityID] {
+			continue
+		}
+
+		data, err := domain.RevisionData(e.entity)
+		if err != nil {
+			return created, err
+		}
+		revision := domain.Revision{
+			EntityType: entityType,
+			EntityID:   entityID,
+			Revision:   1,
+			Action:     domain.AuditActionCreate,
+			Data:       data,
+			Username:   "baseline",
+			CreatedAt:  e.updatedAt,
+		}
+		if err := rs.db.Create(&revision).Error; err != nil {
+			return created, err
+		}
+		created++
+	}
+
+	return created, nil
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"sort"
+
+	"asterisk-manager/domain"
+)
+
+// Запросы поиска используют те же выражения, что и индексы pg_trgm и полнотекстовый индекс
+// из createIndexes, иначе индексы не применяются.
+const (
+	searchProfilesSQL = `
+		SELECT 'profile' AS type, p.id::text AS id, p.name AS title,
+			concat_ws(' · ', p.internal_number::text, NULLIF(p.external_number, ''), NULLIF(p.email, ''), l.name) AS subtitle,
+			GREATEST(
+				word_similarity(@text, lower(p.name)),
+				similarity(@text, lower(p.email)),
+				ts_rank(to_tsvector('simple', p.name || ' ' || p.email), plainto_tsquery('simple', @text)),
+				CASE
+					WHEN @digits = '' THEN 0
+					WHEN p.internal_number::text = @digits
+						OR regexp_replace(p.external_number, '\D', '', 'g') = @digits THEN 1
+					WHEN p.internal_number::text LIKE @digits_like
+						OR regexp_replace(p.external_number, '\D', '', 'g') LIKE @digits_like THEN 0.8
+					ELSE 0
+				END
+			)::float8 AS score
+		FROM sipadmin.profiles p
+		LEFT JOIN sipadmin.locations l ON l.id = p.location_id AND l.deleted_at IS NULL
+		WHERE p.deleted_at IS NULL AND (
+			@text <% lower(p.name)
+			OR lower(p.name) LIKE @like
+			OR lower(p.email) LIKE @like
+			OR to_tsvector('simple', p.name || ' ' || p.email) @@ plainto_tsquery('simple', @text)
+			OR (@digits <> '' AND (
+				p.internal_number::text LIKE @digits_like
+				OR regexp_replace(p.external_number, '\D', '', 'g') LIKE @digits_like
+			))
+		)
+		ORDER BY score DESC, p.id
+		LIMIT @limit`
+
+	searchDevicesSQL = `
+		SELECT 'device' AS type, d.mac::text AS id, d.mac::text AS title,
+			concat_ws(' · ', d.device_model, string_agg(p.name, ', ' ORDER BY p.name)) AS subtitle,
+			CASE
+				WHEN replace(d.mac::text, ':', '') = @mac THEN 1
+				WHEN @mac <> '' AND replace(d.mac::text, ':', '') LIKE @mac_like THEN 0.3 + 0.6 * length(CAST(@mac AS text)) / 12.0
+				ELSE 0.5
+			END::float8 AS score
+		FROM sipadmin.devices d
+		LEFT JOIN sipadmin.profiles p ON p.device = d.mac AND p.deleted_at IS NULL
+		WHERE d.deleted_at IS NULL AND (
+			(@mac <> '' AND replace(d.mac::text, ':', '') LIKE @mac_like)
+			OR lower(d.device_model) LIKE @like
+		)
+		GROUP BY d.mac
+		ORDER BY score DESC, d.mac
+		LIMIT @limit`
+
+	searchLocationsSQL = `
+		SELECT 'location' AS type, l.id::text AS id, l.name AS title,
+			concat_ws(' · ', host(l.server), l.subnet::text) AS subtitle,
+			GREATEST(
+				word_similarity(@text, lower(l.name)),
+				CASE WHEN host(l.server) LIKE @prefix OR l.subnet::text LIKE @prefix THEN 0.9 ELSE 0 END
+			)::float8 AS score
+		FROM sipadmin.locations l
+		WHERE l.deleted_at IS NULL AND (
+			@text <% lower(l.name)
+			OR lower(l.name) LIKE @like
+			OR host(l.server) LIKE @prefix
+			OR l.subnet::text LIKE @prefix
+		)
+		ORDER BY score DESC, l.id
+		LIMIT @limit`
+
+	searchRingGroupsSQL = `
+		SELECT 'ringGroup' AS type, p.ring_group::text AS id, 'Ring group ' || p.ring_group AS title,
+			COUNT(*) || ' profile(s)' AS subtitle,
+			CASE WHEN p.ring_group::text = @digits THEN 1 ELSE 0.6 END::float8 AS score
+		FROM sipadmin.profiles p
+		WHERE p.deleted_at IS NULL AND p.ring_group IS NOT NULL AND p.ring_group::text LIKE @digits_prefix
+		GROUP BY p.ring_group
+		ORDER BY score DESC, p.ring_group
+		LIMIT @limit`
+)
+
+// Search ищет профили, устройства, локации и группы вызова и возвращает не более query.Limit
+// результатов, самые релевантные сверху
+func (rs *Repos) Search(query *domain.SearchQuery) ([]domain.SearchHit, error) {
+	like := "%" + likeEscaper.Replace(query.Text) + "%"
+	args := map[string]interface{}{
+		"text":          query.Text,
+		"like":          like,
+		"prefix":        likeEscaper.Replace(query.Text) + "%",
+		"digits":        query.Digits,
+		"digits_like":   "%" + query.Digits + "%",
+		"digits_prefix": query.Digits + "%",
+		"mac":           query.MACHex,
+		"mac_like":      "%" + query.MACHex + "%",
+		"limit":         query.Limit,
+	}
+
+	searches := []struct {
+		hitType domain.SearchHitType
+		sql     string
+		enabled bool
+	}{
+		{domain.SearchHitProfile, searchProfilesSQL, true},
+		{domain.SearchHitDevice, searchDevicesSQL, true},
+		{domain.SearchHitLocation, searchLocationsSQL, true},
+		// Группы ищутся только по номеру
+		{domain.SearchHitRingGroup, searchRingGroupsSQL, query.NumberOnly},
+	}
+
+	hits := []domain.SearchHit{}
+	for _, search := range searches {
+		if !search.enabled || !query.Has(search.hitType) {
+			continue
+		}
+		var found []domain.SearchHit
+		if err := rs.db.Raw(search.sql, args).Scan(&found).Error; err != nil {
+			return nil, err
+		}
+		hits = append(hits, found...)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
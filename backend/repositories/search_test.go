@@ -0,0 +1,52 @@
+package repositories_test
+
+import (
+	"strconv"
+	"testing"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	repos := dbtest.Open(t)
+	center, _ := seedProfiles(t, repos)
+	all := []domain.SearchHitType{domain.SearchHitProfile, domain.SearchHitDevice, domain.SearchHitLocation, domain.SearchHitRingGroup}
+
+	search := func(q string, limit int) []domain.SearchHit {
+		t.Helper()
+		query, err := domain.NewSearchQuery(q, limit, all)
+		require.NoError(t, err)
+		hits, err := repos.Search(query)
+		require.NoError(t, err)
+		return hits
+	}
+
+	// Точное совпадение внутреннего номера - единственный результат с наибольшей релевантностью
+	hits := search("6101", 0)
+	require.Len(t, hits, 1)
+	assert.Equal(t, domain.SearchHitProfile, hits[0].Type)
+	assert.Equal(t, "Иванов Иван", hits[0].Title)
+	assert.Equal(t, 1.0, hits[0].Score)
+
+	// MAC в любом формате находит устройство
+	hits = search("80-5E-C0-18-AB-AC", 0)
+	require.NotEmpty(t, hits)
+	assert.Equal(t, domain.SearchHit{
+		Type: domain.SearchHitDevice, ID: "80:5e:c0:18:ab:ac", Title: "80:5e:c0:18:ab:ac",
+		Subtitle: "Fanvil · Иванов Иван", Score: 1,
+	}, hits[0])
+
+	// Начало адреса сервера находит локацию
+	hits = search("10.0.0", 0)
+	require.NotEmpty(t, hits)
+	assert.Equal(t, domain.SearchHitLocation, hits[0].Type)
+	assert.Equal(t, strconv.FormatUint(uint64(center.ID), 10), hits[0].ID)
+
+	// Результатов не больше limit, удалённые профили не ищутся
+	assert.Len(t, search("610", 2), 2)
+	assert.Empty(t, search("6104", 0))
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+)
+
+// FindSessionByTokenHash находит сессию по текущему или предыдущему хешу refresh-токена
+func (rs *Repos) FindSessionByTokenHash(dest *domain.Session, hash string) error {
+	return rs.db.Where("token_hash = ? OR previous_token_hash = ?", hash, hash).First(dest).Error
+}
+
+// RotateSessionToken заменяет refresh-токен сессии, только если он не успел смениться
+// параллельным запросом; возвращает false, если замена не удалась
+func (rs *Repos) RotateSessionToken(session *domain.Session, oldHash, newHash string) (bool, error) {
+	result := rs.db.Model(&domain.Session{}).
+		Where("id = ? AND token_hash = ? AND revoked_at IS NULL", session.ID, oldHash).
+		Updates(map[string]interface{}{
+			"token_hash":          newHash,
+			"previous_token_hash": oldHash,
+			"ip":                  session.IP,
+			"user_agent":          session.UserAgent,
+			"last_used_at":        session.LastUsedAt,
+			"expires_at":          session.ExpiresAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// IsSessionActive проверяет, что сессия пользователя не отозвана и не истекла
+func (rs *Repos) IsSessionActive(sessionID, userID uint, now time.Time) (bool, error) {
+	var count int64
+	err := rs.db.Model(&domain.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, now).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindActiveSessions находит действующие сессии пользователя, последние сверху
+func (rs *Repos) FindActiveSessions(userID uint, now time.Time) ([]domain.Session, error) {
+	var sessions []domain.Session
+	err := rs.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSessions отзывает действующие сессии пользователя, кроме exceptID (0 - все)
+func (rs *Repos) RevokeSessions(userID, exceptID uint, reason string, now time.Time) error {
+	return rs.db.Model(&domain.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, exceptID).
+		Updates(map[string]interface{}{"revoked_at": now, "revoke_reason": reason}).Error
+}
+
+// RevokeSession отзывает одну сессию
+func (rs *Repos) RevokeSession(sessionID uint, reason string, now time.Time) error {
+	return rs.db.Model(&domain.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"revoked_at": now, "revoke_reason": reason}).Error
+}
+
+// DeleteExpiredSessions удаляет сессии, срок которых истёк
+func (rs *Repos) DeleteExpiredSessions(now time.Time) (int64, error) {
+	result := rs.db.Where("expires_at < ?", now).Delete(&domain.Session{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+)
+
+// ReplaceRecoveryCodes заменяет коды восстановления пользователя новыми
+func (rs *Repos) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return rs.Transaction(func(tx *Repos) error {
+		if err := tx.DeleteRecoveryCodes(userID); err != nil {
+			return err
+		}
+		codes := make([]domain.RecoveryCode, 0, len(hashes))
+		for _, hash := range hashes {
+			codes = append(codes, domain.RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return tx.db.Create(&codes).Error
+	})
+}
+
+// DeleteRecoveryCodes удаляет все коды восстановления пользователя
+func (rs *Repos) DeleteRecoveryCodes(userID uint) error {
+	return rs.db.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error
+}
+
+// UseRecoveryCode помечает неиспользованный код использованным.
+// Возвращает false, если такого кода нет или он уже использован.
+func (rs *Repos) UseRecoveryCode(userID uint, hash string, now time.Time) (bool, error) {
+	result := rs.db.Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes считает оставшиеся коды восстановления пользователя
+func (rs *Repos) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := rs.db.Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// AdvanceTOTPStep запоминает шаг принятого кода TOTP. Обновление условное, поэтому
+// из двух параллельных запросов с одним кодом пройдёт только один.
+func (rs *Repos) AdvanceTOTPStep(userID uint, step int64) (bool, error) {
+	result := rs.db.Model(&domain.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
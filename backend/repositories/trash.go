@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+)
+
+// FindDeleted находит записи в корзине, недавно удалённые сверху
+func (rs *Repos) FindDeleted(dest interface{}) error {
+	return rs.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(dest).Error
+}
+
+// FindDeletedEntity находит сущность в корзине по идентификатору (ID или MAC)
+func (rs *Repos) FindDeletedEntity(dest domain.Auditable, entityID string) error {
+	query := rs.db.Unscoped().Where("deleted_at IS NOT NULL")
+	if entityType, _ := dest.AuditEntity(); entityType == domain.AuditEntityDevice {
+		return query.Where("mac = ?", entityID).First(dest).Error
+	}
+	return query.Where("id = ?", entityID).First(dest).Error
+}
+
+// SaveUnscoped сохраняет объект, в том числе находящийся в корзине (для восстановления)
+func (rs *Repos) SaveUnscoped(object interface{}) error {
+	return rs.db.Unscoped().Save(object).Error
+}
+
+// Purge окончательно удаляет объект
+func (rs *Repos) Purge(object interface{}) error {
+	return rs.db.Unscoped().Delete(object).Error
+}
+
+// FindPurgeable находит записи dest (*[]Profile, *[]Device или *[]Location), попавшие в корзину
+// раньше before. Устройства и локации, на которые ещё ссылаются профили (в том числе из корзины),
+// не возвращаются: они остаются до удаления этих профилей.
+func (rs *Repos) FindPurgeable(dest interface{}, before time.Time) error {
+	query := rs.db.Unscoped().Where("deleted_at < ?", before)
+	switch dest.(type) {
+	case *[]domain.Device:
+		query = query.Where("NOT EXISTS (SELECT 1 FROM sipadmin.profiles p WHERE p.device = sipadmin.devices.mac)")
+	case *[]domain.Location:
+		query = query.Where("NOT EXISTS (SELECT 1 FROM sipadmin.profiles p WHERE p.location_id = sipadmin.locations.id)")
+	}
+	return query.Order("deleted_at ASC").Find(dest).Error
+}
+
+// InternalNumberTaken проверяет, занят ли внутренний номер другим неудалённым профилем
+func (rs *Repos) InternalNumberTaken(number int, exceptID uint) (bool, error) {
+	var count int64
+	err := rs.db.Model(&domain.Profile{}).
+		Where("internal_number = ? AND id <> ?", number, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -2,14 +2,18 @@ package main
 
 import (
 	"os"
+	"strconv"
+	"time"
 
+	"asterisk-manager/domain"
 	"asterisk-manager/handlers"
 	"asterisk-manager/middleware"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
-func initRoutes(app *fiber.App, h *handlers.Handler, authHandler *handlers.AuthHandler) {
+func initRoutes(app *fiber.App, h *handlers.Handler, authHandler *handlers.AuthHandler, recordingsHandler *handlers.RecordingsHandler, faxesHandler *handlers.FaxesHandler, numberPoolsHandler *handlers.NumberPoolsHandler) {
 	// Health check
 	app.Get("/", func(c *fiber.Ctx) error {
 		version := os.Getenv("APP_VERSION")
@@ -24,39 +28,194 @@ func initRoutes(app *fiber.App, h *handlers.Handler, authHandler *handlers.AuthH
 
 	// Auth endpoints (без авторизации)
 	auth := api.Group("/auth")
-	auth.Post("/login", authHandler.Login)
+	auth.Post("/login", loginRateLimit(), authHandler.Login)
+	auth.Post("/login/mfa", loginRateLimit(), authHandler.LoginMFA)
+	auth.Post("/refresh", loginRateLimit(), authHandler.Refresh)
+	auth.Get("/providers", authHandler.GetAuthProviders)
+	auth.Get("/oidc/login", authHandler.OIDCLogin)
+	auth.Get("/oidc/callback", authHandler.OIDCCallback)
+	auth.Post("/oidc/token", authHandler.OIDCToken)
+
+	// Уведомление от Asterisk о принятом факсе (проверяется X-Fax-Token)
+	api.Post("/faxes/incoming", faxesHandler.IncomingFax)
 
 	// Защищенные эндпоинты
 	protected := api.Group("/", middleware.JWTAuth(authHandler.GetAuthService()))
 
+	// Проверка прав по ролям (см. domain/permission.go)
+	can := middleware.RequirePermission
+
 	// Auth me endpoint (с авторизацией)
 	protected.Get("auth/me", authHandler.Me)
+	protected.Post("auth/password", middleware.DenyAPIKeys, authHandler.ChangePassword)
+	protected.Post("auth/logout", middleware.DenyAPIKeys, authHandler.Logout)
+	protected.Get("auth/sessions", middleware.DenyAPIKeys, authHandler.GetSessions)
+	protected.Delete("auth/sessions/:id", middleware.DenyAPIKeys, authHandler.DeleteSession)
+
+	// Остальные эндпоинты недоступны, пока пользователь не сменит обязательный пароль
+	protected.Use(middleware.RequirePasswordChanged)
+
+	// Второй фактор (TOTP); до его подключения остальные эндпоинты закрыты для ролей из MFA_REQUIRED_ROLES
+	protected.Post("auth/totp/setup", middleware.DenyAPIKeys, authHandler.SetupTOTP)
+	protected.Post("auth/totp/enable", middleware.DenyAPIKeys, authHandler.EnableTOTP)
+	protected.Post("auth/totp/disable", middleware.DenyAPIKeys, authHandler.DisableTOTP)
+	protected.Post("auth/totp/recovery-codes", middleware.DenyAPIKeys, authHandler.RegenerateRecoveryCodes)
+	protected.Use(middleware.RequireMFAEnrolled)
+
+	// Users endpoints
+	users := protected.Group("users", can(domain.PermissionUsersManage))
+	users.Get("/", authHandler.GetUsers)
+	users.Get("/:id", authHandler.GetUser)
+	users.Post("/", authHandler.CreateUser)
+	users.Put("/:id", authHandler.UpdateUser)
+	users.Put("/:id/password", authHandler.ResetUserPassword)
+	users.Delete("/:id", authHandler.DeleteUser)
+	users.Get("/:id/sessions", authHandler.GetUserSessions)
+	users.Delete("/:id/sessions", authHandler.RevokeUserSessions)
+	users.Delete("/:id/totp", authHandler.ResetUserTOTP)
+
+	// Login protection endpoints
+	security := protected.Group("security", can(domain.PermissionUsersManage))
+	security.Get("/lockouts", authHandler.GetLockouts)
+	security.Delete("/lockouts/:id", authHandler.DeleteLockout)
+	security.Get("/login-attempts", h.Pagination, authHandler.GetLoginAttempts)
+
+	// API keys endpoints; ключами управляет только человек, иначе ключ выдал бы себе права роли создателя
+	apiKeys := protected.Group("api-keys", middleware.DenyAPIKeys, can(domain.PermissionAPIKeysManage))
+	apiKeys.Get("/", authHandler.GetAPIKeys)
+	apiKeys.Post("/", authHandler.CreateAPIKey)
+	apiKeys.Delete("/:id", authHandler.RevokeAPIKey)
+	apiKeys.Get("/:id/usage", h.Pagination, authHandler.GetAPIKeyUsage)
+
+	// Audit log endpoints
+	audit := protected.Group("audit", can(domain.PermissionAuditRead))
+	audit.Get("/", h.Pagination, h.GetAudit)
+	audit.Get("/snapshot", h.GetSnapshot)
+
+	// Search endpoint (ищет только сущности, на чтение которых есть права)
+	protected.Get("/search", h.Search)
 
 	// Profiles endpoints
 	profiles := protected.Group("profiles")
-	profiles.Get("/", h.Pagination, h.GetProfiles)
-	profiles.Get("/:id", h.GetProfile)
-	profiles.Post("/", h.CreateProfile)
-	profiles.Put("/:id", h.UpdateProfile)
-	profiles.Delete("/:id", h.DeleteProfile)
+	profiles.Get("/", can(domain.PermissionProfilesRead), h.Pagination, h.GetProfiles)
+	profiles.Get("/trash", can(domain.PermissionProfilesRead), h.GetTrash(domain.AuditEntityProfile))
+	profiles.Get("/:id", can(domain.PermissionProfilesRead), h.GetProfile)
+	profiles.Post("/", can(domain.PermissionProfilesWrite), h.CreateProfile)
+	profiles.Post("/bulk", can(domain.PermissionProfilesWrite), h.BulkProfiles)
+	profiles.Put("/:id", can(domain.PermissionProfilesWrite), h.UpdateProfile)
+	profiles.Delete("/:id", can(domain.PermissionProfilesWrite), h.DeleteProfile)
+	profiles.Post("/:id/restore", can(domain.PermissionProfilesWrite), h.RestoreDeleted(domain.AuditEntityProfile, "id"))
+	profiles.Delete("/:id/purge", can(domain.PermissionProfilesWrite), h.PurgeDeleted(domain.AuditEntityProfile, "id"))
+	profiles.Get("/:id/revisions", can(domain.PermissionProfilesRead), h.GetRevisions(domain.AuditEntityProfile, "id"))
+	profiles.Get("/:id/revisions/:rev", can(domain.PermissionProfilesRead), h.GetRevision(domain.AuditEntityProfile, "id"))
+	profiles.Post("/:id/revisions/:rev/restore", can(domain.PermissionProfilesWrite), h.RestoreRevision(domain.AuditEntityProfile, "id"))
 
 	// Devices endpoints
 	devices := protected.Group("devices")
-	devices.Get("/", h.GetDevices)
-	devices.Get("/:mac", h.GetDevice)
-	devices.Post("/", h.CreateDevice)
-	devices.Put("/:mac", h.UpdateDevice)
-	devices.Delete("/:mac", h.DeleteDevice)
+	devices.Get("/", can(domain.PermissionDevicesRead), h.Pagination, h.GetDevices)
+	devices.Get("/trash", can(domain.PermissionDevicesRead), h.GetTrash(domain.AuditEntityDevice))
+	devices.Get("/:mac", can(domain.PermissionDevicesRead), h.GetDevice)
+	devices.Post("/", can(domain.PermissionDevicesWrite), h.CreateDevice)
+	devices.Put("/:mac", can(domain.PermissionDevicesWrite), h.UpdateDevice)
+	devices.Delete("/:mac", can(domain.PermissionDevicesWrite), h.DeleteDevice)
+	devices.Post("/:mac/restore", can(domain.PermissionDevicesWrite), h.RestoreDeleted(domain.AuditEntityDevice, "mac"))
+	devices.Delete("/:mac/purge", can(domain.PermissionDevicesWrite), h.PurgeDeleted(domain.AuditEntityDevice, "mac"))
+	devices.Get("/:mac/revisions", can(domain.PermissionDevicesRead), h.GetRevisions(domain.AuditEntityDevice, "mac"))
+	devices.Get("/:mac/revisions/:rev", can(domain.PermissionDevicesRead), h.GetRevision(domain.AuditEntityDevice, "mac"))
+	devices.Post("/:mac/revisions/:rev/restore", can(domain.PermissionDevicesWrite), h.RestoreRevision(domain.AuditEntityDevice, "mac"))
 
 	// Locations endpoints
 	locations := protected.Group("locations")
-	locations.Get("/", h.GetLocations)
-	locations.Get("/:id", h.GetLocation)
-	locations.Post("/", h.CreateLocation)
-	locations.Put("/:id", h.UpdateLocation)
-	locations.Delete("/:id", h.DeleteLocation)
+	locations.Get("/", can(domain.PermissionLocationsRead), h.Pagination, h.GetLocations)
+	locations.Get("/trash", can(domain.PermissionLocationsRead), h.GetTrash(domain.AuditEntityLocation))
+	locations.Get("/:id", can(domain.PermissionLocationsRead), h.GetLocation)
+	locations.Post("/", can(domain.PermissionLocationsWrite), h.CreateLocation)
+	locations.Put("/:id", can(domain.PermissionLocationsWrite), h.UpdateLocation)
+	locations.Delete("/:id", can(domain.PermissionLocationsWrite), h.DeleteLocation)
+	locations.Post("/:id/restore", can(domain.PermissionLocationsWrite), h.RestoreDeleted(domain.AuditEntityLocation, "id"))
+	locations.Delete("/:id/purge", can(domain.PermissionLocationsWrite), h.PurgeDeleted(domain.AuditEntityLocation, "id"))
+	locations.Get("/:id/revisions", can(domain.PermissionLocationsRead), h.GetRevisions(domain.AuditEntityLocation, "id"))
+	locations.Get("/:id/revisions/:rev", can(domain.PermissionLocationsRead), h.GetRevision(domain.AuditEntityLocation, "id"))
+	locations.Post("/:id/revisions/:rev/restore", can(domain.PermissionLocationsWrite), h.RestoreRevision(domain.AuditEntityLocation, "id"))
+
+	// Number pools endpoints
+	numberPools := protected.Group("number-pools")
+	numberPools.Get("/", can(domain.PermissionProfilesRead), numberPoolsHandler.GetNumberPools)
+	numberPools.Get("/utilisation", can(domain.PermissionProfilesRead), numberPoolsHandler.GetNumberPoolUtilisation)
+	numberPools.Get("/:id", can(domain.PermissionProfilesRead), numberPoolsHandler.GetNumberPool)
+	numberPools.Post("/", can(domain.PermissionProfilesWrite), numberPoolsHandler.CreateNumberPool)
+	numberPools.Put("/:id", can(domain.PermissionProfilesWrite), numberPoolsHandler.UpdateNumberPool)
+	numberPools.Delete("/:id", can(domain.PermissionProfilesWrite), numberPoolsHandler.DeleteNumberPool)
+	numberPools.Get("/:id/next", can(domain.PermissionProfilesRead), numberPoolsHandler.GetNextFreeNumber)
+	numberPools.Get("/:id/reservations", can(domain.PermissionProfilesRead), numberPoolsHandler.GetNumberReservations)
+	numberPools.Post("/:id/reservations", can(domain.PermissionProfilesWrite), numberPoolsHandler.ReserveNumber)
+	numberPools.Delete("/:id/reservations/:number", can(domain.PermissionProfilesWrite), numberPoolsHandler.DeleteNumberReservation)
+
+	// CDR endpoints
+	cdr := protected.Group("cdr")
+	cdr.Get("/", can(domain.PermissionCDRRead), h.Pagination, h.GetCDR)
+
+	// Reports endpoints
+	reports := protected.Group("reports")
+	reports.Get("/calls-per-day", can(domain.PermissionReportsRead), h.GetCallsPerDayReport)
+	reports.Get("/ring-groups", can(domain.PermissionReportsRead), h.GetRingGroupCallsReport)
+	reports.Get("/durations", can(domain.PermissionReportsRead), h.GetCallDurationsReport)
+	reports.Get("/busiest-hours", can(domain.PermissionReportsRead), h.GetBusiestHoursReport)
+	reports.Get("/top-callers", can(domain.PermissionReportsRead), h.GetTopCallersReport)
+	reports.Get("/unanswered-dids", can(domain.PermissionReportsRead), h.GetUnansweredDIDsReport)
+
+	// Recordings endpoints
+	recordings := protected.Group("recordings")
+	recordings.Get("/", can(domain.PermissionRecordingsRead), h.Pagination, recordingsHandler.GetRecordings)
+	recordings.Post("/scan", can(domain.PermissionRecordingsManage), recordingsHandler.ScanRecordings)
+	recordings.Post("/purge", can(domain.PermissionRecordingsManage), recordingsHandler.PurgeRecordings)
+	recordings.Get("/deletions", can(domain.PermissionRecordingsManage), h.Pagination, recordingsHandler.GetRecordingDeletions)
+	recordings.Get("/:id", can(domain.PermissionRecordingsRead), recordingsHandler.GetRecording)
+	recordings.Get("/:id/audio", can(domain.PermissionRecordingsRead), recordingsHandler.GetRecordingAudio)
+	recordings.Put("/:id/hold", can(domain.PermissionRecordingsManage), recordingsHandler.SetRecordingHold)
+
+	// Retention policies endpoints
+	retention := protected.Group("retention-policies")
+	retention.Get("/", can(domain.PermissionRecordingsRead), recordingsHandler.GetRetentionPolicies)
+	retention.Post("/", can(domain.PermissionRecordingsManage), recordingsHandler.CreateRetentionPolicy)
+	retention.Put("/:id", can(domain.PermissionRecordingsManage), recordingsHandler.UpdateRetentionPolicy)
+	retention.Delete("/:id", can(domain.PermissionRecordingsManage), recordingsHandler.DeleteRetentionPolicy)
+
+	// Faxes endpoints
+	faxes := protected.Group("faxes")
+	faxes.Get("/", can(domain.PermissionFaxesRead), h.Pagination, faxesHandler.GetFaxes)
+	faxes.Post("/scan", can(domain.PermissionFaxesManage), faxesHandler.ScanFaxes)
+	faxes.Get("/:id", can(domain.PermissionFaxesRead), faxesHandler.GetFax)
+	faxes.Get("/:id/file", can(domain.PermissionFaxesRead), faxesHandler.DownloadFax)
+	faxes.Put("/:id/read", can(domain.PermissionFaxesRead), faxesHandler.MarkFaxRead)
+	faxes.Delete("/:id/read", can(domain.PermissionFaxesRead), faxesHandler.MarkFaxUnread)
+	faxes.Get("/:id/deliveries", can(domain.PermissionFaxesRead), faxesHandler.GetFaxDeliveries)
+	faxes.Post("/:id/resend", can(domain.PermissionFaxesManage), faxesHandler.ResendFax)
+
+	// Fax recipients endpoints
+	faxRecipients := protected.Group("fax-recipients")
+	faxRecipients.Get("/", can(domain.PermissionFaxesRead), faxesHandler.GetFaxRecipients)
+	faxRecipients.Post("/", can(domain.PermissionFaxesManage), faxesHandler.CreateFaxRecipient)
+	faxRecipients.Delete("/:id", can(domain.PermissionFaxesManage), faxesHandler.DeleteFaxRecipient)
 
 	// Generator endpoints
-	generator := protected.Group("generator")
-	_ = generator // TODO: добавить handlers для generator
+	generator := protected.Group("generator", can(domain.PermissionGeneratorRun))
+	generator.Post("/run", h.RunGenerator)
+	generator.Get("/export", h.ExportGenerator)
+}
+
+// loginRateLimit ограничивает частоту запросов входа с одного IP (LOGIN_RATE_LIMIT в минуту)
+func loginRateLimit() fiber.Handler {
+	max, err := strconv.Atoi(os.Getenv("LOGIN_RATE_LIMIT"))
+	if err != nil || max < 1 {
+		max = 30
+	}
+
+	return limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			return fiber.NewError(fiber.StatusTooManyRequests, "Too many login requests, try again later")
+		},
+	})
 }
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix префикс ключей, по которому их легко найти в скриптах и логах
+const apiKeyPrefix = "sak_"
+
+var (
+	// ErrInvalidAPIKey ключ неизвестен, истёк, отозван или его владелец заблокирован
+	ErrInvalidAPIKey = errors.New("invalid or expired API key")
+	// ErrInvalidScope scope неизвестен или не доступен роли создателя ключа
+	ErrInvalidScope = errors.New("invalid API key scope")
+)
+
+// CreateAPIKey создаёт ключ от имени пользователя и возвращает его значение.
+// Значение показывается один раз: в БД остаётся только хеш.
+func (s *AuthService) CreateAPIKey(creator *domain.User, name string, scopes []domain.Permission, expiresAt *time.Time) (*domain.APIKey, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", errors.Wrap(ErrInvalidScope, "at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !creator.Role.Can(scope) {
+			return nil, "", errors.Wrapf(ErrInvalidScope, "scope %s", scope)
+		}
+	}
+
+	secret, err := randomString()
+	if err != nil {
+		return nil, "", err
+	}
+	value := apiKeyPrefix + secret
+
+	key := &domain.APIKey{
+		Name:      name,
+		Prefix:    value[:len(apiKeyPrefix)+6],
+		KeyHash:   hashToken(value),
+		Scopes:    scopes,
+		CreatedBy: creator.ID,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repos.Create(key); err != nil {
+		return nil, "", errors.Wrap(err, "failed to create API key")
+	}
+	return key, value, nil
+}
+
+// ValidateAPIKey проверяет ключ и возвращает claims от имени его создателя
+func (s *AuthService) ValidateAPIKey(value string) (*JWTClaims, error) {
+	var key domain.APIKey
+	err := s.repos.FindAPIKeyByHash(&key, hashToken(value))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !key.IsActive(s.now()) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var creator domain.User
+	if err := s.repos.FindByID(&creator, key.CreatedBy); err != nil || !creator.IsActive {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return &JWTClaims{
+		UserID:   creator.ID,
+		Username: "apikey:" + key.Name,
+		Role:     creator.Role,
+		APIKeyID: key.ID,
+		Scopes:   key.Scopes,
+	}, nil
+}
+
+// RevokeAPIKey отзывает ключ
+func (s *AuthService) RevokeAPIKey(id uint) error {
+	return s.repos.RevokeAPIKey(id, s.now())
+}
+
+// LogAPIKeyUsage записывает обращение к API по ключу
+func (s *AuthService) LogAPIKeyUsage(keyID uint, method, path string, status int, ip string) error {
+	return s.repos.LogAPIKeyUsage(&domain.APIKeyUsage{
+		APIKeyID:  keyID,
+		Method:    method,
+		Path:      path,
+		Status:    status,
+		IP:        ip,
+		CreatedAt: s.now(),
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -71,6 +72,11 @@ func GetMD5Hash(text string) string {
 type AsteriskGenerator struct {
 	Records   []PhoneRecord
 	OutputDir string
+
+	// FaxNotifyURL адрес POST /api/faxes/incoming; если задан, диалплан
+	// сообщает о факсе бэкенду вместо отправки через sendEmail.pl
+	FaxNotifyURL   string
+	FaxNotifyToken string
 }
 
 // NewAsteriskGenerator создаёт новый генератор
@@ -116,22 +122,71 @@ func (g *AsteriskGenerator) LoadCSV(filename string) error {
 	return nil
 }
 
+// NewAsteriskGeneratorFromEnv создаёт генератор с настройками уведомления о факсах из окружения
+func NewAsteriskGeneratorFromEnv(outputDir string) *AsteriskGenerator {
+	g := NewAsteriskGenerator(outputDir)
+	g.FaxNotifyURL = os.Getenv("FAX_NOTIFY_URL")
+	g.FaxNotifyToken = os.Getenv("FAX_HOOK_TOKEN")
+	return g
+}
+
 // LoadFromDatabase загружает данные из базы данных
 func (g *AsteriskGenerator) LoadFromDatabase(repos *repositories.Repos) error {
 	isActive := true
-	profiles, _, err := repos.FindProfilesWithLocations(&isActive, nil)
+	profiles, _, err := repos.FindProfilesWithLocations(&domain.ProfileFilter{IsActive: &isActive}, nil)
 	if err != nil {
 		return fmt.Errorf("ошибка загрузки профилей: %w", err)
 	}
 
 	// Загружаем устройства
 	var devices []domain.Device
-	deviceMap := make(map[string]domain.Device)
 	if err := repos.FindAllDevices(&devices); err != nil {
 		return fmt.Errorf("ошибка загрузки устройств: %w", err)
 	}
+
+	g.loadProfiles(profiles, devices)
+
+	fmt.Printf("Загружено записей из БД: %d\n", len(g.Records))
+	return nil
+}
+
+// LoadSnapshot загружает состояние БД на момент снимка (см. Repos.FindSnapshot)
+func (g *AsteriskGenerator) LoadSnapshot(snapshot *domain.Snapshot) {
+	locations := make(map[uint]domain.Location, len(snapshot.Locations))
+	for _, location := range snapshot.Locations {
+		locations[location.ID] = location
+	}
+
+	// Собираем профили с локациями так же, как FindProfilesWithLocations
+	profiles := make([]domain.ProfileWithLocation, 0, len(snapshot.Profiles))
+	for _, profile := range snapshot.Profiles {
+		if !profile.IsActive {
+			continue
+		}
+		p := domain.ProfileWithLocation{Profile: profile}
+		if profile.LocationID != nil {
+			if location, ok := locations[*profile.LocationID]; ok {
+				p.LocationName = &location.Name
+				p.Server = &location.Server
+				p.Subnet = &location.Subnet
+				p.VoipVLAN = &location.VoipVLAN
+				p.VLAN = &location.VLAN
+			}
+		}
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].ID < profiles[j].ID })
+
+	g.loadProfiles(profiles, snapshot.Devices)
+
+	fmt.Printf("Загружено записей на %s: %d\n", snapshot.At.Format("2006-01-02 15:04:05"), len(g.Records))
+}
+
+// loadProfiles конвертирует профили с локациями и устройства в PhoneRecord
+func (g *AsteriskGenerator) loadProfiles(profiles []domain.ProfileWithLocation, devices []domain.Device) {
+	deviceMap := make(map[string]domain.Device)
 	for _, dev := range devices {
-		deviceMap[dev.MAC] = dev
+		deviceMap[domain.NormalizeMAC(dev.MAC)] = dev
 	}
 
 	// Конвертируем domain-модели в PhoneRecord
@@ -141,9 +196,6 @@ func (g *AsteriskGenerator) LoadFromDatabase(repos *repositories.Repos) error {
 			g.Records = append(g.Records, *record)
 		}
 	}
-
-	fmt.Printf("Загружено записей из БД: %d\n", len(g.Records))
-	return nil
 }
 
 // profileToPhoneRecord конвертирует domain.ProfileWithLocation в PhoneRecord
@@ -180,7 +232,7 @@ func profileToPhoneRecord(p domain.ProfileWithLocation, deviceMap map[string]dom
 	// Устройство
 	if p.Device != nil {
 		record.MACAddress = strings.ToLower(*p.Device)
-		if dev, ok := deviceMap[*p.Device]; ok {
+		if dev, ok := deviceMap[domain.NormalizeMAC(*p.Device)]; ok {
 			switch dev.DeviceModel {
 			case domain.DeviceModelYealinkT27G:
 				record.IsT27 = true
@@ -709,11 +761,15 @@ func (g *AsteriskGenerator) generateRingGroups(dir string) error {
 		sbVMCFG.WriteString("exten => 1,4,System(/usr/bin/tiff2pdf ${FAXFILE} > ${PDFFILE})\n")
 		sbVMCFG.WriteString("exten => 1,5,System(/bin/rm -f ${FAXFILE})\n")
 
-		email := first.Email
-		if email == "" {
-			email = "fax@nur.yanao.ru"
+		if g.FaxNotifyURL != "" {
+			sbVMCFG.WriteString(fmt.Sprintf("exten => 1,6,System(/usr/bin/curl -s -m 10 -H \"X-Fax-Token: %s\" --data-urlencode \"path=${PDFFILE}\" %s)\n", g.FaxNotifyToken, g.FaxNotifyURL))
+		} else {
+			email := first.Email
+			if email == "" {
+				email = "fax@nur.yanao.ru"
+			}
+			sbVMCFG.WriteString(fmt.Sprintf("exten => 1,6,System(/root/bin/sendEmail.pl -f fax%s@nur.yanao.ru -t %s -u \"Incoming FAX ${CALLERID(num)}\" -m \"Вам пришел факс с номера ${CALLERID(num)} в ${STRFTIME(${EPOCH},,%%H:%%M:%%S)}. Факс во вложении.\" -a ${PDFFILE} -o message-charset=UTF-8)\n", cityNum, email))
 		}
-		sbVMCFG.WriteString(fmt.Sprintf("exten => 1,6,System(/root/bin/sendEmail.pl -f fax%s@nur.yanao.ru -t %s -u \"Incoming FAX ${CALLERID(num)}\" -m \"Вам пришел факс с номера ${CALLERID(num)} в ${STRFTIME(${EPOCH},,%%H:%%M:%%S)}. Факс во вложении.\" -a ${PDFFILE} -o message-charset=UTF-8)\n", cityNum, email))
 		sbVMCFG.WriteString("exten => 1,7,Hangup()\n")
 		sbVMCFG.WriteString("exten => 2,1,Background(record/VoiceMesAns)\n")
 		sbVMCFG.WriteString(fmt.Sprintf("exten => 2,2,Voicemail(%s,s)\n\n", first.Extension))
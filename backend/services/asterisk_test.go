@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSnapshot(t *testing.T) {
+	locationID := uint(3)
+	mac := "00-15-65-AA-BB-CC"
+	ringGroup := 2
+
+	snapshot := &domain.Snapshot{
+		At: time.Date(2024, 5, 14, 18, 0, 0, 0, time.UTC),
+		Profiles: []domain.Profile{
+			{ID: 2, Name: "Петров", InternalNumber: 1002, LocationID: &locationID, IsActive: false},
+			{ID: 1, Name: "Иванов", InternalNumber: 1001, LocationID: &locationID, Device: &mac, RingGroup: &ringGroup, IsActive: true},
+			{ID: 3, Name: "Без локации", InternalNumber: 1003, IsActive: true},
+		},
+		Devices: []domain.Device{
+			{MAC: "00:15:65:aa:bb:cc", DeviceModel: domain.DeviceModelYealinkT27G},
+		},
+		Locations: []domain.Location{
+			{ID: locationID, Name: "Zags", Server: "10.16.0.102", Subnet: "10.1.191.0/26", VoipVLAN: 5, VLAN: 601},
+		},
+	}
+
+	g := NewAsteriskGenerator(t.TempDir())
+	g.LoadSnapshot(snapshot)
+
+	// Неактивные профили и профили без локации не попадают в конфигурацию
+	require.Len(t, g.Records, 1)
+	record := g.Records[0]
+	assert.Equal(t, "1001", record.Extension)
+	assert.Equal(t, "Zags", record.Location)
+	assert.Equal(t, "601", record.LanVLAN)
+	assert.Equal(t, "2", record.RingGroup)
+	assert.True(t, record.IsT27)
+}
+
+func TestDiffGeneratedFiles(t *testing.T) {
+	locationID := uint(3)
+	mac := "80:5e:c0:18:ab:ac"
+	snapshot := &domain.Snapshot{
+		Profiles: []domain.Profile{
+			{ID: 1, Name: "Иванов", InternalNumber: 1001, LocationID: &locationID, Device: &mac, IsActive: true},
+			{ID: 2, Name: "Петров", InternalNumber: 1002, LocationID: &locationID, IsActive: true},
+		},
+		Devices: []domain.Device{
+			{MAC: mac, DeviceModel: domain.DeviceModelYealinkT27G},
+		},
+		Locations: []domain.Location{
+			{ID: locationID, Name: "Zags", Server: "10.16.0.102", Subnet: "10.1.191.0/26", VoipVLAN: 5, VLAN: 601},
+		},
+	}
+
+	before := NewAsteriskGenerator(t.TempDir())
+	before.LoadSnapshot(snapshot)
+	beforeFiles, err := generateFiles(before)
+	require.NoError(t, err)
+
+	// Удаление устройства: профиль остаётся без модели телефона
+	snapshot.Devices = nil
+	after := NewAsteriskGenerator(t.TempDir())
+	after.LoadSnapshot(snapshot)
+	afterFiles, err := generateFiles(after)
+	require.NoError(t, err)
+
+	changes := DiffGeneratedFiles(beforeFiles, afterFiles)
+	assert.Equal(t, []domain.FileChange{
+		{Path: "UsersConf/User1001.conf", Change: domain.FileRemoved},
+		{Path: "tftpboot/80:5e:c0:18:ab:ac.cfg", Change: domain.FileRemoved},
+	}, changes)
+	assert.Empty(t, DiffGeneratedFiles(beforeFiles, beforeFiles))
+}
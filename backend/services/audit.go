@@ -0,0 +1,124 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+
+	"github.com/pkg/errors"
+)
+
+// AuditActor автор изменения: пользователь, API-ключ или фоновая задача (только Username)
+type AuditActor struct {
+	UserID   *uint
+	APIKeyID *uint
+	Username string
+	IP       string
+}
+
+// SchedulerActor автор изменений, выполненных по расписанию
+var SchedulerActor = AuditActor{Username: "scheduler"}
+
+// RecordAudit записывает изменение сущности в журнал аудита и историю ревизий в транзакции tx.
+// before - состояние до изменения (nil при создании), after - после (nil при удалении).
+// Изменение без отличий в полях не записывается.
+func RecordAudit(tx *repositories.Repos, action domain.AuditAction, before, after domain.Auditable, actor AuditActor) error {
+	diff, err := AuditDiff(before, after)
+	if err != nil {
+		return err
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+
+	entity := after
+	if entity == nil {
+		entity = before
+	}
+	entityType, entityID := entity.AuditEntity()
+
+	entry := domain.AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    diff,
+		IP:         actor.IP,
+		Username:   actor.Username,
+		UserID:     actor.UserID,
+		APIKeyID:   actor.APIKeyID,
+	}
+	if err := tx.Create(&entry); err != nil {
+		return err
+	}
+
+	// Ревизия хранит полное состояние: после изменения, а для удаления - последнее
+	data, err := domain.RevisionData(entity)
+	if err != nil {
+		return err
+	}
+	return tx.CreateRevision(&domain.Revision{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		Data:       data,
+		UserID:     entry.UserID,
+		Username:   entry.Username,
+		CreatedAt:  entry.CreatedAt,
+	})
+}
+
+// auditIgnoredFields служебные поля, которые не попадают в diff
+var auditIgnoredFields = map[string]bool{
+	"createdAt": true,
+	"updatedAt": true,
+}
+
+// AuditDiff сравнивает JSON-представления сущности до и после изменения.
+// before == nil - создание, after == nil - удаление; в обоих случаях в diff попадают все поля.
+func AuditDiff(before, after interface{}) (map[string]domain.AuditChange, error) {
+	old, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]domain.AuditChange)
+	for field, value := range old {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		newValue, ok := updated[field]
+		if !ok || !reflect.DeepEqual(value, newValue) {
+			changes[field] = domain.AuditChange{Old: value, New: newValue}
+		}
+	}
+	for field, value := range updated {
+		if _, ok := old[field]; ok || auditIgnoredFields[field] {
+			continue
+		}
+		changes[field] = domain.AuditChange{New: value}
+	}
+	return changes, nil
+}
+
+// auditFields раскладывает сущность на поля так, как она видна в API
+func auditFields(entity interface{}) (map[string]interface{}, error) {
+	if entity == nil || reflect.ValueOf(entity).Kind() == reflect.Ptr && reflect.ValueOf(entity).IsNil() {
+		return map[string]interface{}{}, nil
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal audited entity")
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal audited entity")
+	}
+	return fields, nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+
+	"asterisk-manager/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditDiff(t *testing.T) {
+	mac := "00:15:65:aa:bb:cc"
+	before := &domain.Profile{ID: 7, Name: "Иванов", InternalNumber: 1001}
+	after := &domain.Profile{ID: 7, Name: "Иванов", InternalNumber: 1002, Device: &mac}
+
+	changes, err := AuditDiff(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]domain.AuditChange{
+		"internalNumber": {Old: float64(1001), New: float64(1002)},
+		"device":         {Old: nil, New: mac},
+	}, changes)
+
+	// Создание: все поля, кроме служебных
+	changes, err = AuditDiff(nil, after)
+	require.NoError(t, err)
+	assert.Equal(t, domain.AuditChange{New: "Иванов"}, changes["name"])
+	assert.NotContains(t, changes, "createdAt")
+
+	// Удаление
+	var none *domain.Profile
+	changes, err = AuditDiff(before, none)
+	require.NoError(t, err)
+	assert.Equal(t, domain.AuditChange{Old: float64(7)}, changes["id"])
+
+	changes, err = AuditDiff(before, before)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
@@ -1,71 +1,427 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"os"
+	"strings"
 	"time"
 
 	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Причины отзыва сессий
+const (
+	RevokeReasonLogout          = "logout"
+	RevokeReasonRefreshReuse    = "refresh token reuse"
+	RevokeReasonRoleChanged     = "role changed"
+	RevokeReasonDisabled        = "user disabled"
+	RevokeReasonPasswordChanged = "password changed"
+	RevokeReasonPasswordReset   = "password reset"
+	RevokeReasonAdmin           = "revoked by admin"
+	RevokeReasonTOTPReset       = "two-factor reset"
+)
+
+var (
+	// ErrInvalidCredentials неверное имя пользователя или пароль
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUserDisabled учётная запись заблокирована
+	ErrUserDisabled = errors.New("account is disabled")
+	// ErrInvalidRefreshToken refresh-токен неизвестен, истёк или отозван
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused повторно предъявлен уже обменянный refresh-токен
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrSessionRevoked сессия access-токена отозвана
+	ErrSessionRevoked = errors.New("session revoked")
 )
 
 // JWTClaims содержит claims для JWT токена
 type JWTClaims struct {
-	UserID   uint            `json:"userId"`
-	Username string          `json:"username"`
-	Role     domain.UserRole `json:"role"`
+	UserID             uint            `json:"userId"`
+	Username           string          `json:"username"`
+	Role               domain.UserRole `json:"role"`
+	SessionID          uint            `json:"sid"`
+	MustChangePassword bool            `json:"mustChangePassword,omitempty"` // токен годится только для смены пароля
+	// MFAEnrollmentRequired - роль требует второй фактор, а он не подключён;
+	// токен годится только для подключения TOTP
+	MFAEnrollmentRequired bool `json:"mfaEnrollmentRequired,omitempty"`
 	jwt.RegisteredClaims
+
+	// APIKeyID и Scopes заполняются, если запрос выполнен с API-ключом
+	APIKeyID uint                `json:"-"`
+	Scopes   []domain.Permission `json:"-"`
+}
+
+// Can проверяет право: по роли, а для API-ключа ещё и по его scopes
+func (c *JWTClaims) Can(permission domain.Permission) bool {
+	if !c.Role.Can(permission) {
+		return false
+	}
+	if c.APIKeyID == 0 {
+		return true
+	}
+	for _, scope := range c.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenPair access-токен и (при входе и обновлении) refresh-токен
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
 }
 
 // AuthService сервис авторизации
 type AuthService struct {
-	secretKey     []byte
-	tokenDuration time.Duration
+	repos           *repositories.Repos
+	secretKey       []byte
+	tokenDuration   time.Duration
+	refreshDuration time.Duration
+	passwordPolicy  PasswordPolicy
+	mfaPolicy       MFAPolicy
+	ldap            *LDAPAuthenticator
+	oidc            *OIDCAuthenticator
+	now             func() time.Time
 }
 
 // NewAuthService создает новый сервис авторизации
-func NewAuthService() *AuthService {
+func NewAuthService(repos *repositories.Repos) *AuthService {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
 		secret = "asterisk-manager-secret-key-change-in-production"
 	}
 
+	var ldapAuth *LDAPAuthenticator
+	if config := LDAPConfigFromEnv(); config.Enabled() {
+		ldapAuth = NewLDAPAuthenticator(config)
+	}
+
+	var oidcAuth *OIDCAuthenticator
+	if config := OIDCConfigFromEnv(); config.Enabled() {
+		oidcAuth = NewOIDCAuthenticator(config)
+	}
+
 	return &AuthService{
-		secretKey:     []byte(secret),
-		tokenDuration: 24 * time.Hour,
+		repos:           repos,
+		secretKey:       []byte(secret),
+		tokenDuration:   durationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		refreshDuration: durationFromEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		passwordPolicy:  PasswordPolicyFromEnv(),
+		mfaPolicy:       MFAPolicyFromEnv(),
+		ldap:            ldapAuth,
+		oidc:            oidcAuth,
+		now:             time.Now,
+	}
+}
+
+// PasswordPolicy возвращает требования к паролям
+func (s *AuthService) PasswordPolicy() PasswordPolicy {
+	return s.passwordPolicy
+}
+
+// LDAPEnabled проверяет, включён ли вход через LDAP
+func (s *AuthService) LDAPEnabled() bool {
+	return s.ldap != nil
+}
+
+// OIDC возвращает аутентификатор OpenID Connect или nil, если вход через OIDC выключен
+func (s *AuthService) OIDC() *OIDCAuthenticator {
+	return s.oidc
+}
+
+// Authenticate проверяет имя и пароль. Локальные пользователи проверяются по БД
+// (в том числе при недоступном каталоге), остальные - в LDAP; пользователь каталога
+// создаётся при первом входе, а его роль обновляется по группам при каждом входе.
+func (s *AuthService) Authenticate(username, password string) (*domain.User, error) {
+	var user domain.User
+	err := s.repos.FindUserByUsername(&user, username)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil && user.AuthSource != domain.AuthSourceLDAP {
+		if !user.CheckPassword(password) {
+			return nil, ErrInvalidCredentials
+		}
+		if !user.IsActive {
+			return nil, ErrUserDisabled
+		}
+		return &user, nil
+	}
+
+	if s.ldap == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return s.authenticateLDAP(strings.ToLower(username), password)
+}
+
+func (s *AuthService) authenticateLDAP(username, password string) (*domain.User, error) {
+	identity, err := s.ldap.Authenticate(username, password)
+	if errors.Is(err, ErrLDAPInvalidCredentials) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.ldap.RoleFor(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	var user domain.User
+	err = s.repos.FindUserByUsername(&user, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = domain.User{
+			Username:   username,
+			Role:       role,
+			AuthSource: domain.AuthSourceLDAP,
+			IsActive:   true,
+		}
+		if err := s.repos.Create(&user); err != nil {
+			return nil, errors.Wrap(err, "failed to provision directory user")
+		}
+		return &user, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.syncExternalUser(&user, domain.AuthSourceLDAP, role)
+}
+
+// AuthenticateOIDC находит или создаёт пользователя, вошедшего через провайдера OIDC
+func (s *AuthService) AuthenticateOIDC(identity *OIDCIdentity) (*domain.User, error) {
+	var user domain.User
+	err := s.repos.FindUserByUsername(&user, identity.Username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = domain.User{
+			Username:   identity.Username,
+			Role:       identity.Role,
+			AuthSource: domain.AuthSourceOIDC,
+			IsActive:   true,
+		}
+		if err := s.repos.Create(&user); err != nil {
+			return nil, errors.Wrap(err, "failed to provision OIDC user")
+		}
+		return &user, nil
+	}
+	if err != nil {
+		return nil, err
 	}
+
+	return s.syncExternalUser(&user, domain.AuthSourceOIDC, identity.Role)
 }
 
-// GenerateToken генерирует JWT токен для пользователя
-func (s *AuthService) GenerateToken(user *domain.User) (string, error) {
+// syncExternalUser проверяет, что учётная запись принадлежит внешнему источнику,
+// и обновляет её роль; при смене роли старые сессии отзываются
+func (s *AuthService) syncExternalUser(user *domain.User, source domain.AuthSource, role domain.UserRole) (*domain.User, error) {
+	if user.AuthSource != source {
+		// Учётная запись с тем же именем из другого источника не перехватывается
+		return nil, ErrInvalidCredentials
+	}
+	if !user.IsActive {
+		return nil, ErrUserDisabled
+	}
+	if user.Role != role {
+		user.Role = role
+		if err := s.repos.Save(user); err != nil {
+			return nil, err
+		}
+		if err := s.RevokeUserSessions(user.ID, 0, RevokeReasonRoleChanged); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// GenerateToken генерирует короткоживущий JWT токен пользователя в рамках сессии
+func (s *AuthService) GenerateToken(user *domain.User, sessionID uint) (string, time.Time, error) {
+	now := s.now()
+	expiresAt := now.Add(s.tokenDuration)
 	claims := JWTClaims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Role:     user.Role,
+		UserID:                user.ID,
+		Username:              user.Username,
+		Role:                  user.Role,
+		SessionID:             sessionID,
+		MustChangePassword:    user.MustChangePassword,
+		MFAEnrollmentRequired: s.MFAEnrollmentRequired(user),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.tokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Subject:   user.Username,
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secretKey)
+	signed, err := token.SignedString(s.secretKey)
+	return signed, expiresAt, err
 }
 
-// ValidateToken проверяет JWT токен и возвращает claims
+// ValidateToken проверяет JWT токен и то, что его сессия не отозвана
 func (s *AuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return s.secretKey, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	active, err := s.repos.IsSessionActive(claims.SessionID, claims.UserID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrSessionRevoked
+	}
+
+	return claims, nil
+}
 
+// StartSession создаёт сессию пользователя и выдаёт пару токенов
+func (s *AuthService) StartSession(user *domain.User, ip, userAgent string) (*TokenPair, error) {
+	refreshToken, hash, err := newRefreshToken()
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+	now := s.now()
+	session := domain.Session{
+		UserID:     user.ID,
+		TokenHash:  hash,
+		IP:         ip,
+		UserAgent:  userAgent,
+		ExpiresAt:  now.Add(s.refreshDuration),
+		LastUsedAt: now,
+	}
+	if err := s.repos.Create(&session); err != nil {
+		return nil, errors.Wrap(err, "failed to create session")
+	}
+
+	return s.tokenPair(user, session.ID, refreshToken)
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов. Предъявление уже
+// обменянного токена означает его утечку, поэтому сессия отзывается целиком.
+func (s *AuthService) Refresh(refreshToken, ip, userAgent string) (*TokenPair, *domain.User, error) {
+	hash := hashToken(refreshToken)
+	now := s.now()
+
+	var session domain.Session
+	err := s.repos.FindSessionByTokenHash(&session, hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if session.TokenHash != hash {
+		if err := s.repos.RevokeSession(session.ID, RevokeReasonRefreshReuse, now); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+	if !session.IsActive(now) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	var user domain.User
+	if err := s.repos.FindByID(&user, session.UserID); err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if !user.IsActive {
+		if err := s.repos.RevokeSession(session.ID, RevokeReasonDisabled, now); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidRefreshToken
 	}
 
-	return nil, jwt.ErrSignatureInvalid
+	newToken, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	session.IP = ip
+	session.UserAgent = userAgent
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(s.refreshDuration)
+
+	rotated, err := s.repos.RotateSessionToken(&session, hash, newHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rotated {
+		// Токен успел обменять параллельный запрос
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	pair, err := s.tokenPair(&user, session.ID, newToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, &user, nil
+}
+
+// RevokeSession отзывает одну сессию
+func (s *AuthService) RevokeSession(sessionID uint, reason string) error {
+	return s.repos.RevokeSession(sessionID, reason, s.now())
+}
+
+// RevokeUserSessions отзывает все сессии пользователя, кроме exceptID (0 - все)
+func (s *AuthService) RevokeUserSessions(userID, exceptID uint, reason string) error {
+	return s.repos.RevokeSessions(userID, exceptID, reason, s.now())
+}
+
+// ActiveSessions возвращает действующие сессии пользователя
+func (s *AuthService) ActiveSessions(userID uint) ([]domain.Session, error) {
+	return s.repos.FindActiveSessions(userID, s.now())
+}
+
+// CleanupSessions удаляет истёкшие сессии
+func (s *AuthService) CleanupSessions() error {
+	_, err := s.repos.DeleteExpiredSessions(s.now())
+	return err
+}
+
+func (s *AuthService) tokenPair(user *domain.User, sessionID uint, refreshToken string) (*TokenPair, error) {
+	accessToken, expiresAt, err := s.GenerateToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// newRefreshToken генерирует случайный refresh-токен и его хеш для хранения в БД
+func newRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", errors.Wrap(err, "failed to generate refresh token")
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWTClaimsCan(t *testing.T) {
+	// Пользовательский токен: права роли
+	user := &JWTClaims{Role: domain.UserRoleUser}
+	assert.True(t, user.Can(domain.PermissionProfilesRead))
+	assert.False(t, user.Can(domain.PermissionProfilesWrite))
+
+	// API-ключ: только выданные scopes
+	key := &JWTClaims{
+		Role:     domain.UserRoleAdmin,
+		APIKeyID: 1,
+		Scopes:   []domain.Permission{domain.PermissionProfilesRead, domain.PermissionProfilesWrite},
+	}
+	assert.True(t, key.Can(domain.PermissionProfilesWrite))
+	assert.False(t, key.Can(domain.PermissionLocationsWrite))
+
+	// Scope не расширяет права роли создателя ключа
+	demoted := &JWTClaims{
+		Role:     domain.UserRoleUser,
+		APIKeyID: 1,
+		Scopes:   []domain.Permission{domain.PermissionProfilesWrite},
+	}
+	assert.False(t, demoted.Can(domain.PermissionProfilesWrite))
+}
+
+func TestLoginGuardLockoutDuration(t *testing.T) {
+	guard := NewLoginGuard(nil, LoginGuardConfig{
+		BaseLockout: time.Minute,
+		MaxLockout:  time.Hour,
+	})
+
+	assert.Equal(t, time.Minute, guard.LockoutDuration(0))
+	assert.Equal(t, 2*time.Minute, guard.LockoutDuration(1))
+	assert.Equal(t, 32*time.Minute, guard.LockoutDuration(5))
+	assert.Equal(t, time.Hour, guard.LockoutDuration(6))
+	assert.Equal(t, time.Hour, guard.LockoutDuration(1000))
+}
+
+func TestMFAPolicyRequired(t *testing.T) {
+	policy := MFAPolicy{RequiredRoles: []domain.UserRole{domain.UserRoleAdmin}}
+
+	assert.True(t, policy.Required(&domain.User{Role: domain.UserRoleAdmin, AuthSource: domain.AuthSourceLocal}))
+	assert.True(t, policy.Required(&domain.User{Role: domain.UserRoleAdmin, AuthSource: domain.AuthSourceLDAP}))
+	assert.False(t, policy.Required(&domain.User{Role: domain.UserRoleAdmin, AuthSource: domain.AuthSourceOIDC}))
+	assert.False(t, policy.Required(&domain.User{Role: domain.UserRoleUser, AuthSource: domain.AuthSourceLocal}))
+}
@@ -0,0 +1,431 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Источники CDR
+const (
+	CDRSourceCSV = "csv"
+	CDRSourceAMI = "ami"
+)
+
+// cdrTimeLayout формат времени в Master.csv и событиях cdr_manager
+const cdrTimeLayout = "2006-01-02 15:04:05"
+
+// CDRConfig настройки приёма CDR
+type CDRConfig struct {
+	Source       string
+	CSVPath      string
+	PollInterval time.Duration
+	AMIAddr      string
+	AMIUser      string
+	AMISecret    string
+}
+
+// CDRConfigFromEnv читает настройки приёма CDR из переменных окружения
+func CDRConfigFromEnv() CDRConfig {
+	return CDRConfig{
+		Source:       os.Getenv("CDR_SOURCE"),
+		CSVPath:      stringFromEnv("CDR_CSV_PATH", "/var/log/asterisk/cdr-csv/Master.csv"),
+		PollInterval: durationFromEnv("CDR_POLL_INTERVAL", 5*time.Second),
+		AMIAddr:      stringFromEnv("AMI_ADDR", "127.0.0.1:5038"),
+		AMIUser:      os.Getenv("AMI_USER"),
+		AMISecret:    os.Getenv("AMI_SECRET"),
+	}
+}
+
+// CDRService принимает CDR из Asterisk и связывает их с профилями и локациями
+type CDRService struct {
+	repos  *repositories.Repos
+	config CDRConfig
+}
+
+// NewCDRService создаёт сервис приёма CDR
+func NewCDRService(repos *repositories.Repos, config CDRConfig) *CDRService {
+	return &CDRService{
+		repos:  repos,
+		config: config,
+	}
+}
+
+// Run запускает приём CDR из настроенного источника до отмены контекста
+func (s *CDRService) Run(ctx context.Context) error {
+	switch s.config.Source {
+	case CDRSourceCSV:
+		return s.followCSV(ctx)
+	case CDRSourceAMI:
+		return s.listenAMI(ctx)
+	case "":
+		return nil
+	default:
+		return fmt.Errorf("неизвестный источник CDR: %s", s.config.Source)
+	}
+}
+
+// Ingest связывает запись с профилем и локацией и сохраняет её
+func (s *CDRService) Ingest(cdr *domain.CDR) error {
+	if err := s.link(cdr); err != nil {
+		return err
+	}
+	return s.repos.SaveCDR(cdr)
+}
+
+// link определяет направление звонка и заполняет связи с профилем и локацией
+func (s *CDRService) link(cdr *domain.CDR) error {
+	cdr.Direction = classifyCDR(cdr)
+
+	var profile domain.Profile
+	var err error
+
+	switch {
+	case cdr.Direction != domain.CDRDirectionInbound:
+		cdr.Extension = cdr.Src
+		err = s.findByExtension(&profile, cdr.Src)
+	case isCityNumber(cdr.Dst):
+		cdr.CityNumber = domain.CleanPhoneNumber(cdr.Dst)
+		err = s.repos.FindProfileByCityNumber(&profile, cdr.CityNumber)
+	case isExtension(cdr.Dst):
+		cdr.Extension = cdr.Dst
+		err = s.findByExtension(&profile, cdr.Dst)
+	default:
+		return nil
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to link CDR to profile")
+	}
+
+	cdr.ProfileID = &profile.ID
+	cdr.LocationID = profile.LocationID
+	cdr.RingGroup = profile.RingGroup
+	if cdr.Extension == "" {
+		cdr.Extension = strconv.Itoa(profile.InternalNumber)
+	}
+	if cdr.CityNumber == "" {
+		cdr.CityNumber = profile.GetExternalNumberClean()
+	}
+	return nil
+}
+
+func (s *CDRService) findByExtension(dest *domain.Profile, extension string) error {
+	number, err := strconv.Atoi(extension)
+	if err != nil {
+		return gorm.ErrRecordNotFound
+	}
+	return s.repos.FindProfileByInternalNumber(dest, number)
+}
+
+// classifyCDR определяет направление звонка по номерам и контексту.
+// Входящие с транков попадают в контексты DID_trunk_*.
+func classifyCDR(cdr *domain.CDR) domain.CDRDirection {
+	if strings.HasPrefix(cdr.DContext, "DID_") {
+		return domain.CDRDirectionInbound
+	}
+	if isExtension(cdr.Src) && isExtension(cdr.Dst) {
+		return domain.CDRDirectionInternal
+	}
+	if isExtension(cdr.Src) {
+		return domain.CDRDirectionOutbound
+	}
+	return domain.CDRDirectionInbound
+}
+
+// isExtension проверяет, является ли номер внутренним (4 цифры)
+func isExtension(number string) bool {
+	return len(number) == 4 && domain.CleanPhoneNumber(number) == number
+}
+
+// isCityNumber проверяет, является ли номер городским (6 цифр)
+func isCityNumber(number string) bool {
+	return len(domain.CleanPhoneNumber(number)) == 6
+}
+
+// ParseCDRRow разбирает строку Master.csv.
+// Колонки uniqueid и userfield есть только при loguniqueid/loguserfield в cdr.conf;
+// без uniqueid идентификатор строится из содержимого строки.
+func ParseCDRRow(row []string) (*domain.CDR, error) {
+	if len(row) < 16 {
+		return nil, fmt.Errorf("ожидалось минимум 16 колонок, получено %d", len(row))
+	}
+
+	cdr := &domain.CDR{
+		AccountCode: row[0],
+		Src:         row[1],
+		Dst:         row[2],
+		DContext:    row[3],
+		CallerID:    row[4],
+		Channel:     row[5],
+		DstChannel:  row[6],
+		LastApp:     row[7],
+		LastData:    row[8],
+		Disposition: domain.CDRDisposition(row[14]),
+		AMAFlags:    row[15],
+		UniqueID:    getField(row, 16),
+		UserField:   getField(row, 17),
+	}
+
+	if err := fillCDRTimes(cdr, row[9], row[10], row[11], row[12], row[13]); err != nil {
+		return nil, err
+	}
+
+	if cdr.UniqueID == "" {
+		cdr.UniqueID = "csv-" + GetMD5Hash(strings.Join(row, ","))
+	}
+
+	return cdr, nil
+}
+
+// parseAMICDR разбирает событие Cdr модуля cdr_manager
+func parseAMICDR(event map[string]string) (*domain.CDR, error) {
+	cdr := &domain.CDR{
+		AccountCode: event["AccountCode"],
+		Src:         event["Source"],
+		Dst:         event["Destination"],
+		DContext:    event["DestinationContext"],
+		CallerID:    event["CallerID"],
+		Channel:     event["Channel"],
+		DstChannel:  event["DestinationChannel"],
+		LastApp:     event["LastApplication"],
+		LastData:    event["LastData"],
+		Disposition: domain.CDRDisposition(event["Disposition"]),
+		AMAFlags:    event["AMAFlags"],
+		UniqueID:    event["UniqueID"],
+		UserField:   event["UserField"],
+	}
+
+	err := fillCDRTimes(cdr, event["StartTime"], event["AnswerTime"], event["EndTime"],
+		event["Duration"], event["BillableSeconds"])
+	if err != nil {
+		return nil, err
+	}
+
+	if cdr.UniqueID == "" {
+		return nil, fmt.Errorf("событие Cdr без UniqueID")
+	}
+
+	return cdr, nil
+}
+
+func fillCDRTimes(cdr *domain.CDR, start, answer, end, duration, billsec string) error {
+	var err error
+
+	cdr.Start, err = time.ParseInLocation(cdrTimeLayout, start, time.Local)
+	if err != nil {
+		return fmt.Errorf("некорректное время начала %q: %w", start, err)
+	}
+	if answer != "" {
+		answered, err := time.ParseInLocation(cdrTimeLayout, answer, time.Local)
+		if err != nil {
+			return fmt.Errorf("некорректное время ответа %q: %w", answer, err)
+		}
+		cdr.Answer = &answered
+	}
+	if end != "" {
+		cdr.End, err = time.ParseInLocation(cdrTimeLayout, end, time.Local)
+		if err != nil {
+			return fmt.Errorf("некорректное время окончания %q: %w", end, err)
+		}
+	}
+
+	cdr.Duration, _ = strconv.Atoi(strings.TrimSpace(duration))
+	cdr.BillSec, _ = strconv.Atoi(strings.TrimSpace(billsec))
+	return nil
+}
+
+// followCSV читает Master.csv с начала и дописываемые строки в режиме tail -F.
+// Повторно прочитанные записи отсекаются уникальным индексом по uniqueid.
+func (s *CDRService) followCSV(ctx context.Context) error {
+	log.Printf("CDR: чтение %s", s.config.CSVPath)
+
+	var offset int64
+	var pending string
+
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		info, err := os.Stat(s.config.CSVPath)
+		if err == nil {
+			if info.Size() < offset {
+				// Файл ротирован или обрезан
+				offset = 0
+				pending = ""
+			}
+			if info.Size() > offset {
+				read, err := s.readCSVFrom(offset, &pending)
+				if err != nil {
+					log.Printf("CDR: ошибка чтения %s: %v", s.config.CSVPath, err)
+				}
+				offset += read
+			}
+		} else if !os.IsNotExist(err) {
+			log.Printf("CDR: ошибка доступа к %s: %v", s.config.CSVPath, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// readCSVFrom читает файл с позиции offset и обрабатывает завершённые строки.
+// Незавершённый хвост сохраняется в pending до следующего чтения.
+func (s *CDRService) readCSVFrom(offset int64, pending *string) (int64, error) {
+	file, err := os.Open(s.config.CSVPath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return 0, err
+	}
+
+	chunk := *pending + string(data)
+	lastNewline := strings.LastIndex(chunk, "\n")
+	if lastNewline < 0 {
+		*pending = chunk
+		return int64(len(data)), nil
+	}
+	*pending = chunk[lastNewline+1:]
+
+	for _, line := range strings.Split(chunk[:lastNewline], "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		reader := csv.NewReader(strings.NewReader(line))
+		reader.FieldsPerRecord = -1
+		row, err := reader.Read()
+		if err != nil {
+			log.Printf("CDR: пропущена строка %q: %v", line, err)
+			continue
+		}
+
+		cdr, err := ParseCDRRow(row)
+		if err != nil {
+			log.Printf("CDR: пропущена строка %q: %v", line, err)
+			continue
+		}
+		if err := s.Ingest(cdr); err != nil {
+			log.Printf("CDR: ошибка сохранения %s: %v", cdr.UniqueID, err)
+		}
+	}
+
+	return int64(len(data)), nil
+}
+
+// listenAMI подключается к AMI и принимает события Cdr, переподключаясь при обрыве
+func (s *CDRService) listenAMI(ctx context.Context) error {
+	for {
+		err := s.readAMI(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("CDR: соединение с AMI %s прервано: %v", s.config.AMIAddr, err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.config.PollInterval):
+		}
+	}
+}
+
+func (s *CDRService) readAMI(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.config.AMIAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Закрытие соединения прерывает чтение при отмене ctx
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	reader := bufio.NewReader(conn)
+
+	// Приветствие "Asterisk Call Manager/x.y"
+	if _, err := reader.ReadString('\n'); err != nil {
+		return err
+	}
+
+	login := fmt.Sprintf("Action: Login\r\nUsername: %s\r\nSecret: %s\r\nEvents: cdr\r\n\r\n",
+		s.config.AMIUser, s.config.AMISecret)
+	if _, err := conn.Write([]byte(login)); err != nil {
+		return err
+	}
+
+	for {
+		message, err := readAMIMessage(reader)
+		if err != nil {
+			return err
+		}
+
+		if message["Response"] == "Error" {
+			return fmt.Errorf("AMI: %s", message["Message"])
+		}
+		if message["Event"] != "Cdr" {
+			continue
+		}
+
+		cdr, err := parseAMICDR(message)
+		if err != nil {
+			log.Printf("CDR: пропущено событие AMI: %v", err)
+			continue
+		}
+		if err := s.Ingest(cdr); err != nil {
+			log.Printf("CDR: ошибка сохранения %s: %v", cdr.UniqueID, err)
+		}
+	}
+}
+
+// readAMIMessage читает одно сообщение AMI (строки "Key: Value" до пустой строки)
+func readAMIMessage(reader *bufio.Reader) (map[string]string, error) {
+	message := make(map[string]string)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if len(message) == 0 {
+				continue
+			}
+			return message, nil
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			message[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"testing"
+
+	"asterisk-manager/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCDRRow(t *testing.T) {
+	row := []string{
+		"", "1119", "947719", "DLPN_DialPlan_Zags_244842", "\"Лычкина\" <1119>",
+		"SIP/1119-00000001", "SIP/trunk_2-00000002", "Dial", "SIP/trunk_2/947719",
+		"2025-03-10 09:15:00", "2025-03-10 09:15:07", "2025-03-10 09:17:07",
+		"127", "120", "ANSWERED", "DOCUMENTATION", "1741580100.1", "",
+	}
+
+	cdr, err := ParseCDRRow(row)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1119", cdr.Src)
+	assert.Equal(t, "947719", cdr.Dst)
+	assert.Equal(t, "1741580100.1", cdr.UniqueID)
+	assert.Equal(t, domain.CDRDispositionAnswered, cdr.Disposition)
+	assert.Equal(t, 127, cdr.Duration)
+	assert.Equal(t, 120, cdr.BillSec)
+	assert.NotNil(t, cdr.Answer)
+	assert.Equal(t, 7.0, cdr.Answer.Sub(cdr.Start).Seconds())
+}
+
+func TestParseCDRRow_WithoutUniqueID(t *testing.T) {
+	row := []string{
+		"", "244842", "244842", "DID_trunk_2", "", "SIP/trunk_2-00000003", "",
+		"Hangup", "", "2025-03-10 10:00:00", "", "2025-03-10 10:00:30",
+		"30", "0", "NO ANSWER", "DOCUMENTATION",
+	}
+
+	first, err := ParseCDRRow(row)
+	assert.NoError(t, err)
+	assert.Nil(t, first.Answer)
+
+	second, err := ParseCDRRow(row)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, first.UniqueID)
+	assert.Equal(t, first.UniqueID, second.UniqueID)
+}
+
+func TestParseCDRRow_Invalid(t *testing.T) {
+	_, err := ParseCDRRow([]string{"", "1119"})
+	assert.Error(t, err)
+
+	row := make([]string, 16)
+	row[9] = "not a date"
+	_, err = ParseCDRRow(row)
+	assert.Error(t, err)
+}
+
+func TestClassifyCDR(t *testing.T) {
+	tests := []struct {
+		name     string
+		cdr      domain.CDR
+		expected domain.CDRDirection
+	}{
+		{
+			name:     "Internal call",
+			cdr:      domain.CDR{Src: "1119", Dst: "1058"},
+			expected: domain.CDRDirectionInternal,
+		},
+		{
+			name:     "Outbound call",
+			cdr:      domain.CDR{Src: "1119", Dst: "89001234567"},
+			expected: domain.CDRDirectionOutbound,
+		},
+		{
+			name:     "Inbound via DID context",
+			cdr:      domain.CDR{Src: "1119", Dst: "244842", DContext: "DID_trunk_2"},
+			expected: domain.CDRDirectionInbound,
+		},
+		{
+			name:     "Inbound from external number",
+			cdr:      domain.CDR{Src: "89001234567", Dst: "6008"},
+			expected: domain.CDRDirectionInbound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifyCDR(&tt.cdr))
+		})
+	}
+}
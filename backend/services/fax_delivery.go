@@ -0,0 +1,182 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+)
+
+// FaxDeliveryConfig настройки доставки факсов по почте
+type FaxDeliveryConfig struct {
+	SMTP          SMTPConfig
+	From          string // Шаблон адреса отправителя, %s - городской номер
+	FallbackTo    string
+	MaxAttempts   int
+	RetryInterval time.Duration
+	PollInterval  time.Duration
+	MaxAge        time.Duration
+}
+
+// FaxDeliveryConfigFromEnv читает настройки доставки факсов из переменных окружения
+func FaxDeliveryConfigFromEnv() FaxDeliveryConfig {
+	return FaxDeliveryConfig{
+		SMTP:          SMTPConfigFromEnv(),
+		From:          stringFromEnv("FAX_MAIL_FROM", "fax%s@nur.yanao.ru"),
+		FallbackTo:    stringFromEnv("FAX_MAIL_FALLBACK", "fax@nur.yanao.ru"),
+		MaxAttempts:   intFromEnv("FAX_DELIVERY_MAX_ATTEMPTS", 5),
+		RetryInterval: durationFromEnv("FAX_DELIVERY_RETRY", time.Minute),
+		PollInterval:  durationFromEnv("FAX_DELIVERY_INTERVAL", 30*time.Second),
+		MaxAge:        durationFromEnv("FAX_DELIVERY_MAX_AGE", 24*time.Hour),
+	}
+}
+
+// FaxDeliveryService рассылает принятые факсы по почте с повторами при ошибках
+type FaxDeliveryService struct {
+	repos  *repositories.Repos
+	mailer *Mailer
+	config FaxDeliveryConfig
+	now    func() time.Time
+}
+
+// NewFaxDeliveryService создаёт сервис доставки факсов
+func NewFaxDeliveryService(repos *repositories.Repos, config FaxDeliveryConfig) *FaxDeliveryService {
+	return &FaxDeliveryService{
+		repos:  repos,
+		mailer: NewMailer(config.SMTP),
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Config возвращает настройки сервиса
+func (s *FaxDeliveryService) Config() FaxDeliveryConfig {
+	return s.config
+}
+
+// Enqueue ставит факс в очередь на отправку получателям его ринг-группы
+func (s *FaxDeliveryService) Enqueue(fax *domain.Fax) (*domain.FaxDelivery, error) {
+	recipients, err := s.recipientsFor(fax)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &domain.FaxDelivery{
+		FaxID:         fax.ID,
+		Recipients:    strings.Join(recipients, ","),
+		Status:        domain.FaxDeliveryPending,
+		NextAttemptAt: s.now(),
+	}
+	if err := s.repos.Create(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// EnqueueIfRecent ставит в очередь только недавно принятые факсы,
+// чтобы первое сканирование старого каталога не разослало архив
+func (s *FaxDeliveryService) EnqueueIfRecent(fax *domain.Fax) error {
+	if s.now().Sub(fax.ReceivedAt) > s.config.MaxAge {
+		return nil
+	}
+	_, err := s.Enqueue(fax)
+	return err
+}
+
+// recipientsFor определяет получателей: список ринг-группы, затем email сотрудников
+// ринг-группы и резервный адрес, как раньше делал диалплан с sendEmail.pl
+func (s *FaxDeliveryService) recipientsFor(fax *domain.Fax) ([]string, error) {
+	if fax.RingGroup != nil {
+		configured, err := s.repos.FindFaxRecipients(*fax.RingGroup)
+		if err != nil {
+			return nil, err
+		}
+		if len(configured) > 0 {
+			emails := make([]string, 0, len(configured))
+			for _, recipient := range configured {
+				emails = append(emails, recipient.Email)
+			}
+			return emails, nil
+		}
+
+		emails, err := s.repos.FindRingGroupEmails(*fax.RingGroup)
+		if err != nil {
+			return nil, err
+		}
+		if len(emails) > 0 {
+			return emails, nil
+		}
+	}
+
+	return []string{s.config.FallbackTo}, nil
+}
+
+// ProcessPending отправляет все доставки, время которых подошло
+func (s *FaxDeliveryService) ProcessPending() error {
+	deliveries, err := s.repos.FindDueFaxDeliveries(s.now())
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		if err := s.attempt(&deliveries[i]); err != nil {
+			log.Printf("Fax delivery %d: %v", deliveries[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// attempt выполняет одну попытку отправки и сохраняет её результат.
+// Интервал между повторами удваивается, после MaxAttempts доставка считается неудачной.
+func (s *FaxDeliveryService) attempt(delivery *domain.FaxDelivery) error {
+	sendErr := s.send(delivery)
+
+	delivery.Attempts++
+	now := s.now()
+	if sendErr == nil {
+		delivery.Status = domain.FaxDeliverySent
+		delivery.SentAt = &now
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = sendErr.Error()
+		if delivery.Attempts >= s.config.MaxAttempts {
+			delivery.Status = domain.FaxDeliveryFailed
+		} else {
+			delivery.NextAttemptAt = now.Add(s.config.RetryInterval << (delivery.Attempts - 1))
+		}
+	}
+
+	if err := s.repos.Save(delivery); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+func (s *FaxDeliveryService) send(delivery *domain.FaxDelivery) error {
+	var fax domain.Fax
+	if err := s.repos.FindByID(&fax, delivery.FaxID); err != nil {
+		return fmt.Errorf("факс %d не найден: %w", delivery.FaxID, err)
+	}
+
+	data, err := os.ReadFile(fax.Path)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(&Mail{
+		From:    fmt.Sprintf(s.config.From, fax.CityNumber),
+		To:      strings.Split(delivery.Recipients, ","),
+		Subject: fmt.Sprintf("Incoming FAX %s", fax.Sender),
+		Body: fmt.Sprintf("Вам пришел факс с номера %s в %s. Факс во вложении.",
+			fax.Sender, fax.ReceivedAt.Format("15:04:05")),
+		Attachments: []Attachment{{
+			Name:        fax.FileName,
+			ContentType: "application/pdf",
+			Data:        data,
+		}},
+	})
+}
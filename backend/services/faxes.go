@@ -0,0 +1,222 @@
+package services
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// faxNamePattern имя файла из голосового меню (generateRingGroups):
+// <city>-<YYYYMMDD>-<HH_MM_SS>-from-<callerid>.pdf
+var faxNamePattern = regexp.MustCompile(`^(\d+)-(\d{8}-\d{2}_\d{2}_\d{2})-from-(.*)\.pdf$`)
+
+// FaxConfig настройки индекса факсов
+type FaxConfig struct {
+	Dir          string
+	ScanInterval time.Duration
+	HookToken    string
+}
+
+// FaxConfigFromEnv читает настройки индекса факсов из переменных окружения
+func FaxConfigFromEnv() FaxConfig {
+	return FaxConfig{
+		Dir:          stringFromEnv("FAX_DIR", "/var/calls/FAX"),
+		ScanInterval: durationFromEnv("FAX_SCAN_INTERVAL", time.Minute),
+		HookToken:    os.Getenv("FAX_HOOK_TOKEN"),
+	}
+}
+
+// ErrFaxOutsideDir файл факса находится вне каталога факсов
+var ErrFaxOutsideDir = errors.New("fax file is outside of fax directory")
+
+// FaxService индексирует каталог принятых факсов
+type FaxService struct {
+	repos    *repositories.Repos
+	config   FaxConfig
+	delivery *FaxDeliveryService
+}
+
+// NewFaxService создаёт сервис индекса факсов; delivery может быть nil,
+// тогда новые факсы только индексируются без рассылки
+func NewFaxService(repos *repositories.Repos, config FaxConfig, delivery *FaxDeliveryService) *FaxService {
+	return &FaxService{
+		repos:    repos,
+		config:   config,
+		delivery: delivery,
+	}
+}
+
+// Config возвращает настройки сервиса
+func (s *FaxService) Config() FaxConfig {
+	return s.config
+}
+
+// Scan обходит каталог факсов, добавляет новые PDF в индекс и удаляет пропавшие
+func (s *FaxService) Scan() (*ScanResult, error) {
+	if _, err := os.Stat(s.config.Dir); err != nil {
+		return nil, errors.Wrapf(err, "fax directory %s is not available", s.config.Dir)
+	}
+
+	known, err := s.repos.FindFaxPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ScanResult{}
+	seen := make(map[string]bool, len(known))
+
+	err = filepath.WalkDir(s.config.Dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			log.Printf("Faxes: пропущен %s: %v", path, err)
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		fax, ok := ParseFaxName(entry.Name())
+		if !ok {
+			return nil
+		}
+
+		seen[path] = true
+		if _, exists := known[path]; exists {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			result.Skipped++
+			return nil
+		}
+		fax.Path = path
+		fax.Size = info.Size()
+
+		if err := s.link(fax); err != nil {
+			return err
+		}
+		if err := s.repos.Create(fax); err != nil {
+			return errors.Wrapf(err, "failed to index %s", path)
+		}
+		result.Added++
+
+		if s.delivery != nil {
+			if err := s.delivery.EnqueueIfRecent(fax); err != nil {
+				log.Printf("Faxes: не удалось поставить %s в очередь рассылки: %v", path, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []uint
+	for path, id := range known {
+		if !seen[path] {
+			missing = append(missing, id)
+		}
+	}
+	if err := s.repos.DeleteFaxesByIDs(missing); err != nil {
+		return nil, err
+	}
+	result.Removed = len(missing)
+
+	return result, nil
+}
+
+// Ingest индексирует факс, о котором сообщил Asterisk, и ставит его в очередь рассылки
+func (s *FaxService) Ingest(path string) (*domain.Fax, error) {
+	path = filepath.Clean(path)
+	rel, err := filepath.Rel(s.config.Dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, ErrFaxOutsideDir
+	}
+
+	var existing domain.Fax
+	err = s.repos.FindOne(&existing, "path = ?", path)
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	fax, ok := ParseFaxName(filepath.Base(path))
+	if !ok {
+		return nil, errors.Errorf("unexpected fax file name: %s", filepath.Base(path))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	fax.Path = path
+	fax.Size = info.Size()
+
+	if err := s.link(fax); err != nil {
+		return nil, err
+	}
+	if err := s.repos.Create(fax); err != nil {
+		// Файл мог успеть проиндексировать сканер
+		if findErr := s.repos.FindOne(&existing, "path = ?", path); findErr == nil {
+			return &existing, nil
+		}
+		return nil, err
+	}
+
+	if s.delivery != nil {
+		if _, err := s.delivery.Enqueue(fax); err != nil {
+			return nil, err
+		}
+	}
+
+	return fax, nil
+}
+
+// link связывает факс с ринг-группой и локацией по городскому номеру
+func (s *FaxService) link(fax *domain.Fax) error {
+	var profile domain.Profile
+	err := s.repos.FindProfileByCityNumber(&profile, fax.CityNumber)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to link fax to profile")
+	}
+
+	fax.ProfileID = &profile.ID
+	fax.LocationID = profile.LocationID
+	fax.RingGroup = profile.RingGroup
+	return nil
+}
+
+// ParseFaxName разбирает имя файла факса в городской номер, время и отправителя
+func ParseFaxName(name string) (*domain.Fax, bool) {
+	match := faxNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return nil, false
+	}
+
+	receivedAt, err := time.ParseInLocation("20060102-15_04_05", match[2], time.Local)
+	if err != nil {
+		return nil, false
+	}
+
+	return &domain.Fax{
+		FileName:   name,
+		CityNumber: match[1],
+		Sender:     match[3],
+		ReceivedAt: receivedAt,
+	}, true
+}
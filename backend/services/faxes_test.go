@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFaxName(t *testing.T) {
+	fax, ok := ParseFaxName("244842-20250310-09_15_00-from-89001234567.pdf")
+
+	assert.True(t, ok)
+	assert.Equal(t, "244842", fax.CityNumber)
+	assert.Equal(t, "89001234567", fax.Sender)
+	assert.True(t, time.Date(2025, 3, 10, 9, 15, 0, 0, time.Local).Equal(fax.ReceivedAt))
+
+	// Отправитель может быть скрыт
+	fax, ok = ParseFaxName("947947-20250310-23_59_59-from-.pdf")
+	assert.True(t, ok)
+	assert.Equal(t, "", fax.Sender)
+
+	// Промежуточный TIFF не индексируется
+	_, ok = ParseFaxName("244842-20250310-09_15_00-from-89001234567.tif")
+	assert.False(t, ok)
+}
+
+// startSMTPStub поднимает минимальный SMTP-сервер и возвращает его адрес
+// и канал с полученными письмами (DATA)
+func startSMTPStub(t *testing.T) (string, string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+		reply("220 localhost ESMTP stub")
+
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "DATA"):
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				messages <- data.String()
+				reply("250 OK")
+			case strings.HasPrefix(command, "QUIT"):
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return host, port, messages
+}
+
+func TestMailerSend(t *testing.T) {
+	host, port, messages := startSMTPStub(t)
+	mailer := NewMailer(SMTPConfig{Host: host, Port: port})
+
+	err := mailer.Send(&Mail{
+		From:    "fax244842@nur.yanao.ru",
+		To:      []string{"zags@nur.yanao.ru"},
+		Subject: "Incoming FAX 89001234567",
+		Body:    "Вам пришел факс",
+		Attachments: []Attachment{{
+			Name:        "244842-20250310-09_15_00-from-89001234567.pdf",
+			ContentType: "application/pdf",
+			Data:        []byte("%PDF-1.4"),
+		}},
+	})
+	assert.NoError(t, err)
+
+	message := <-messages
+	assert.Contains(t, message, "To: zags@nur.yanao.ru")
+	assert.Contains(t, message, "multipart/mixed")
+	assert.Contains(t, message, `filename="244842-20250310-09_15_00-from-89001234567.pdf"`)
+	assert.Contains(t, message, base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")))
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+
+	"github.com/pkg/errors"
+)
+
+// GeneratedFiles генерирует конфигурацию из БД во временный каталог и возвращает
+// содержимое файлов по путям относительно каталога генерации. Рабочий каталог не затрагивается.
+func GeneratedFiles(repos *repositories.Repos) (map[string][]byte, error) {
+	dir, err := os.MkdirTemp("", "asterisk-impact-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(dir)
+
+	g := NewAsteriskGeneratorFromEnv(dir)
+	if err := g.LoadFromDatabase(repos); err != nil {
+		return nil, err
+	}
+	return generateFiles(g)
+}
+
+// generateFiles запускает генерацию в g.OutputDir и читает результат
+func generateFiles(g *AsteriskGenerator) (map[string][]byte, error) {
+	if err := g.Generate(); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(g.OutputDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(g.OutputDir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read generated files")
+	}
+	return files, nil
+}
+
+// DiffGeneratedFiles сравнивает два результата генерации и возвращает изменения, отсортированные по пути
+func DiffGeneratedFiles(before, after map[string][]byte) []domain.FileChange {
+	changes := []domain.FileChange{}
+	for path, content := range before {
+		next, ok := after[path]
+		switch {
+		case !ok:
+			changes = append(changes, domain.FileChange{Path: path, Change: domain.FileRemoved})
+		case !bytes.Equal(content, next):
+			changes = append(changes, domain.FileChange{Path: path, Change: domain.FileModified})
+		}
+	}
+	for path := range after {
+		if _, ok := before[path]; !ok {
+			changes = append(changes, domain.FileChange{Path: path, Change: domain.FileAdded})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
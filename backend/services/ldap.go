@@ -0,0 +1,191 @@
+package services
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrLDAPInvalidCredentials пользователь не найден в каталоге или неверный пароль
+	ErrLDAPInvalidCredentials = errors.New("invalid directory credentials")
+	// ErrLDAPNotAllowed пользователь не входит ни в одну из разрешённых групп
+	ErrLDAPNotAllowed = errors.New("directory user is not in an allowed group")
+)
+
+// LDAPConfig настройки аутентификации через LDAP / Active Directory
+type LDAPConfig struct {
+	URL                string // ldap://dc.nur.local:389 или ldaps://dc.nur.local:636
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string // сервисная учётная запись для поиска пользователей
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // %s заменяется на экранированное имя пользователя
+	AdminGroups        []string
+	UserGroups         []string // пусто - войти может любой найденный пользователь
+	Timeout            time.Duration
+}
+
+// LDAPConfigFromEnv читает настройки LDAP из переменных окружения.
+// Группы перечисляются через ";", так как DN содержат запятые.
+func LDAPConfigFromEnv() LDAPConfig {
+	return LDAPConfig{
+		URL:                os.Getenv("LDAP_URL"),
+		StartTLS:           os.Getenv("LDAP_STARTTLS") == "true",
+		InsecureSkipVerify: os.Getenv("LDAP_INSECURE_SKIP_VERIFY") == "true",
+		BindDN:             os.Getenv("LDAP_BIND_DN"),
+		BindPassword:       os.Getenv("LDAP_BIND_PASSWORD"),
+		BaseDN:             os.Getenv("LDAP_BASE_DN"),
+		UserFilter:         stringFromEnv("LDAP_USER_FILTER", "(&(objectClass=user)(sAMAccountName=%s))"),
+		AdminGroups:        splitGroups(os.Getenv("LDAP_ADMIN_GROUPS")),
+		UserGroups:         splitGroups(os.Getenv("LDAP_USER_GROUPS")),
+		Timeout:            durationFromEnv("LDAP_TIMEOUT", 5*time.Second),
+	}
+}
+
+// Enabled проверяет, настроен ли LDAP
+func (c LDAPConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+func splitGroups(value string) []string {
+	var groups []string
+	for _, group := range strings.Split(value, ";") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// LDAPIdentity пользователь каталога после успешной аутентификации
+type LDAPIdentity struct {
+	Username string
+	DN       string
+	Groups   []string
+}
+
+// LDAPAuthenticator проверяет пароли в каталоге: bind сервисной учётной записью,
+// поиск пользователя, bind от имени пользователя
+type LDAPAuthenticator struct {
+	config LDAPConfig
+}
+
+// NewLDAPAuthenticator создаёт аутентификатор LDAP
+func NewLDAPAuthenticator(config LDAPConfig) *LDAPAuthenticator {
+	return &LDAPAuthenticator{config: config}
+}
+
+// Authenticate проверяет имя и пароль пользователя в каталоге
+func (a *LDAPAuthenticator) Authenticate(username, password string) (*LDAPIdentity, error) {
+	// Пустой пароль в LDAP означает анонимный bind, который всегда успешен
+	if username == "" || password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.config.BindDN != "" {
+		if err := conn.Bind(a.config.BindDN, a.config.BindPassword); err != nil {
+			return nil, errors.Wrap(err, "LDAP service bind failed")
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.config.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(a.config.Timeout.Seconds()), false,
+		fmt.Sprintf(a.config.UserFilter, ldap.EscapeFilter(username)),
+		[]string{"dn", "memberOf"},
+		nil,
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "LDAP user search failed")
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		return nil, errors.Wrap(err, "LDAP user bind failed")
+	}
+
+	return &LDAPIdentity{
+		Username: username,
+		DN:       entry.DN,
+		Groups:   entry.GetAttributeValues("memberOf"),
+	}, nil
+}
+
+// RoleFor определяет роль пользователя по группам каталога
+func (a *LDAPAuthenticator) RoleFor(identity *LDAPIdentity) (domain.UserRole, error) {
+	if inGroups(identity.Groups, a.config.AdminGroups) {
+		return domain.UserRoleAdmin, nil
+	}
+	if len(a.config.UserGroups) == 0 || inGroups(identity.Groups, a.config.UserGroups) {
+		return domain.UserRoleUser, nil
+	}
+	return "", ErrLDAPNotAllowed
+}
+
+func (a *LDAPAuthenticator) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: a.config.InsecureSkipVerify}
+
+	conn, err := ldap.DialURL(a.config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: a.config.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to %s", a.config.URL)
+	}
+	conn.SetTimeout(a.config.Timeout)
+
+	if a.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "LDAP StartTLS failed")
+		}
+	}
+	return conn, nil
+}
+
+// inGroups проверяет членство: группа задаётся полным DN или именем (CN)
+func inGroups(memberOf, groups []string) bool {
+	for _, dn := range memberOf {
+		for _, group := range groups {
+			if strings.EqualFold(dn, group) || strings.EqualFold(groupName(dn), group) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// groupName возвращает CN группы из её DN
+func groupName(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return ""
+	}
+	attr := parsed.RDNs[0].Attributes[0]
+	if !strings.EqualFold(attr.Type, "cn") {
+		return ""
+	}
+	return attr.Value
+}
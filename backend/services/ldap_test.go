@@ -0,0 +1,92 @@
+package services
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLDAPRoleFor(t *testing.T) {
+	auth := NewLDAPAuthenticator(LDAPConfig{
+		AdminGroups: []string{"cn=SipAdmin-Admins,ou=groups,dc=nur,dc=local"},
+		UserGroups:  []string{"sipadmin-users"},
+	})
+
+	tests := []struct {
+		name   string
+		groups []string
+		role   domain.UserRole
+		err    error
+	}{
+		{"Admin by DN", []string{"cn=sipadmin-admins,ou=groups,dc=nur,dc=local"}, domain.UserRoleAdmin, nil},
+		{"User by CN", []string{"CN=SipAdmin-Users,OU=Groups,DC=nur,DC=local"}, domain.UserRoleUser, nil},
+		{"Not in allowed groups", []string{"cn=accounting,ou=groups,dc=nur,dc=local"}, "", ErrLDAPNotAllowed},
+		{"No groups", nil, "", ErrLDAPNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := auth.RoleFor(&LDAPIdentity{Groups: tt.groups})
+			assert.Equal(t, tt.role, role)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+
+	// Без списка пользовательских групп войти может любой пользователь каталога
+	open := NewLDAPAuthenticator(LDAPConfig{})
+	role, err := open.RoleFor(&LDAPIdentity{})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.UserRoleUser, role)
+}
+
+// TestLDAPAuthenticate проверяет вход на тестовом каталоге docs/ldap/bootstrap.ldif (make test-ldap)
+func TestLDAPAuthenticate(t *testing.T) {
+	url := os.Getenv("LDAP_TEST_URL")
+	if url == "" {
+		t.Skip("LDAP_TEST_URL is not set")
+	}
+
+	auth := NewLDAPAuthenticator(LDAPConfig{
+		URL:          url,
+		BindDN:       "cn=admin,dc=nur,dc=local",
+		BindPassword: "admin",
+		BaseDN:       "dc=nur,dc=local",
+		UserFilter:   "(&(objectClass=inetOrgPerson)(uid=%s))",
+		AdminGroups:  []string{"sipadmin-admins"},
+		UserGroups:   []string{"sipadmin-users"},
+		Timeout:      5 * time.Second,
+	})
+
+	identity, err := auth.Authenticate("ivanov", "Passw0rd")
+	require.NoError(t, err)
+	assert.Equal(t, "uid=ivanov,ou=people,dc=nur,dc=local", identity.DN)
+	role, err := auth.RoleFor(identity)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserRoleAdmin, role)
+
+	identity, err = auth.Authenticate("petrov", "Passw0rd")
+	require.NoError(t, err)
+	role, err = auth.RoleFor(identity)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserRoleUser, role)
+
+	identity, err = auth.Authenticate("sidorov", "Passw0rd")
+	require.NoError(t, err)
+	_, err = auth.RoleFor(identity)
+	assert.Equal(t, ErrLDAPNotAllowed, err)
+
+	_, err = auth.Authenticate("ivanov", "wrong")
+	assert.Equal(t, ErrLDAPInvalidCredentials, err)
+
+	_, err = auth.Authenticate("nobody", "Passw0rd")
+	assert.Equal(t, ErrLDAPInvalidCredentials, err)
+
+	// Фильтр экранируется: подстановка не должна находить чужие записи
+	_, err = auth.Authenticate("*", "Passw0rd")
+	assert.Equal(t, ErrLDAPInvalidCredentials, err)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+)
+
+// LoginGuardConfig настройки защиты входа от подбора пароля
+type LoginGuardConfig struct {
+	MaxUsernameFailures int           // неудач подряд до блокировки имени пользователя
+	MaxIPFailures       int           // неудач подряд до блокировки IP
+	Window              time.Duration // через сколько без неудач счётчик сбрасывается
+	BaseLockout         time.Duration // первая блокировка, дальше удваивается
+	MaxLockout          time.Duration
+	Retention           time.Duration // сколько хранить журнал попыток
+}
+
+// LoginGuardConfigFromEnv читает настройки защиты входа из переменных окружения
+func LoginGuardConfigFromEnv() LoginGuardConfig {
+	return LoginGuardConfig{
+		MaxUsernameFailures: intFromEnv("LOGIN_MAX_FAILURES", 5),
+		MaxIPFailures:       intFromEnv("LOGIN_MAX_FAILURES_PER_IP", 20),
+		Window:              durationFromEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		BaseLockout:         durationFromEnv("LOGIN_LOCKOUT", time.Minute),
+		MaxLockout:          durationFromEnv("LOGIN_LOCKOUT_MAX", time.Hour),
+		Retention:           durationFromEnv("LOGIN_ATTEMPTS_RETENTION", 90*24*time.Hour),
+	}
+}
+
+// LoginGuard считает неудачные входы по имени пользователя и по IP и временно блокирует вход
+type LoginGuard struct {
+	repos  *repositories.Repos
+	config LoginGuardConfig
+	now    func() time.Time
+}
+
+// NewLoginGuard создаёт защиту входа
+func NewLoginGuard(repos *repositories.Repos, config LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{
+		repos:  repos,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Check возвращает, сколько ещё ждать до следующей попытки; 0 - вход разрешён
+func (g *LoginGuard) Check(username, ip string) (time.Duration, error) {
+	now := g.now()
+	var wait time.Duration
+
+	for kind, key := range map[domain.LockoutKind]string{
+		domain.LockoutByUsername: normalizeUsername(username),
+		domain.LockoutByIP:       ip,
+	} {
+		lockouts, err := g.repos.FindLockouts(kind, key)
+		if err != nil {
+			return 0, err
+		}
+		for _, lockout := range lockouts {
+			if lockout.LockedUntil != nil && lockout.LockedUntil.After(now) {
+				if left := lockout.LockedUntil.Sub(now); left > wait {
+					wait = left
+				}
+			}
+		}
+	}
+
+	if wait > 0 {
+		g.record(username, ip, false, "locked")
+	}
+	return wait, nil
+}
+
+// RecordFailure записывает неудачную попытку и при превышении порога блокирует вход
+func (g *LoginGuard) RecordFailure(username, ip, reason string) error {
+	g.record(username, ip, false, reason)
+
+	if err := g.increment(domain.LockoutByUsername, normalizeUsername(username), g.config.MaxUsernameFailures); err != nil {
+		return err
+	}
+	return g.increment(domain.LockoutByIP, ip, g.config.MaxIPFailures)
+}
+
+// RecordSuccess записывает успешный вход и сбрасывает счётчик пользователя.
+// Счётчик IP не сбрасывается, чтобы вход под своей учётной записью не обнулял подбор чужих.
+func (g *LoginGuard) RecordSuccess(username, ip string) error {
+	g.record(username, ip, true, "")
+	return g.repos.ResetLockout(domain.LockoutByUsername, normalizeUsername(username))
+}
+
+// Cleanup удаляет устаревшие записи журнала и счётчики
+func (g *LoginGuard) Cleanup() error {
+	return g.repos.DeleteLoginAttemptsBefore(g.now().Add(-g.config.Retention))
+}
+
+// Config возвращает настройки защиты входа
+func (g *LoginGuard) Config() LoginGuardConfig {
+	return g.config
+}
+
+func (g *LoginGuard) increment(kind domain.LockoutKind, key string, max int) error {
+	now := g.now()
+	lockout, err := g.repos.IncrementLockout(kind, key, now, g.config.Window)
+	if err != nil {
+		return err
+	}
+	if lockout.Failures < max {
+		return nil
+	}
+	return g.repos.LockUntil(lockout.ID, now.Add(g.LockoutDuration(lockout.Failures-max)))
+}
+
+// LockoutDuration длительность блокировки после extra неудач сверх порога:
+// BaseLockout, затем удвоение на каждую неудачу, но не больше MaxLockout
+func (g *LoginGuard) LockoutDuration(extra int) time.Duration {
+	duration := g.config.BaseLockout
+	for i := 0; i < extra && duration < g.config.MaxLockout; i++ {
+		duration *= 2
+	}
+	if duration > g.config.MaxLockout {
+		duration = g.config.MaxLockout
+	}
+	return duration
+}
+
+// record пишет попытку в журнал; ошибка записи не должна мешать входу
+func (g *LoginGuard) record(username, ip string, success bool, reason string) {
+	attempt := domain.LoginAttempt{
+		Username:  normalizeUsername(username),
+		IP:        ip,
+		Success:   success,
+		Reason:    reason,
+		CreatedAt: g.now(),
+	}
+	if err := g.repos.Create(&attempt); err != nil {
+		log.Printf("Login guard: не удалось записать попытку входа %s: %v", attempt.Username, err)
+	}
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// SMTPConfig настройки SMTP-сервера
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// SMTPConfigFromEnv читает настройки SMTP из переменных окружения
+func SMTPConfigFromEnv() SMTPConfig {
+	return SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     stringFromEnv("SMTP_PORT", "25"),
+		Username: os.Getenv("SMTP_USER"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	}
+}
+
+// Enabled проверяет, настроен ли SMTP
+func (c SMTPConfig) Enabled() bool {
+	return c.Host != ""
+}
+
+// Attachment вложение письма
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Mail письмо для отправки
+type Mail struct {
+	From        string
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Mailer отправляет письма через SMTP
+type Mailer struct {
+	config SMTPConfig
+}
+
+// NewMailer создаёт отправителя писем
+func NewMailer(config SMTPConfig) *Mailer {
+	return &Mailer{config: config}
+}
+
+// Send отправляет письмо; STARTTLS используется, если сервер его предлагает
+func (m *Mailer) Send(mail *Mail) error {
+	message, err := buildMessage(mail)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+	return smtp.SendMail(addr, auth, mail.From, mail.To, message)
+}
+
+// buildMessage собирает MIME-письмо с текстом и вложениями
+func buildMessage(mail *Mail) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	headers := []string{
+		"From: " + mail.From,
+		"To: " + strings.Join(mail.To, ", "),
+		"Subject: " + mime.BEncoding.Encode("UTF-8", mail.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		fmt.Sprintf("Content-Type: multipart/mixed; boundary=%q", writer.Boundary()),
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	body, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64(body, []byte(mail.Body)); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range mail.Attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {fmt.Sprintf("%s; name=%q", attachment.ContentType, attachment.Name)},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", attachment.Name)},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, attachment.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 пишет данные в base64 строками по 76 символов (RFC 2045)
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := w.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := w.Write([]byte(encoded + "\r\n"))
+	return err
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+// mfaAudience аудитория промежуточного токена между вводом пароля и кода
+const mfaAudience = "mfa"
+
+// recoveryCodeCount сколько кодов восстановления выдаётся за раз
+const recoveryCodeCount = 10
+
+var (
+	// ErrInvalidMFAToken промежуточный токен входа неизвестен или истёк
+	ErrInvalidMFAToken = errors.New("invalid or expired MFA token")
+	// ErrInvalidMFACode неверный или уже использованный код TOTP / код восстановления
+	ErrInvalidMFACode = errors.New("invalid two-factor code")
+	// ErrTOTPAlreadyEnabled второй фактор уже подключён
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTOTPNotSetUp подключение не начато или второй фактор не подключён
+	ErrTOTPNotSetUp = errors.New("two-factor authentication is not set up")
+	// ErrTOTPRequired второй фактор обязателен для роли пользователя и не может быть отключён
+	ErrTOTPRequired = errors.New("two-factor authentication is required for this role")
+	// ErrTOTPExternal второй фактор пользователей OIDC проверяет провайдер
+	ErrTOTPExternal = errors.New("two-factor authentication of OIDC users is managed by the identity provider")
+)
+
+// MFAPolicy настройки двухфакторной аутентификации
+type MFAPolicy struct {
+	Issuer        string            // имя сервиса в приложении-аутентификаторе
+	RequiredRoles []domain.UserRole // роли, для которых второй фактор обязателен
+	TokenTTL      time.Duration     // время на ввод кода после пароля
+}
+
+// MFAPolicyFromEnv читает настройки из переменных окружения
+func MFAPolicyFromEnv() MFAPolicy {
+	var roles []domain.UserRole
+	for _, role := range strings.Split(stringFromEnv("MFA_REQUIRED_ROLES", string(domain.UserRoleAdmin)), ",") {
+		if role = strings.TrimSpace(role); role != "" && role != "none" {
+			roles = append(roles, domain.UserRole(role))
+		}
+	}
+
+	return MFAPolicy{
+		Issuer:        stringFromEnv("TOTP_ISSUER", "Asterisk Manager"),
+		RequiredRoles: roles,
+		TokenTTL:      durationFromEnv("MFA_TOKEN_TTL", 5*time.Minute),
+	}
+}
+
+// Required проверяет, обязателен ли второй фактор для пользователя.
+// Пользователи OIDC проходят второй фактор у провайдера.
+func (p MFAPolicy) Required(user *domain.User) bool {
+	if user.AuthSource == domain.AuthSourceOIDC {
+		return false
+	}
+	for _, role := range p.RequiredRoles {
+		if role == user.Role {
+			return true
+		}
+	}
+	return false
+}
+
+// TOTPSetup данные для подключения приложения-аутентификатора
+type TOTPSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QRCode string `json:"qrCode"`
+}
+
+// MFAPolicy возвращает настройки двухфакторной аутентификации
+func (s *AuthService) MFAPolicy() MFAPolicy {
+	return s.mfaPolicy
+}
+
+// MFAEnrollmentRequired проверяет, должен ли пользователь подключить второй фактор,
+// прежде чем работать с API
+func (s *AuthService) MFAEnrollmentRequired(user *domain.User) bool {
+	return !user.TOTPEnabled && s.mfaPolicy.Required(user)
+}
+
+// IssueMFAToken выдаёт короткий токен, подтверждающий верный пароль. Он не привязан
+// к сессии, поэтому не годится как access-токен, и обменивается на сессию в VerifyMFA.
+func (s *AuthService) IssueMFAToken(user *domain.User) (string, error) {
+	now := s.now()
+	claims := JWTClaims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.mfaPolicy.TokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Subject:   user.Username,
+			Audience:  jwt.ClaimStrings{mfaAudience},
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.secretKey)
+}
+
+// ParseMFAToken проверяет промежуточный токен и возвращает его claims
+func (s *AuthService) ParseMFAToken(tokenString string) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return s.secretKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(mfaAudience))
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidMFAToken
+	}
+	return claims, nil
+}
+
+// VerifyMFA завершает вход: проверяет код TOTP или код восстановления пользователя
+// из промежуточного токена
+func (s *AuthService) VerifyMFA(claims *JWTClaims, code, recoveryCode string) (*domain.User, error) {
+	var user domain.User
+	if err := s.repos.FindByID(&user, claims.UserID); err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	if !user.IsActive {
+		return nil, ErrUserDisabled
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if recoveryCode != "" {
+		used, err := s.repos.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(recoveryCode)), s.now())
+		if err != nil {
+			return nil, err
+		}
+		if !used {
+			return nil, ErrInvalidMFACode
+		}
+		return &user, nil
+	}
+
+	if err := s.CheckTOTP(&user, code); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// BeginTOTPSetup генерирует новый секрет; второй фактор включается после подтверждения кодом
+func (s *AuthService) BeginTOTPSetup(user *domain.User) (*TOTPSetup, error) {
+	if user.AuthSource == domain.AuthSourceOIDC {
+		return nil, ErrTOTPExternal
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	uri := TOTPProvisioningURI(s.mfaPolicy.Issuer, user.Username, secret)
+	qrCode, err := TOTPQRCode(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := s.repos.Save(user); err != nil {
+		return nil, err
+	}
+
+	return &TOTPSetup{Secret: secret, URI: uri, QRCode: qrCode}, nil
+}
+
+// EnableTOTP подтверждает подключение кодом из приложения и выдаёт коды восстановления
+func (s *AuthService) EnableTOTP(user *domain.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotSetUp
+	}
+
+	step, ok := ValidateTOTP(user.TOTPSecret, code, s.now(), user.TOTPLastStep)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	if err := s.repos.Save(user); err != nil {
+		return nil, err
+	}
+	return s.RegenerateRecoveryCodes(user)
+}
+
+// DisableTOTP отключает второй фактор по коду TOTP или коду восстановления
+func (s *AuthService) DisableTOTP(user *domain.User, code, recoveryCode string) error {
+	if !user.TOTPEnabled {
+		return ErrTOTPNotSetUp
+	}
+	if s.mfaPolicy.Required(user) {
+		return ErrTOTPRequired
+	}
+
+	if recoveryCode != "" {
+		used, err := s.repos.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(recoveryCode)), s.now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+	} else if err := s.CheckTOTP(user, code); err != nil {
+		return err
+	}
+
+	return s.ResetTOTP(user)
+}
+
+// ResetTOTP отключает второй фактор без проверки кода (сброс администратором)
+func (s *AuthService) ResetTOTP(user *domain.User) error {
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	if err := s.repos.Save(user); err != nil {
+		return err
+	}
+	return s.repos.DeleteRecoveryCodes(user.ID)
+}
+
+// RegenerateRecoveryCodes выдаёт новые коды восстановления взамен старых
+func (s *AuthService) RegenerateRecoveryCodes(user *domain.User) ([]string, error) {
+	if !user.TOTPEnabled {
+		return nil, ErrTOTPNotSetUp
+	}
+
+	codes, err := GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, hashToken(code))
+	}
+	if err := s.repos.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CheckTOTP проверяет код и запоминает его шаг, чтобы код нельзя было предъявить повторно
+func (s *AuthService) CheckTOTP(user *domain.User, code string) error {
+	step, ok := ValidateTOTP(user.TOTPSecret, code, s.now(), user.TOTPLastStep)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	advanced, err := s.repos.AdvanceTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidMFACode
+	}
+	user.TOTPLastStep = step
+	return nil
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrOIDCInvalidState неизвестный или просроченный state / код входа
+	ErrOIDCInvalidState = errors.New("invalid or expired OIDC login state")
+	// ErrOIDCNotAllowed у пользователя нет ролей, дающих доступ
+	ErrOIDCNotAllowed = errors.New("OIDC user has no allowed role")
+)
+
+// oidcStateTTL время на прохождение входа у провайдера и обмен кода входа
+const oidcStateTTL = 10 * time.Minute
+
+// OIDCConfig настройки входа через OpenID Connect (например, Keycloak)
+type OIDCConfig struct {
+	Issuer        string // https://sso.nur.local/realms/nur
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string // адрес /api/auth/oidc/callback, зарегистрированный у провайдера
+	FrontendURL   string // куда вернуть браузер после входа
+	Scopes        []string
+	UsernameClaim string
+	RoleClaim     string // путь к claim с ролями/группами, например realm_access.roles
+	AdminValues   []string
+	UserValues    []string // пусто - войти может любой пользователь провайдера
+}
+
+// OIDCConfigFromEnv читает настройки OIDC из переменных окружения
+func OIDCConfigFromEnv() OIDCConfig {
+	return OIDCConfig{
+		Issuer:        os.Getenv("OIDC_ISSUER"),
+		ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		FrontendURL:   stringFromEnv("OIDC_FRONTEND_URL", "/login/callback"),
+		Scopes:        strings.Fields(stringFromEnv("OIDC_SCOPES", "openid profile email")),
+		UsernameClaim: stringFromEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+		RoleClaim:     stringFromEnv("OIDC_ROLE_CLAIM", "groups"),
+		AdminValues:   splitGroups(os.Getenv("OIDC_ADMIN_VALUES")),
+		UserValues:    splitGroups(os.Getenv("OIDC_USER_VALUES")),
+	}
+}
+
+// Enabled проверяет, настроен ли OIDC
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != ""
+}
+
+// OIDCIdentity пользователь провайдера после успешного входа
+type OIDCIdentity struct {
+	Subject  string
+	Username string
+	Role     domain.UserRole
+}
+
+// oidcPending незавершённый вход: PKCE verifier и nonce для state
+type oidcPending struct {
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+// oidcLoginCode одноразовый код, по которому фронтенд забирает токены
+type oidcLoginCode struct {
+	userID    uint
+	expiresAt time.Time
+}
+
+// OIDCAuthenticator реализует authorization code flow с PKCE.
+// Незавершённые входы хранятся в памяти процесса.
+type OIDCAuthenticator struct {
+	config OIDCConfig
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	pending  map[string]oidcPending
+	codes    map[string]oidcLoginCode
+	now      func() time.Time
+}
+
+// NewOIDCAuthenticator создаёт аутентификатор OIDC; провайдер опрашивается при первом входе
+func NewOIDCAuthenticator(config OIDCConfig) *OIDCAuthenticator {
+	return &OIDCAuthenticator{
+		config:  config,
+		pending: make(map[string]oidcPending),
+		codes:   make(map[string]oidcLoginCode),
+		now:     time.Now,
+	}
+}
+
+// Config возвращает настройки аутентификатора
+func (a *OIDCAuthenticator) Config() OIDCConfig {
+	return a.config
+}
+
+// AuthCodeURL начинает вход и возвращает адрес страницы входа провайдера
+func (a *OIDCAuthenticator) AuthCodeURL(ctx context.Context) (string, error) {
+	oauth, _, err := a.oauthConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	a.mu.Lock()
+	a.cleanup()
+	a.pending[state] = oidcPending{
+		verifier:  verifier,
+		nonce:     nonce,
+		expiresAt: a.now().Add(oidcStateTTL),
+	}
+	a.mu.Unlock()
+
+	return oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange завершает вход: обменивает код на токены и проверяет ID-токен
+func (a *OIDCAuthenticator) Exchange(ctx context.Context, state, code string) (*OIDCIdentity, error) {
+	a.mu.Lock()
+	pending, ok := a.pending[state]
+	delete(a.pending, state)
+	a.mu.Unlock()
+	if !ok || a.now().After(pending.expiresAt) {
+		return nil, ErrOIDCInvalidState
+	}
+
+	oauth, provider, err := a.oauthConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(pending.verifier))
+	if err != nil {
+		return nil, errors.Wrap(err, "OIDC code exchange failed")
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("OIDC token response has no id_token")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: a.config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "OIDC ID token verification failed")
+	}
+	if idToken.Nonce != pending.nonce {
+		return nil, errors.New("OIDC nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, errors.Wrap(err, "failed to parse OIDC claims")
+	}
+
+	return a.identity(idToken.Subject, claims)
+}
+
+// identity извлекает имя пользователя и роль из claims
+func (a *OIDCAuthenticator) identity(subject string, claims map[string]interface{}) (*OIDCIdentity, error) {
+	username, _ := claimValue(claims, a.config.UsernameClaim).(string)
+	if username == "" {
+		username, _ = claims["email"].(string)
+	}
+	if username == "" {
+		username = subject
+	}
+
+	values := claimStrings(claimValue(claims, a.config.RoleClaim))
+	var role domain.UserRole
+	switch {
+	case inGroups(values, a.config.AdminValues):
+		role = domain.UserRoleAdmin
+	case len(a.config.UserValues) == 0 || inGroups(values, a.config.UserValues):
+		role = domain.UserRoleUser
+	default:
+		return nil, ErrOIDCNotAllowed
+	}
+
+	return &OIDCIdentity{
+		Subject:  subject,
+		Username: strings.ToLower(username),
+		Role:     role,
+	}, nil
+}
+
+// IssueLoginCode выдаёт одноразовый код, по которому фронтенд получит токены.
+// Так токены не попадают в адресную строку и историю браузера.
+func (a *OIDCAuthenticator) IssueLoginCode(userID uint) (string, error) {
+	code, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cleanup()
+	a.codes[code] = oidcLoginCode{userID: userID, expiresAt: a.now().Add(time.Minute)}
+	return code, nil
+}
+
+// RedeemLoginCode погашает одноразовый код и возвращает ID пользователя
+func (a *OIDCAuthenticator) RedeemLoginCode(code string) (uint, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	login, ok := a.codes[code]
+	delete(a.codes, code)
+	if !ok || a.now().After(login.expiresAt) {
+		return 0, ErrOIDCInvalidState
+	}
+	return login.userID, nil
+}
+
+// oauthConfig возвращает настройки OAuth2, при первом вызове загружая discovery-документ
+func (a *OIDCAuthenticator) oauthConfig(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	a.mu.Lock()
+	provider := a.provider
+	a.mu.Unlock()
+
+	if provider == nil {
+		var err error
+		provider, err = oidc.NewProvider(ctx, a.config.Issuer)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to query OIDC provider %s", a.config.Issuer)
+		}
+		a.mu.Lock()
+		a.provider = provider
+		a.mu.Unlock()
+	}
+
+	return &oauth2.Config{
+		ClientID:     a.config.ClientID,
+		ClientSecret: a.config.ClientSecret,
+		RedirectURL:  a.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       a.config.Scopes,
+	}, provider, nil
+}
+
+// cleanup удаляет просроченные входы; вызывается под мьютексом
+func (a *OIDCAuthenticator) cleanup() {
+	now := a.now()
+	for state, pending := range a.pending {
+		if now.After(pending.expiresAt) {
+			delete(a.pending, state)
+		}
+	}
+	for code, login := range a.codes {
+		if now.After(login.expiresAt) {
+			delete(a.codes, code)
+		}
+	}
+}
+
+// claimValue достаёт claim по пути через точку (realm_access.roles)
+func claimValue(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// claimStrings приводит claim (строку или массив строк) к списку строк
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "failed to generate random value")
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockOIDCProvider минимальный провайдер OpenID Connect: discovery, JWKS и token endpoint с PKCE
+type mockOIDCProvider struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &mockOIDCProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/auth",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if r.FormValue("code") != "valid-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":   p.URL,
+			"aud":   "sipadmin",
+			"sub":   "3f2a",
+			"nonce": p.nonce,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Minute).Unix(),
+		}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		idToken, err := token.SignedString(key)
+		require.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   60,
+			"id_token":     idToken,
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// authorize имитирует вход пользователя у провайдера: запоминает challenge и nonce, возвращает state
+func (p *mockOIDCProvider) authorize(t *testing.T, authURL string) string {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := u.Query()
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+	require.Equal(t, "sipadmin", query.Get("client_id"))
+
+	p.challenge = query.Get("code_challenge")
+	p.nonce = query.Get("nonce")
+	return query.Get("state")
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	auth := NewOIDCAuthenticator(OIDCConfig{
+		Issuer:        provider.URL,
+		ClientID:      "sipadmin",
+		RedirectURL:   "http://localhost:8080/api/auth/oidc/callback",
+		Scopes:        []string{"openid", "profile"},
+		UsernameClaim: "preferred_username",
+		RoleClaim:     "realm_access.roles",
+		AdminValues:   []string{"sipadmin-admin"},
+		UserValues:    []string{"sipadmin-user"},
+	})
+	ctx := context.Background()
+
+	provider.claims = jwt.MapClaims{
+		"preferred_username": "Ivanov",
+		"realm_access":       map[string]interface{}{"roles": []string{"offline_access", "sipadmin-admin"}},
+	}
+	authURL, err := auth.AuthCodeURL(ctx)
+	require.NoError(t, err)
+	state := provider.authorize(t, authURL)
+
+	identity, err := auth.Exchange(ctx, state, "valid-code")
+	require.NoError(t, err)
+	assert.Equal(t, "ivanov", identity.Username)
+	assert.Equal(t, "3f2a", identity.Subject)
+	assert.Equal(t, domain.UserRoleAdmin, identity.Role)
+
+	// state одноразовый
+	_, err = auth.Exchange(ctx, state, "valid-code")
+	assert.Equal(t, ErrOIDCInvalidState, err)
+
+	// Пользователь без разрешённых ролей не допускается
+	provider.claims = jwt.MapClaims{
+		"preferred_username": "petrov",
+		"realm_access":       map[string]interface{}{"roles": []string{"offline_access"}},
+	}
+	authURL, err = auth.AuthCodeURL(ctx)
+	require.NoError(t, err)
+	state = provider.authorize(t, authURL)
+	_, err = auth.Exchange(ctx, state, "valid-code")
+	assert.Equal(t, ErrOIDCNotAllowed, err)
+
+	// Подменённый код отклоняется провайдером
+	authURL, err = auth.AuthCodeURL(ctx)
+	require.NoError(t, err)
+	state = provider.authorize(t, authURL)
+	_, err = auth.Exchange(ctx, state, "stolen-code")
+	assert.Error(t, err)
+}
+
+func TestOIDCLoginCode(t *testing.T) {
+	auth := NewOIDCAuthenticator(OIDCConfig{})
+
+	code, err := auth.IssueLoginCode(42)
+	require.NoError(t, err)
+
+	userID, err := auth.RedeemLoginCode(code)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), userID)
+
+	_, err = auth.RedeemLoginCode(code)
+	assert.Equal(t, ErrOIDCInvalidState, err)
+
+	// Просроченный код
+	code, err = auth.IssueLoginCode(42)
+	require.NoError(t, err)
+	auth.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = auth.RedeemLoginCode(code)
+	assert.Equal(t, ErrOIDCInvalidState, err)
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// PasswordPolicy требования к паролям пользователей
+type PasswordPolicy struct {
+	MinLength     int
+	RequireLetter bool
+	RequireDigit  bool
+}
+
+// PasswordPolicyFromEnv читает требования к паролям из переменных окружения
+func PasswordPolicyFromEnv() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:     intFromEnv("PASSWORD_MIN_LENGTH", 8),
+		RequireLetter: true,
+		RequireDigit:  true,
+	}
+}
+
+// commonPasswords пароли, которые нельзя использовать независимо от длины
+var commonPasswords = map[string]bool{
+	"admin":     true,
+	"password":  true,
+	"12345678":  true,
+	"qwerty123": true,
+	"password1": true,
+	"admin123":  true,
+}
+
+// Validate проверяет пароль на соответствие политике; ошибка пригодна для показа пользователю
+func (p PasswordPolicy) Validate(password, username string) error {
+	if len([]rune(password)) < p.MinLength {
+		return errors.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if p.RequireLetter && !hasLetter {
+		return errors.New("password must contain a letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		return errors.New("password must contain a digit")
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return errors.New("password is too common")
+	}
+	if username != "" && strings.Contains(lower, strings.ToLower(username)) {
+		return errors.New("password must not contain the username")
+	}
+
+	return nil
+}
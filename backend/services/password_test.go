@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, RequireLetter: true, RequireDigit: true}
+
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"Valid password", "Zvonok2025", true},
+		{"Too short", "ab1", false},
+		{"No digit", "abcdefghij", false},
+		{"No letter", "1234567890", false},
+		{"Common password", "Password1", false},
+		{"Contains username", "ivanov2025", false},
+		{"Cyrillic letters count", "пароль2025", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, "ivanov")
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
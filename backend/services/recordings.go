@@ -0,0 +1,200 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// recordingNamePattern имя файла, которое пишет Macro(recording,${CALLERID(num)},${EXTEN}):
+// <YYYYMMDD>-<HHMMSS>-<caller>-<callee>.<ext>, время допускается и в виде HH_MM_SS
+var recordingNamePattern = regexp.MustCompile(`^(\d{8})-(\d{2}_?\d{2}_?\d{2})-(.+)-([^-]+)\.(wav|WAV|mp3|gsm|ogg)$`)
+
+// RecordingConfig настройки индекса записей разговоров
+type RecordingConfig struct {
+	Dir          string
+	ScanInterval time.Duration
+}
+
+// RecordingConfigFromEnv читает настройки индекса записей из переменных окружения
+func RecordingConfigFromEnv() RecordingConfig {
+	return RecordingConfig{
+		Dir:          stringFromEnv("RECORDINGS_DIR", "/var/calls"),
+		ScanInterval: durationFromEnv("RECORDINGS_SCAN_INTERVAL", 10*time.Minute),
+	}
+}
+
+// RecordingService индексирует каталог записей разговоров
+type RecordingService struct {
+	repos  *repositories.Repos
+	config RecordingConfig
+}
+
+// NewRecordingService создаёт сервис индекса записей
+func NewRecordingService(repos *repositories.Repos, config RecordingConfig) *RecordingService {
+	return &RecordingService{
+		repos:  repos,
+		config: config,
+	}
+}
+
+// Config возвращает настройки сервиса
+func (s *RecordingService) Config() RecordingConfig {
+	return s.config
+}
+
+// ScanResult итог сканирования каталога записей
+type ScanResult struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Skipped int `json:"skipped"`
+}
+
+// Scan обходит каталог записей, добавляет новые файлы в индекс и удаляет пропавшие
+func (s *RecordingService) Scan() (*ScanResult, error) {
+	if _, err := os.Stat(s.config.Dir); err != nil {
+		return nil, errors.Wrapf(err, "recordings directory %s is not available", s.config.Dir)
+	}
+
+	known, err := s.repos.FindRecordingPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ScanResult{}
+	seen := make(map[string]bool, len(known))
+
+	err = filepath.WalkDir(s.config.Dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			log.Printf("Recordings: пропущен %s: %v", path, err)
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		recording, ok := ParseRecordingName(entry.Name())
+		if !ok {
+			return nil
+		}
+
+		seen[path] = true
+		if _, exists := known[path]; exists {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			result.Skipped++
+			return nil
+		}
+		recording.Path = path
+		recording.Size = info.Size()
+
+		if err := s.link(recording); err != nil {
+			return err
+		}
+		if err := s.repos.Create(recording); err != nil {
+			return errors.Wrapf(err, "failed to index %s", path)
+		}
+		result.Added++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []uint
+	for path, id := range known {
+		if !seen[path] {
+			missing = append(missing, id)
+		}
+	}
+	if err := s.repos.DeleteRecordingsByIDs(missing); err != nil {
+		return nil, err
+	}
+	result.Removed = len(missing)
+
+	return result, nil
+}
+
+// link связывает запись со звонком из CDR, а при его отсутствии - с профилем по номеру
+func (s *RecordingService) link(recording *domain.Recording) error {
+	var cdr domain.CDR
+	err := s.repos.FindCDRForRecording(&cdr, recording.Caller, recording.Callee, recording.StartedAt)
+	if err == nil {
+		recording.CDRID = &cdr.ID
+		recording.ProfileID = cdr.ProfileID
+		recording.LocationID = cdr.LocationID
+		recording.RingGroup = cdr.RingGroup
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.Wrap(err, "failed to link recording to CDR")
+	}
+
+	var profile domain.Profile
+	switch {
+	case isExtension(recording.Caller):
+		number, _ := strconv.Atoi(recording.Caller)
+		err = s.repos.FindProfileByInternalNumber(&profile, number)
+	case isExtension(recording.Callee):
+		number, _ := strconv.Atoi(recording.Callee)
+		err = s.repos.FindProfileByInternalNumber(&profile, number)
+	case isCityNumber(recording.Callee):
+		err = s.repos.FindProfileByCityNumber(&profile, domain.CleanPhoneNumber(recording.Callee))
+	default:
+		return nil
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to link recording to profile")
+	}
+
+	recording.ProfileID = &profile.ID
+	recording.LocationID = profile.LocationID
+	recording.RingGroup = profile.RingGroup
+	return nil
+}
+
+// ParseRecordingName разбирает имя файла записи в номера и время начала
+func ParseRecordingName(name string) (*domain.Recording, bool) {
+	match := recordingNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return nil, false
+	}
+
+	clock := strings.ReplaceAll(match[2], "_", "")
+	startedAt, err := time.ParseInLocation("20060102150405", match[1]+clock, time.Local)
+	if err != nil {
+		return nil, false
+	}
+
+	return &domain.Recording{
+		FileName:  name,
+		Caller:    match[3],
+		Callee:    match[4],
+		StartedAt: startedAt,
+		Format:    strings.ToLower(match[5]),
+	}, true
+}
+
+// String возвращает краткое описание результата сканирования
+func (r *ScanResult) String() string {
+	return fmt.Sprintf("добавлено %d, удалено %d, пропущено %d", r.Added, r.Removed, r.Skipped)
+}
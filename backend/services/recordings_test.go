@@ -0,0 +1,108 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"asterisk-manager/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecordingName(t *testing.T) {
+	tests := []struct {
+		name      string
+		fileName  string
+		ok        bool
+		caller    string
+		callee    string
+		startedAt time.Time
+	}{
+		{
+			name:      "Outbound call",
+			fileName:  "20250310-091500-1119-89001234567.wav",
+			ok:        true,
+			caller:    "1119",
+			callee:    "89001234567",
+			startedAt: time.Date(2025, 3, 10, 9, 15, 0, 0, time.Local),
+		},
+		{
+			name:      "Time with underscores",
+			fileName:  "20250310-09_15_00-89001234567-244842.WAV",
+			ok:        true,
+			caller:    "89001234567",
+			callee:    "244842",
+			startedAt: time.Date(2025, 3, 10, 9, 15, 0, 0, time.Local),
+		},
+		{
+			name:     "Fax is not a recording",
+			fileName: "244842-20250310-09_15_00-from-89001234567.pdf",
+			ok:       false,
+		},
+		{
+			name:     "Unknown name",
+			fileName: "readme.txt",
+			ok:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recording, ok := ParseRecordingName(tt.fileName)
+
+			assert.Equal(t, tt.ok, ok)
+			if !tt.ok {
+				return
+			}
+			assert.Equal(t, tt.caller, recording.Caller)
+			assert.Equal(t, tt.callee, recording.Callee)
+			assert.True(t, tt.startedAt.Equal(recording.StartedAt))
+		})
+	}
+}
+
+func TestSelectRetentionPolicy(t *testing.T) {
+	zags := uint(1)
+	adm := uint(2)
+	ringGroup := 6008
+
+	policies := []domain.RetentionPolicy{
+		{ID: 1, Name: "Default", Days: 180},
+		{ID: 2, Name: "Zags", LocationID: &zags, Days: 90},
+		{ID: 3, Name: "Administration", LocationID: &adm, Days: 365},
+		{ID: 4, Name: "RG 6008", RingGroup: &ringGroup, Days: 30},
+	}
+
+	tests := []struct {
+		name       string
+		recording  domain.Recording
+		expectedID uint
+	}{
+		{
+			name:       "Location policy",
+			recording:  domain.Recording{LocationID: &adm},
+			expectedID: 3,
+		},
+		{
+			name:       "Ring group wins over location",
+			recording:  domain.Recording{LocationID: &zags, RingGroup: &ringGroup},
+			expectedID: 4,
+		},
+		{
+			name:       "Default policy",
+			recording:  domain.Recording{},
+			expectedID: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := SelectRetentionPolicy(policies, &tt.recording)
+
+			assert.NotNil(t, policy)
+			assert.Equal(t, tt.expectedID, policy.ID)
+		})
+	}
+
+	assert.Nil(t, SelectRetentionPolicy(policies[1:3], &domain.Recording{}))
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"os"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+
+	"github.com/pkg/errors"
+)
+
+// RetentionService удаляет записи разговоров по политикам хранения
+type RetentionService struct {
+	repos *repositories.Repos
+	now   func() time.Time
+}
+
+// NewRetentionService создаёт сервис политик хранения
+func NewRetentionService(repos *repositories.Repos) *RetentionService {
+	return &RetentionService{
+		repos: repos,
+		now:   time.Now,
+	}
+}
+
+// PurgeIntervalFromEnv возвращает интервал автоматической очистки записей
+func PurgeIntervalFromEnv() time.Duration {
+	return durationFromEnv("RECORDINGS_PURGE_INTERVAL", 24*time.Hour)
+}
+
+// Purge находит записи с истёкшим сроком хранения и удаляет их.
+// При dryRun только возвращает список; каждое удаление фиксируется в журнале.
+func (s *RetentionService) Purge(dryRun bool, actor string) (*domain.PurgeReport, error) {
+	var policies []domain.RetentionPolicy
+	if err := s.repos.FindAll(&policies); err != nil {
+		return nil, err
+	}
+
+	report := &domain.PurgeReport{DryRun: dryRun, Items: []domain.PurgeItem{}}
+	if len(policies) == 0 {
+		return report, nil
+	}
+
+	now := s.now()
+	recordings, err := s.repos.FindRecordingsForPurge(now.AddDate(0, 0, -minRetentionDays(policies)))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range recordings {
+		recording := &recordings[i]
+
+		policy := SelectRetentionPolicy(policies, recording)
+		if policy == nil || !recording.StartedAt.Before(now.AddDate(0, 0, -policy.Days)) {
+			continue
+		}
+
+		item := domain.PurgeItem{
+			RecordingID:   recording.ID,
+			FileName:      recording.FileName,
+			StartedAt:     recording.StartedAt,
+			LocationID:    recording.LocationID,
+			RingGroup:     recording.RingGroup,
+			PolicyID:      policy.ID,
+			PolicyName:    policy.Name,
+			RetentionDays: policy.Days,
+		}
+
+		if !dryRun {
+			if err := s.deleteRecording(recording, policy, actor, now); err != nil {
+				item.Error = err.Error()
+				report.Failed++
+			} else {
+				report.Deleted++
+			}
+		}
+
+		report.Items = append(report.Items, item)
+	}
+
+	return report, nil
+}
+
+// deleteRecording удаляет файл и строку индекса, записывая удаление в журнал.
+// Файл удаляется внутри транзакции, чтобы журнал не расходился с диском.
+func (s *RetentionService) deleteRecording(recording *domain.Recording, policy *domain.RetentionPolicy, actor string, now time.Time) error {
+	return s.repos.Transaction(func(tx *repositories.Repos) error {
+		deletion := domain.RecordingDeletion{
+			RecordingID:   recording.ID,
+			Path:          recording.Path,
+			Caller:        recording.Caller,
+			Callee:        recording.Callee,
+			StartedAt:     recording.StartedAt,
+			LocationID:    recording.LocationID,
+			RingGroup:     recording.RingGroup,
+			PolicyID:      &policy.ID,
+			RetentionDays: policy.Days,
+			DeletedBy:     actor,
+			DeletedAt:     now,
+		}
+		if err := tx.Create(&deletion); err != nil {
+			return err
+		}
+		if err := tx.Delete(recording); err != nil {
+			return err
+		}
+		if err := os.Remove(recording.Path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove %s", recording.Path)
+		}
+		return nil
+	})
+}
+
+// SelectRetentionPolicy выбирает наиболее точную политику для записи.
+// При равной точности побеждает более длинный срок хранения.
+func SelectRetentionPolicy(policies []domain.RetentionPolicy, recording *domain.Recording) *domain.RetentionPolicy {
+	var selected *domain.RetentionPolicy
+	for i := range policies {
+		policy := &policies[i]
+		if !policy.Matches(recording) {
+			continue
+		}
+		if selected == nil ||
+			policy.Specificity() > selected.Specificity() ||
+			(policy.Specificity() == selected.Specificity() && policy.Days > selected.Days) {
+			selected = policy
+		}
+	}
+	return selected
+}
+
+func minRetentionDays(policies []domain.RetentionPolicy) int {
+	days := policies[0].Days
+	for _, policy := range policies[1:] {
+		if policy.Days < days {
+			days = policy.Days
+		}
+	}
+	return days
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RunPeriodically выполняет задачу сразу и затем с заданным интервалом до отмены контекста
+func RunPeriodically(ctx context.Context, name string, interval time.Duration, task func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := task(); err != nil {
+			log.Printf("%s: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// durationFromEnv читает длительность из переменной окружения
+func durationFromEnv(key string, defaultValue time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return defaultValue
+}
+
+// stringFromEnv читает строку из переменной окружения
+func stringFromEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// intFromEnv читает положительное целое из переменной окружения
+func intFromEnv(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 1 {
+		return defaultValue
+	}
+	return value
+}
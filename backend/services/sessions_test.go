@@ -0,0 +1,256 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+	"asterisk-manager/repositories/dbtest"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock часы, которые двигаются только вручную
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	// Токены проверяются библиотекой JWT по настоящим часам, поэтому начинаем с текущего времени
+	return &fakeClock{now: time.Now().Truncate(time.Second)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestAuthService(repos *repositories.Repos, clock *fakeClock) *AuthService {
+	return &AuthService{
+		repos:           repos,
+		secretKey:       []byte("test-secret"),
+		tokenDuration:   15 * time.Minute,
+		refreshDuration: 24 * time.Hour,
+		mfaPolicy:       MFAPolicy{},
+		now:             clock.Now,
+	}
+}
+
+func TestNewRefreshToken(t *testing.T) {
+	token, hash, err := newRefreshToken()
+	require.NoError(t, err)
+	other, otherHash, err := newRefreshToken()
+	require.NoError(t, err)
+
+	assert.NotEqual(t, token, other)
+	assert.NotEqual(t, hash, otherHash)
+	assert.Equal(t, hashToken(token), hash)
+}
+
+func TestGenerateTokenUsesClock(t *testing.T) {
+	clock := newFakeClock()
+	auth := newTestAuthService(nil, clock)
+	clock.Advance(time.Minute)
+
+	_, expiresAt, err := auth.GenerateToken(&domain.User{ID: 1, Username: "ivanov"}, 7)
+	require.NoError(t, err)
+	assert.Equal(t, clock.Now().Add(15*time.Minute), expiresAt)
+}
+
+func TestSessionIsActive(t *testing.T) {
+	now := time.Date(2025, 1, 11, 12, 0, 0, 0, time.UTC)
+	session := domain.Session{ExpiresAt: now.Add(time.Hour)}
+	assert.True(t, session.IsActive(now))
+	assert.False(t, session.IsActive(now.Add(time.Hour)))
+
+	session.RevokedAt = &now
+	assert.False(t, session.IsActive(now))
+}
+
+// createTestUser создаёт активного пользователя
+func createTestUser(t *testing.T, repos *repositories.Repos, username string, source domain.AuthSource) *domain.User {
+	t.Helper()
+	user := domain.User{Username: username, PasswordHash: "-", Role: domain.UserRoleUser, AuthSource: source, IsActive: true}
+	require.NoError(t, repos.Create(&user))
+	return &user
+}
+
+// findSession читает сессию из БД вместе с причиной отзыва
+func findSession(t *testing.T, repos *repositories.Repos, id uint) domain.Session {
+	t.Helper()
+	var session domain.Session
+	require.NoError(t, repos.FindByID(&session, id))
+	return session
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	repos := dbtest.Open(t)
+	clock := newFakeClock()
+	auth := newTestAuthService(repos, clock)
+	user := createTestUser(t, repos, "ivanov", domain.AuthSourceLocal)
+
+	pair, err := auth.StartSession(user, "10.0.0.1", "test")
+	require.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	rotated, refreshedUser, err := auth.Refresh(pair.RefreshToken, "10.0.0.2", "test")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, refreshedUser.ID)
+	assert.NotEqual(t, pair.RefreshToken, rotated.RefreshToken)
+
+	claims, err := auth.ValidateToken(rotated.AccessToken)
+	require.NoError(t, err)
+	session := findSession(t, repos, claims.SessionID)
+	assert.Equal(t, hashToken(rotated.RefreshToken), session.TokenHash)
+	assert.Equal(t, hashToken(pair.RefreshToken), session.PreviousTokenHash)
+	assert.Equal(t, "10.0.0.2", session.IP)
+	// Срок сессии продлевается от момента обновления
+	assert.WithinDuration(t, clock.Now().Add(24*time.Hour), session.ExpiresAt, time.Second)
+
+	// Сессия без обновлений дольше refreshDuration истекает
+	clock.Advance(25 * time.Hour)
+	_, _, err = auth.Refresh(rotated.RefreshToken, "10.0.0.2", "test")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	repos := dbtest.Open(t)
+	clock := newFakeClock()
+	auth := newTestAuthService(repos, clock)
+	user := createTestUser(t, repos, "ivanov", domain.AuthSourceLocal)
+
+	pair, err := auth.StartSession(user, "10.0.0.1", "test")
+	require.NoError(t, err)
+	rotated, _, err := auth.Refresh(pair.RefreshToken, "10.0.0.1", "test")
+	require.NoError(t, err)
+
+	// Старый токен предъявлен повторно - утечка, сессия отзывается целиком
+	_, _, err = auth.Refresh(pair.RefreshToken, "10.6.6.6", "attacker")
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	_, _, err = auth.Refresh(rotated.RefreshToken, "10.0.0.1", "test")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = auth.ValidateToken(rotated.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+
+	claims, err := auth.ValidateToken(pair.AccessToken)
+	assert.Nil(t, claims)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+
+	var sessions []domain.Session
+	require.NoError(t, repos.FindAll(&sessions))
+	require.Len(t, sessions, 1)
+	require.NotNil(t, sessions[0].RevokedAt)
+	assert.Equal(t, RevokeReasonRefreshReuse, sessions[0].RevokeReason)
+}
+
+func TestConcurrentRefreshSingleWinner(t *testing.T) {
+	repos := dbtest.Open(t)
+	clock := newFakeClock()
+	auth := newTestAuthService(repos, clock)
+	user := createTestUser(t, repos, "ivanov", domain.AuthSourceLocal)
+
+	pair, err := auth.StartSession(user, "10.0.0.1", "test")
+	require.NoError(t, err)
+
+	const requests = 4
+	errs := make([]error, requests)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, _, errs[i] = auth.Refresh(pair.RefreshToken, "10.0.0.1", "test")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.True(t, errors.Is(err, ErrRefreshTokenReused) || errors.Is(err, ErrInvalidRefreshToken), err)
+	}
+	assert.Equal(t, 1, succeeded, "exactly one concurrent refresh must rotate the token")
+}
+
+func TestRefreshDisabledUser(t *testing.T) {
+	repos := dbtest.Open(t)
+	clock := newFakeClock()
+	auth := newTestAuthService(repos, clock)
+	user := createTestUser(t, repos, "ivanov", domain.AuthSourceLocal)
+
+	pair, err := auth.StartSession(user, "10.0.0.1", "test")
+	require.NoError(t, err)
+
+	user.IsActive = false
+	require.NoError(t, repos.Save(user))
+
+	_, _, err = auth.Refresh(pair.RefreshToken, "10.0.0.1", "test")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = auth.ValidateToken(pair.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+
+	var sessions []domain.Session
+	require.NoError(t, repos.FindAll(&sessions))
+	require.Len(t, sessions, 1)
+	assert.Equal(t, RevokeReasonDisabled, sessions[0].RevokeReason)
+}
+
+func TestRevokeUserSessionsOnRoleChange(t *testing.T) {
+	repos := dbtest.Open(t)
+	clock := newFakeClock()
+	auth := newTestAuthService(repos, clock)
+	user := createTestUser(t, repos, "petrov", domain.AuthSourceOIDC)
+
+	first, err := auth.StartSession(user, "10.0.0.1", "browser")
+	require.NoError(t, err)
+	second, err := auth.StartSession(user, "10.0.0.2", "phone")
+	require.NoError(t, err)
+
+	// Провайдер вернул другую роль - старые сессии со старой ролью в токенах отзываются
+	_, err = auth.AuthenticateOIDC(&OIDCIdentity{Username: "petrov", Role: domain.UserRoleAdmin})
+	require.NoError(t, err)
+
+	for _, pair := range []*TokenPair{first, second} {
+		_, err = auth.ValidateToken(pair.AccessToken)
+		assert.ErrorIs(t, err, ErrSessionRevoked)
+		_, _, err = auth.Refresh(pair.RefreshToken, "10.0.0.1", "test")
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	}
+
+	sessions, err := auth.ActiveSessions(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	// RevokeUserSessions оставляет сессию exceptID
+	third, err := auth.StartSession(user, "10.0.0.3", "browser")
+	require.NoError(t, err)
+	fourth, err := auth.StartSession(user, "10.0.0.4", "browser")
+	require.NoError(t, err)
+	thirdClaims, err := auth.ValidateToken(third.AccessToken)
+	require.NoError(t, err)
+
+	require.NoError(t, auth.RevokeUserSessions(user.ID, thirdClaims.SessionID, RevokeReasonPasswordChanged))
+	_, err = auth.ValidateToken(third.AccessToken)
+	assert.NoError(t, err)
+	_, err = auth.ValidateToken(fourth.AccessToken)
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+}
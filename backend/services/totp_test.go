@@ -0,0 +1,76 @@
+package services
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// Тестовые векторы RFC 6238 (SHA-1), последние 6 цифр
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.code, code, "time %d", tt.unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+
+	code, err := TOTPCode(secret, TOTPStep(now))
+	require.NoError(t, err)
+
+	step, ok := ValidateTOTP(secret, code, now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now), step)
+
+	// Повтор того же кода отклоняется
+	_, ok = ValidateTOTP(secret, code, now, step)
+	assert.False(t, ok)
+
+	// Допускается расхождение часов на один шаг
+	_, ok = ValidateTOTP(secret, code, now.Add(30*time.Second), 0)
+	assert.True(t, ok)
+	_, ok = ValidateTOTP(secret, code, now.Add(90*time.Second), 0)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(secret, "000000x", now, 0)
+	assert.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Asterisk Manager", "admin", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Asterisk%20Manager:admin?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Asterisk+Manager")
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	for _, code := range codes {
+		assert.Len(t, code, 9)
+		assert.Equal(t, code, normalizeRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))))
+	}
+}
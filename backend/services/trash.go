@@ -0,0 +1,87 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories"
+)
+
+// TrashService окончательно удаляет профили, устройства и локации, пролежавшие в корзине дольше срока
+type TrashService struct {
+	repos     *repositories.Repos
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewTrashService создаёт сервис очистки корзины; срок хранения - TRASH_RETENTION
+func NewTrashService(repos *repositories.Repos) *TrashService {
+	return &TrashService{
+		repos:     repos,
+		retention: durationFromEnv("TRASH_RETENTION", 30*24*time.Hour),
+		now:       time.Now,
+	}
+}
+
+// Retention возвращает срок хранения в корзине
+func (s *TrashService) Retention() time.Duration {
+	return s.retention
+}
+
+// Purge окончательно удаляет записи, попавшие в корзину раньше срока хранения. Как и ручное
+// удаление из корзины, каждая запись попадает в журнал аудита (purge, автор "scheduler").
+func (s *TrashService) Purge() error {
+	before := s.now().Add(-s.retention)
+	purged := 0
+
+	err := s.repos.Transaction(func(tx *repositories.Repos) error {
+		purge := func(entity domain.Auditable) error {
+			if err := tx.Purge(entity); err != nil {
+				return err
+			}
+			purged++
+			return RecordAudit(tx, domain.AuditActionPurge, entity, nil, SchedulerActor)
+		}
+
+		// Сначала профили: после них освобождаются их устройства и локации
+		var profiles []domain.Profile
+		if err := tx.FindPurgeable(&profiles, before); err != nil {
+			return err
+		}
+		for i := range profiles {
+			if err := purge(&profiles[i]); err != nil {
+				return err
+			}
+		}
+
+		var devices []domain.Device
+		if err := tx.FindPurgeable(&devices, before); err != nil {
+			return err
+		}
+		for i := range devices {
+			if err := purge(&devices[i]); err != nil {
+				return err
+			}
+		}
+
+		var locations []domain.Location
+		if err := tx.FindPurgeable(&locations, before); err != nil {
+			return err
+		}
+		for i := range locations {
+			if err := purge(&locations[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		log.Printf("Trash: окончательно удалено записей: %d", purged)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"asterisk-manager/domain"
+	"asterisk-manager/repositories/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashPurge(t *testing.T) {
+	repos := dbtest.Open(t)
+
+	location := domain.Location{Name: "ЗАГС", Server: "10.0.0.1", Subnet: "10.0.0.0/24", VoipVLAN: 10, VLAN: 20}
+	require.NoError(t, repos.Create(&location))
+	device := domain.Device{MAC: "80:5e:c0:18:ab:ac", DeviceModel: domain.DeviceModelFanvil}
+	require.NoError(t, repos.Create(&device))
+	kept := domain.Device{MAC: "80:5e:c0:18:ab:ad", DeviceModel: domain.DeviceModelFanvil}
+	require.NoError(t, repos.Create(&kept))
+
+	profile := domain.Profile{Name: "Иванов", InternalNumber: 6101, Device: &device.MAC, LocationID: &location.ID, IsActive: true}
+	require.NoError(t, repos.Create(&profile))
+	active := domain.Profile{Name: "Петров", InternalNumber: 6102, Device: &kept.MAC, IsActive: true}
+	require.NoError(t, repos.Create(&active))
+
+	// Мягкое удаление: запись остаётся в корзине
+	for _, entity := range []interface{}{&profile, &device, &location, &kept} {
+		require.NoError(t, repos.Delete(entity))
+	}
+	var trashed []domain.Profile
+	require.NoError(t, repos.FindDeleted(&trashed))
+	require.Len(t, trashed, 1)
+	assert.Equal(t, profile.ID, trashed[0].ID)
+
+	// До истечения срока ничего не удаляется
+	trash := NewTrashService(repos)
+	trash.retention = 30 * 24 * time.Hour
+	require.NoError(t, trash.Purge())
+	var stillTrashed []domain.Profile
+	require.NoError(t, repos.FindDeleted(&stillTrashed))
+	assert.Len(t, stillTrashed, 1)
+
+	// Через срок удаляются профиль и освободившиеся устройство и локация;
+	// устройство активного профиля остаётся в корзине
+	trash.now = func() time.Time { return time.Now().Add(31 * 24 * time.Hour) }
+	require.NoError(t, trash.Purge())
+
+	var profiles []domain.Profile
+	require.NoError(t, repos.FindDeleted(&profiles))
+	assert.Empty(t, profiles)
+	var devices []domain.Device
+	require.NoError(t, repos.FindDeleted(&devices))
+	require.Len(t, devices, 1)
+	assert.Equal(t, kept.MAC, devices[0].MAC)
+	var locations []domain.Location
+	require.NoError(t, repos.FindDeleted(&locations))
+	assert.Empty(t, locations)
+
+	// Окончательное удаление по расписанию пишется в журнал аудита, как ручное
+	var entries []domain.AuditEntry
+	require.NoError(t, repos.FindAll(&entries))
+	purged := map[domain.AuditEntityType]string{}
+	for _, entry := range entries {
+		assert.Equal(t, domain.AuditActionPurge, entry.Action)
+		assert.Equal(t, SchedulerActor.Username, entry.Username)
+		purged[entry.EntityType] = entry.EntityID
+	}
+	entityID := func(entity domain.Auditable) string {
+		_, id := entity.AuditEntity()
+		return id
+	}
+	assert.Equal(t, map[domain.AuditEntityType]string{
+		domain.AuditEntityProfile:  entityID(profile),
+		domain.AuditEntityDevice:   entityID(device),
+		domain.AuditEntityLocation: entityID(location),
+	}, purged)
+}
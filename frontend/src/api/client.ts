@@ -4,7 +4,23 @@ import type {
   Device,
   Location,
   PaginationParams,
-  PaginatedResult
+  PaginatedResult,
+  ProfileFilter,
+  DeviceFilter,
+  DeviceWithUsage,
+  LocationWithCounts,
+  SearchResponse,
+  BulkProfileRequest,
+  BulkResult,
+  CreateProfileRequest,
+  NumberPool,
+  NumberReservation,
+  ReserveNumberRequest,
+  FreeNumber,
+  PoolUtilisation,
+  ErrorResponse,
+  DeleteOptions,
+  DeleteImpact
 } from '@/types/api'
 import { useAuth } from '@/stores/auth'
 
@@ -13,14 +29,27 @@ import { useAuth } from '@/stores/auth'
 // In dev mode: vite proxies /api to backend at localhost:8080
 const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || '/api'
 
+// Ошибка API с текстом ответа сервера и ошибками отдельных полей (422)
+export class ApiError extends Error {
+  constructor(
+    public status: number,
+    message: string,
+    public fields: Record<string, string> = {},
+    public requestId?: string
+  ) {
+    super(message)
+    this.name = 'ApiError'
+  }
+}
+
 // Generic fetch wrapper with error handling
 async function fetchAPI<T>(endpoint: string, options?: RequestInit): Promise<T> {
   const url = `${API_BASE_URL}${endpoint}`
-  const { getToken, logout } = useAuth()
-  const token = getToken()
+  const { getToken, logout, refresh } = useAuth()
 
-  try {
-    const response = await fetch(url, {
+  const send = () => {
+    const token = getToken()
+    return fetch(url, {
       ...options,
       headers: {
         'Content-Type': 'application/json',
@@ -28,6 +57,15 @@ async function fetchAPI<T>(endpoint: string, options?: RequestInit): Promise<T>
         ...options?.headers,
       },
     })
+  }
+
+  try {
+    let response = await send()
+
+    // Access token expired - refresh once and retry
+    if (response.status === 401 && await refresh()) {
+      response = await send()
+    }
 
     // Handle 401 Unauthorized - logout and redirect
     if (response.status === 401) {
@@ -37,7 +75,13 @@ async function fetchAPI<T>(endpoint: string, options?: RequestInit): Promise<T>
     }
 
     if (!response.ok) {
-      throw new Error(`HTTP error! status: ${response.status}`)
+      const body: ErrorResponse | null = await response.json().catch(() => null)
+      throw new ApiError(
+        response.status,
+        body?.error || `HTTP error! status: ${response.status}`,
+        body?.fields,
+        body?.requestId
+      )
     }
 
     // 204 No Content - нет тела ответа
@@ -52,17 +96,49 @@ async function fetchAPI<T>(endpoint: string, options?: RequestInit): Promise<T>
   }
 }
 
+// Query string with pagination and filters; empty filter values are skipped
+function pageQuery(params: PaginationParams, filter: object = {}): URLSearchParams {
+  const queryParams = new URLSearchParams({
+    page: params.page.toString(),
+    perPage: params.perPage.toString(),
+  })
+  for (const [key, value] of Object.entries(filter)) {
+    if (value !== undefined && value !== null && value !== '') {
+      queryParams.set(key, String(value))
+    }
+  }
+  return queryParams
+}
+
+// Loads every page of a paginated list (for dropdowns and small tables)
+async function fetchAllPages<T>(getPage: (params: PaginationParams) => Promise<PaginatedResult<T>>): Promise<T[]> {
+  const items: T[] = []
+  for (let page = 1; ; page++) {
+    const result = await getPage({ page, perPage: 100 })
+    items.push(...result.data)
+    if (page >= result.pagination.pages) {
+      return items
+    }
+  }
+}
+
+// Query string for deleting a device or location with a dependents policy
+function deleteQuery(options?: DeleteOptions, dryRun = false): string {
+  const params = new URLSearchParams()
+  if (options?.policy) params.set('policy', options.policy)
+  if (options?.reassignTo) params.set('reassignTo', options.reassignTo)
+  if (dryRun) params.set('dryRun', 'true')
+  const query = params.toString()
+  return query ? `?${query}` : ''
+}
+
 // Profiles API
 export const profilesAPI = {
   /**
-   * Get all profiles with pagination
+   * Get profiles with pagination, filters, search and sorting
    */
-  getAll(params: PaginationParams): Promise<PaginatedResult<ProfileWithLocation>> {
-    const queryParams = new URLSearchParams({
-      page: params.page.toString(),
-      perPage: params.perPage.toString(),
-    })
-    return fetchAPI<PaginatedResult<ProfileWithLocation>>(`/profiles?${queryParams}`)
+  getAll(params: PaginationParams, filter: ProfileFilter = {}): Promise<PaginatedResult<ProfileWithLocation>> {
+    return fetchAPI<PaginatedResult<ProfileWithLocation>>(`/profiles?${pageQuery(params, filter)}`)
   },
 
   /**
@@ -73,9 +149,9 @@ export const profilesAPI = {
   },
 
   /**
-   * Create a new profile
+   * Create a new profile; with allocateFromPool the internal number is taken from the pool
    */
-  create(profile: Omit<Profile, 'id' | 'createdAt' | 'updatedAt'>): Promise<Profile> {
+  create(profile: CreateProfileRequest): Promise<Profile> {
     return fetchAPI<Profile>('/profiles', {
       method: 'POST',
       body: JSON.stringify(profile),
@@ -100,15 +176,32 @@ export const profilesAPI = {
       method: 'DELETE',
     })
   },
+
+  /**
+   * Change many profiles at once; an atomic request that fails is rejected with 422
+   */
+  bulk(request: BulkProfileRequest): Promise<BulkResult> {
+    return fetchAPI<BulkResult>('/profiles/bulk', {
+      method: 'POST',
+      body: JSON.stringify(request),
+    })
+  },
 }
 
 // Devices API
 export const devicesAPI = {
   /**
-   * Get all devices (no pagination)
+   * Get a page of devices with filters
+   */
+  getPage(params: PaginationParams, filter: DeviceFilter = {}): Promise<PaginatedResult<DeviceWithUsage>> {
+    return fetchAPI<PaginatedResult<DeviceWithUsage>>(`/devices?${pageQuery(params, filter)}`)
+  },
+
+  /**
+   * Get all devices, page by page
    */
-  getAll(): Promise<Device[]> {
-    return fetchAPI<Device[]>('/devices')
+  getAll(filter: DeviceFilter = {}): Promise<DeviceWithUsage[]> {
+    return fetchAllPages(params => devicesAPI.getPage(params, filter))
   },
 
   /**
@@ -141,8 +234,17 @@ export const devicesAPI = {
   /**
    * Delete a device
    */
-  delete(mac: string): Promise<void> {
-    return fetchAPI<void>(`/devices/${mac}`, {
+  delete(mac: string, options?: DeleteOptions): Promise<void> {
+    return fetchAPI<void>(`/devices/${mac}${deleteQuery(options)}`, {
+      method: 'DELETE',
+    })
+  },
+
+  /**
+   * Preview deleting a device: dependent profiles and affected config files
+   */
+  deleteImpact(mac: string, options?: DeleteOptions): Promise<DeleteImpact> {
+    return fetchAPI<DeleteImpact>(`/devices/${mac}${deleteQuery(options, true)}`, {
       method: 'DELETE',
     })
   },
@@ -151,10 +253,17 @@ export const devicesAPI = {
 // Locations API
 export const locationsAPI = {
   /**
-   * Get all locations (no pagination)
+   * Get a page of locations with profile and device counts
+   */
+  getPage(params: PaginationParams): Promise<PaginatedResult<LocationWithCounts>> {
+    return fetchAPI<PaginatedResult<LocationWithCounts>>(`/locations?${pageQuery(params)}`)
+  },
+
+  /**
+   * Get all locations, page by page
    */
-  getAll(): Promise<Location[]> {
-    return fetchAPI<Location[]>('/locations')
+  getAll(): Promise<LocationWithCounts[]> {
+    return fetchAllPages(params => locationsAPI.getPage(params))
   },
 
   /**
@@ -187,9 +296,108 @@ export const locationsAPI = {
   /**
    * Delete a location
    */
+  delete(id: number, options?: DeleteOptions): Promise<void> {
+    return fetchAPI<void>(`/locations/${id}${deleteQuery(options)}`, {
+      method: 'DELETE',
+    })
+  },
+
+  /**
+   * Preview deleting a location: dependent profiles and affected config files
+   */
+  deleteImpact(id: number, options?: DeleteOptions): Promise<DeleteImpact> {
+    return fetchAPI<DeleteImpact>(`/locations/${id}${deleteQuery(options, true)}`, {
+      method: 'DELETE',
+    })
+  },
+}
+
+// Number pools API
+export const numberPoolsAPI = {
+  /**
+   * Get all number pools ordered by range
+   */
+  getAll(): Promise<NumberPool[]> {
+    return fetchAPI<NumberPool[]>('/number-pools')
+  },
+
+  /**
+   * Get used, reserved and free numbers of every pool
+   */
+  getUtilisation(): Promise<PoolUtilisation[]> {
+    return fetchAPI<PoolUtilisation[]>('/number-pools/utilisation')
+  },
+
+  /**
+   * Create a new number pool
+   */
+  create(pool: Omit<NumberPool, 'id' | 'createdAt' | 'updatedAt'>): Promise<NumberPool> {
+    return fetchAPI<NumberPool>('/number-pools', {
+      method: 'POST',
+      body: JSON.stringify(pool),
+    })
+  },
+
+  /**
+   * Update an existing number pool
+   */
+  update(id: number, pool: Partial<NumberPool>): Promise<NumberPool> {
+    return fetchAPI<NumberPool>(`/number-pools/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(pool),
+    })
+  },
+
+  /**
+   * Delete a number pool with its reservations
+   */
   delete(id: number): Promise<void> {
-    return fetchAPI<void>(`/locations/${id}`, {
+    return fetchAPI<void>(`/number-pools/${id}`, {
       method: 'DELETE',
     })
   },
+
+  /**
+   * Get the first free number of a pool without reserving it
+   */
+  next(id: number): Promise<FreeNumber> {
+    return fetchAPI<FreeNumber>(`/number-pools/${id}/next`)
+  },
+
+  /**
+   * Get active reservations of a pool
+   */
+  getReservations(id: number): Promise<NumberReservation[]> {
+    return fetchAPI<NumberReservation[]>(`/number-pools/${id}/reservations`)
+  },
+
+  /**
+   * Reserve a number (or the first free one) of a pool
+   */
+  reserve(id: number, request: ReserveNumberRequest = {}): Promise<NumberReservation> {
+    return fetchAPI<NumberReservation>(`/number-pools/${id}/reservations`, {
+      method: 'POST',
+      body: JSON.stringify(request),
+    })
+  },
+
+  /**
+   * Release a reservation
+   */
+  release(id: number, number: number): Promise<void> {
+    return fetchAPI<void>(`/number-pools/${id}/reservations/${number}`, {
+      method: 'DELETE',
+    })
+  },
+}
+
+// Search API
+export const searchAPI = {
+  /**
+   * Ranked search across profiles, devices, locations and ring groups
+   */
+  search(q: string, limit = 20): Promise<SearchResponse> {
+    const queryParams = new URLSearchParams({ q, limit: limit.toString() })
+    return fetchAPI<SearchResponse>(`/search?${queryParams}`)
+  },
 }
@@ -17,6 +17,16 @@ const routes: RouteRecordRaw[] = [
       requiresAuth: false
     }
   },
+  {
+    // Return from OIDC single sign-on
+    path: '/login/callback',
+    name: 'login-callback',
+    component: LoginView,
+    meta: {
+      title: 'Вход',
+      requiresAuth: false
+    }
+  },
   {
     path: '/',
     redirect: '/admin/profiles'
@@ -1,14 +1,18 @@
 import { ref, computed } from 'vue'
-import type { User, LoginRequest, LoginResponse } from '@/types/api'
+import type { User, LoginRequest, LoginResponse, MFAChallengeResponse } from '@/types/api'
 
 const TOKEN_KEY = 'auth_token'
+const REFRESH_TOKEN_KEY = 'auth_refresh_token'
 const USER_KEY = 'auth_user'
 
 // State
 const token = ref<string | null>(localStorage.getItem(TOKEN_KEY))
+const refreshToken = ref<string | null>(localStorage.getItem(REFRESH_TOKEN_KEY))
 const user = ref<User | null>(null)
 const loading = ref(false)
 const error = ref<string | null>(null)
+// Set after a correct password when the second factor (TOTP) is still required
+const mfaToken = ref<string | null>(null)
 
 // Initialize user from localStorage
 const storedUser = localStorage.getItem(USER_KEY)
@@ -45,14 +49,51 @@ async function login(credentials: LoginRequest): Promise<boolean> {
       throw new Error(data.error || 'Ошибка авторизации')
     }
 
-    const data: LoginResponse = await response.json()
+    const data: LoginResponse | MFAChallengeResponse = await response.json()
+    if ('mfaRequired' in data) {
+      mfaToken.value = data.mfaToken
+      return false
+    }
+    saveTokens(data)
+
+    return true
+  } catch (err) {
+    error.value = err instanceof Error ? err.message : 'Ошибка авторизации'
+    return false
+  } finally {
+    loading.value = false
+  }
+}
+
+// Second login step: TOTP code or recovery code for the pending mfaToken
+async function completeMFALogin(code: string, recovery = false): Promise<boolean> {
+  loading.value = true
+  error.value = null
+
+  try {
+    const response = await fetch(`${API_BASE_URL}/auth/login/mfa`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(
+        recovery
+          ? { mfaToken: mfaToken.value, recoveryCode: code }
+          : { mfaToken: mfaToken.value, code },
+      ),
+    })
 
-    // Save to state and localStorage
-    token.value = data.token
-    user.value = data.user
-    localStorage.setItem(TOKEN_KEY, data.token)
-    localStorage.setItem(USER_KEY, JSON.stringify(data.user))
+    if (!response.ok) {
+      const data = await response.json()
+      if (response.status === 401 && data.error !== 'Invalid two-factor code') {
+        // Step expired, start over with the password
+        mfaToken.value = null
+      }
+      throw new Error(data.error || 'Ошибка авторизации')
+    }
 
+    saveTokens(await response.json())
+    mfaToken.value = null
     return true
   } catch (err) {
     error.value = err instanceof Error ? err.message : 'Ошибка авторизации'
@@ -62,27 +103,123 @@ async function login(credentials: LoginRequest): Promise<boolean> {
   }
 }
 
-function logout() {
+// Complete OIDC single sign-on: exchange one-time login code for tokens
+async function completeOIDCLogin(code: string): Promise<boolean> {
+  loading.value = true
+  error.value = null
+
+  try {
+    const response = await fetch(`${API_BASE_URL}/auth/oidc/token`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ code }),
+    })
+
+    if (!response.ok) {
+      const data = await response.json()
+      throw new Error(data.error || 'Ошибка авторизации')
+    }
+
+    saveTokens(await response.json())
+    return true
+  } catch (err) {
+    error.value = err instanceof Error ? err.message : 'Ошибка авторизации'
+    return false
+  } finally {
+    loading.value = false
+  }
+}
+
+// Save tokens and user to state and localStorage
+function saveTokens(data: LoginResponse) {
+  token.value = data.token
+  user.value = data.user
+  localStorage.setItem(TOKEN_KEY, data.token)
+  localStorage.setItem(USER_KEY, JSON.stringify(data.user))
+  if (data.refreshToken) {
+    refreshToken.value = data.refreshToken
+    localStorage.setItem(REFRESH_TOKEN_KEY, data.refreshToken)
+  }
+}
+
+// Exchange refresh token for a new token pair; concurrent callers share one request
+let refreshing: Promise<boolean> | null = null
+
+function refresh(): Promise<boolean> {
+  if (!refreshToken.value) {
+    return Promise.resolve(false)
+  }
+  if (!refreshing) {
+    refreshing = (async () => {
+      try {
+        const response = await fetch(`${API_BASE_URL}/auth/refresh`, {
+          method: 'POST',
+          headers: {
+            'Content-Type': 'application/json',
+          },
+          body: JSON.stringify({ refreshToken: refreshToken.value }),
+        })
+        if (!response.ok) {
+          return false
+        }
+        saveTokens(await response.json())
+        return true
+      } catch {
+        return false
+      } finally {
+        refreshing = null
+      }
+    })()
+  }
+  return refreshing
+}
+
+function clearSession() {
   token.value = null
+  refreshToken.value = null
   user.value = null
   localStorage.removeItem(TOKEN_KEY)
+  localStorage.removeItem(REFRESH_TOKEN_KEY)
   localStorage.removeItem(USER_KEY)
 }
 
+function logout() {
+  // Revoke the session on the server, local state is cleared regardless
+  if (token.value) {
+    fetch(`${API_BASE_URL}/auth/logout`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token.value}`,
+      },
+    }).catch(() => {})
+  }
+  clearSession()
+}
+
 async function checkAuth(): Promise<boolean> {
   if (!token.value) {
     return false
   }
 
   try {
-    const response = await fetch(`${API_BASE_URL}/auth/me`, {
+    let response = await fetch(`${API_BASE_URL}/auth/me`, {
       headers: {
         'Authorization': `Bearer ${token.value}`,
       },
     })
 
+    if (response.status === 401 && await refresh()) {
+      response = await fetch(`${API_BASE_URL}/auth/me`, {
+        headers: {
+          'Authorization': `Bearer ${token.value}`,
+        },
+      })
+    }
+
     if (!response.ok) {
-      logout()
+      clearSession()
       return false
     }
 
@@ -91,7 +228,7 @@ async function checkAuth(): Promise<boolean> {
     localStorage.setItem(USER_KEY, JSON.stringify(userData))
     return true
   } catch {
-    logout()
+    clearSession()
     return false
   }
 }
@@ -108,11 +245,15 @@ export function useAuth() {
     user,
     loading,
     error,
+    mfaToken,
     // Computed
     isAuthenticated,
     // Actions
     login,
+    completeMFALogin,
+    completeOIDCLogin,
     logout,
+    refresh,
     checkAuth,
     getToken,
   }
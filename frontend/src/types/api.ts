@@ -3,10 +3,34 @@
 // User types
 export type UserRole = 'admin' | 'user'
 
+export type Permission =
+  | 'profiles:read'
+  | 'profiles:write'
+  | 'devices:read'
+  | 'devices:write'
+  | 'locations:read'
+  | 'locations:write'
+  | 'cdr:read'
+  | 'reports:read'
+  | 'recordings:read'
+  | 'recordings:manage'
+  | 'faxes:read'
+  | 'faxes:manage'
+  | 'generator:run'
+  | 'users:manage'
+  | 'apikeys:manage'
+  | 'audit:read'
+
 export interface User {
   id: number
   username: string
   role: UserRole
+  locationId: number | null
+  authSource: 'local' | 'ldap' | 'oidc'
+  isActive: boolean
+  mustChangePassword: boolean
+  totpEnabled: boolean
+  permissions: Permission[]
   createdAt: string
 }
 
@@ -17,9 +41,29 @@ export interface LoginRequest {
 
 export interface LoginResponse {
   token: string
+  refreshToken?: string
+  expiresAt: string
   user: User
 }
 
+// Returned by /auth/login instead of tokens when the user has TOTP enabled
+export interface MFAChallengeResponse {
+  mfaRequired: true
+  mfaToken: string
+}
+
+export interface LoginMFARequest {
+  mfaToken: string
+  code?: string
+  recoveryCode?: string
+}
+
+export interface TOTPSetup {
+  secret: string
+  uri: string
+  qrCode: string
+}
+
 // Device Models (enum from backend)
 export type DeviceModel = 'Yealink T27G' | 'Yealink T23G' | 'Fanvil' | 'Cisco'
 
@@ -29,6 +73,7 @@ export interface Device {
   deviceModel: DeviceModel
   createdAt: string
   updatedAt: string
+  deletedAt: string | null // set while in trash
 }
 
 // Location represents a location (building/address) with network settings
@@ -41,6 +86,7 @@ export interface Location {
   vlan: number
   createdAt: string
   updatedAt: string
+  deletedAt: string | null // set while in trash
 }
 
 // Profile represents an employee profile with SIP settings
@@ -57,10 +103,12 @@ export interface Profile {
   isActive: boolean
   createdAt: string
   updatedAt: string
+  deletedAt: string | null // set while in trash
 }
 
 // ProfileWithLocation represents a profile with location data
 export interface ProfileWithLocation extends Profile {
+  deviceModel: DeviceModel | null
   locationName: string | null
   server: string | null
   subnet: string | null
@@ -68,6 +116,171 @@ export interface ProfileWithLocation extends Profile {
   vlan: number | null
 }
 
+// Error response. fields заполняется при ошибке валидации (422) и конфликте значений (409),
+// requestId совпадает с заголовком X-Request-ID и записью в логе сервера
+export interface ErrorResponse {
+  error: string
+  fields?: Record<string, string>
+  requestId?: string
+}
+
+// Удаление устройства или локации, на которые ссылаются профили
+export type DeletePolicy = 'block' | 'cascade' | 'reassign'
+
+export interface DeleteOptions {
+  policy?: DeletePolicy
+  reassignTo?: string
+}
+
+export interface FileChange {
+  path: string
+  change: 'added' | 'removed' | 'modified'
+}
+
+export interface DeleteImpact {
+  entityType: 'device' | 'location'
+  entityId: string
+  policy: DeletePolicy
+  reassignTo?: string
+  blocked: boolean
+  profiles: Profile[]
+  files: FileChange[]
+}
+
+// Устройство в списке с числом назначенных ему сотрудников
+export interface DeviceWithUsage extends Device {
+  profileCount: number
+}
+
+// Фильтр списка устройств; locationId - устройства сотрудников этой локации
+export interface DeviceFilter {
+  deviceModel?: DeviceModel | null
+  assigned?: boolean | null
+  locationId?: number | null
+}
+
+// Локация в списке с числом сотрудников и их устройств
+export interface LocationWithCounts extends Location {
+  profileCount: number
+  deviceCount: number
+}
+
+// Фильтр списка профилей; sort - поле профиля, с "-" в начале по убыванию
+export interface ProfileFilter {
+  locationId?: number | null
+  isActive?: boolean | null
+  ringGroup?: number | null
+  pickupGroup?: number | null
+  deviceModel?: DeviceModel | null
+  hasDevice?: boolean | null
+  search?: string
+  sort?: string
+}
+
+// Массовое изменение профилей (POST /api/profiles/bulk)
+export type BulkMode = 'atomic' | 'bestEffort'
+
+export interface BulkProfileChanges {
+  locationId?: number
+  ringGroup?: number
+  pickupGroup?: number
+  isActive?: boolean
+  unassignDevice?: boolean
+}
+
+export interface BulkProfileRequest {
+  ids?: number[]
+  filter?: ProfileFilter
+  set: BulkProfileChanges
+  mode?: BulkMode
+}
+
+export interface BulkItemResult {
+  id: number
+  status: 'updated' | 'unchanged' | 'failed' | 'rolledBack'
+  error?: string
+  fields?: Record<string, string>
+}
+
+export interface BulkResult {
+  mode: BulkMode
+  applied: boolean
+  total: number
+  updated: number
+  unchanged: number
+  failed: number
+  results: BulkItemResult[]
+}
+
+// Общий поиск (GET /api/search?q=)
+export type SearchHitType = 'profile' | 'device' | 'location' | 'ringGroup'
+
+export interface SearchHit {
+  type: SearchHitType
+  id: string
+  title: string
+  subtitle: string
+  score: number
+}
+
+export interface SearchResponse {
+  query: string
+  hits: SearchHit[]
+}
+
+// Пул внутренних номеров локации или отдела
+export interface NumberPool {
+  id: number
+  name: string
+  locationId: number | null
+  department: string
+  rangeStart: number
+  rangeEnd: number
+  createdAt: string
+  updatedAt: string
+}
+
+// Бронь номера пула до expiresAt
+export interface NumberReservation {
+  id: number
+  poolId: number
+  number: number
+  note: string
+  username: string
+  expiresAt: string
+  createdAt: string
+}
+
+// Запрос брони: без number - первый свободный номер, ttl - длительность Go ("48h")
+export interface ReserveNumberRequest {
+  number?: number
+  ttl?: string
+  note?: string
+}
+
+export interface FreeNumber {
+  poolId: number
+  number: number
+}
+
+// Заполненность пула; percent учитывает и брони
+export interface PoolUtilisation extends NumberPool {
+  locationName: string
+  size: number
+  used: number
+  reserved: number
+  free: number
+  percent: number
+  nextFree: number | null
+}
+
+// Создание профиля; с allocateFromPool номер выдаётся из пула, internalNumber не указывается.
+// С fromReservation профиль занимает забронированный internalNumber, бронь снимается
+export type CreateProfileRequest = Omit<Profile, 'id' | 'createdAt' | 'updatedAt'> & {
+  allocateFromPool?: number | null
+  fromReservation?: boolean
+}
+
 // Pagination types
 export interface PaginationParams {
   page: number
@@ -85,3 +298,37 @@ export interface PaginatedResult<T> {
   data: T[]
   pagination: PaginationResponse
 }
+
+export type AuditAction = 'create' | 'update' | 'delete' | 'restore' | 'purge'
+
+export interface AuditEntry {
+  id: number
+  userId: number | null
+  username: string
+  apiKeyId?: number
+  action: AuditAction
+  entityType: 'profile' | 'device' | 'location'
+  entityId: string
+  changes: Record<string, { old: unknown; new: unknown }>
+  ip: string
+  createdAt: string
+}
+
+export interface Revision<T = Record<string, unknown>> {
+  id: number
+  entityType: AuditEntry['entityType']
+  entityId: string
+  revision: number
+  action: AuditAction
+  data: T
+  userId: number | null
+  username: string
+  createdAt: string
+}
+
+export interface Snapshot {
+  at: string
+  profiles: Profile[]
+  devices: Device[]
+  locations: Location[]
+}